@@ -0,0 +1,36 @@
+// Package all registers every attack with the attacks package. Import it for its side effects:
+//
+//	import _ "github.com/sourcekris/goRsaTool/attacks/all"
+package all
+
+import (
+	_ "github.com/sourcekris/goRsaTool/attacks/apbq"
+	_ "github.com/sourcekris/goRsaTool/attacks/brokenrsa"
+	_ "github.com/sourcekris/goRsaTool/attacks/commonfactor"
+	_ "github.com/sourcekris/goRsaTool/attacks/commonmodulus"
+	_ "github.com/sourcekris/goRsaTool/attacks/crt"
+	_ "github.com/sourcekris/goRsaTool/attacks/defectivee"
+	_ "github.com/sourcekris/goRsaTool/attacks/factordb"
+	_ "github.com/sourcekris/goRsaTool/attacks/fermat"
+	_ "github.com/sourcekris/goRsaTool/attacks/franklinreiter"
+	_ "github.com/sourcekris/goRsaTool/attacks/gmpecm"
+	_ "github.com/sourcekris/goRsaTool/attacks/hastads"
+	_ "github.com/sourcekris/goRsaTool/attacks/hastadsbroadcast"
+	_ "github.com/sourcekris/goRsaTool/attacks/knownprime"
+	_ "github.com/sourcekris/goRsaTool/attacks/londahl"
+	_ "github.com/sourcekris/goRsaTool/attacks/manysmallprimes"
+	_ "github.com/sourcekris/goRsaTool/attacks/notableprimes"
+	_ "github.com/sourcekris/goRsaTool/attacks/oraclemodulus"
+	_ "github.com/sourcekris/goRsaTool/attacks/partiald"
+	_ "github.com/sourcekris/goRsaTool/attacks/pastctfprimes"
+	_ "github.com/sourcekris/goRsaTool/attacks/pollardrhobrent"
+	_ "github.com/sourcekris/goRsaTool/attacks/pollardsp1"
+	_ "github.com/sourcekris/goRsaTool/attacks/pollardsrho"
+	_ "github.com/sourcekris/goRsaTool/attacks/qicheng"
+	_ "github.com/sourcekris/goRsaTool/attacks/smallfractions"
+	_ "github.com/sourcekris/goRsaTool/attacks/smallq"
+	_ "github.com/sourcekris/goRsaTool/attacks/squaren"
+	_ "github.com/sourcekris/goRsaTool/attacks/wiener"
+	_ "github.com/sourcekris/goRsaTool/attacks/wienermultiprime"
+	_ "github.com/sourcekris/goRsaTool/attacks/williamsp1"
+)
@@ -14,8 +14,10 @@
 package apbq
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 
@@ -24,30 +26,51 @@ import (
 
 const name = "abpq"
 
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "apbq",
+		Description: "Factor the modulus given hints of the form a*p + b*q with small a and b.",
+		Category:    attacks.CategoryFactorization,
+		Requires:    []attacks.Input{attacks.InputHints},
+		Suits:       []attacks.Feature{attacks.FeatureHints},
+		Unnatended:  true,
+		Params:      []*attacks.Param{bruteMax},
+		F:           Attack,
+	})
+}
+
+// bruteMax bounds the search for a and b.
+var bruteMax = &attacks.Param{Name: "brutemax", Description: "The largest a and b to try.", Default: int64(4096)}
+
 // Attack implements the abpq method against a ciphertext.
-func Attack(ks []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ks []*keys.RSA) (*keys.Result, error) {
 	var x, y int64
 	k := ks[0]
 	if k.Hints == nil || len(k.Hints) < 2 {
-		ch <- fmt.Errorf("invalid arguments for attack %s: this attack requires 2 hints", name)
-		return
+		return nil, fmt.Errorf("invalid arguments for attack %s: this attack requires 2 hints", name)
 	}
 
-	if k.BruteMax == 0 {
-		ch <- fmt.Errorf("invalid arguments for attack %s: this attack requires a maximum value > 0 to brute force for x and y", name)
-		return
+	max := bruteMax.Int()
+	if max <= 0 {
+		return nil, fmt.Errorf("invalid arguments for attack %s: this attack requires a maximum value > 0 to brute force for x and y", name)
 	}
 
-	for x = 1; x <= k.BruteMax; x++ {
-		for y = 1; y <= k.BruteMax; y++ {
+	progress := attacks.ProgressFrom(ctx)
+	progress.Start("values of x", max)
+
+	for x = 1; x <= max; x++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		progress.Add(1)
+
+		for y = 1; y <= max; y++ {
 			kq := new(fmp.Fmpz).GCD(fmp.NewFmpz(x).MulZ(k.Hints[0]).SubZ(fmp.NewFmpz(y).MulZ(k.Hints[1])), k.Key.N)
 			if kq.Cmp(ln.BigOne) > 0 {
-				k.PackGivenP(kq)
-				ch <- nil
-				return
+				return &keys.Result{Factors: []*fmp.Fmpz{kq}}, nil
 			}
 		}
 	}
 
-	ch <- fmt.Errorf("%s was unable to factor the key", name)
+	return nil, fmt.Errorf("%s was unable to factor the key", name)
 }
@@ -1,6 +1,8 @@
 package apbq
 
 import (
+	"context"
+	"strconv"
 	"testing"
 
 	"github.com/sourcekris/goRsaTool/keys"
@@ -56,14 +58,17 @@ func TestAttack(t *testing.T) {
 		}), nil, nil, "", false)
 
 		k.Hints = tc.hints
-		k.BruteMax = tc.max
+		if err := bruteMax.Set(strconv.FormatInt(tc.max, 10)); err != nil {
+			t.Fatalf("%s: failed setting brutemax: %v", tc.name, err)
+		}
 
 		if tc.ct != nil {
 			k.CipherText = ln.NumberToBytes(tc.ct)
 		}
-		ch := make(chan error)
-		go Attack([]*keys.RSA{k}, ch)
-		err := <-ch
+		r, err := Attack(context.Background(), []*keys.RSA{k})
+		if err == nil {
+			err = keys.Merge([]*keys.RSA{k}, r)
+		}
 		if err != nil && !tc.wantErr {
 			t.Errorf("Attack() failed: %s expected no error got error: %v", tc.name, err)
 		}
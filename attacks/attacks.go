@@ -1,101 +1,180 @@
 package attacks
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
 	"time"
 
-	"github.com/sourcekris/goRsaTool/attacks/apbq"
-	"github.com/sourcekris/goRsaTool/attacks/brokenrsa"
-	"github.com/sourcekris/goRsaTool/attacks/commonfactor"
-	"github.com/sourcekris/goRsaTool/attacks/commonmodulus"
-	"github.com/sourcekris/goRsaTool/attacks/crt"
-	"github.com/sourcekris/goRsaTool/attacks/defectivee"
-	"github.com/sourcekris/goRsaTool/attacks/factordb"
-	"github.com/sourcekris/goRsaTool/attacks/fermat"
-	"github.com/sourcekris/goRsaTool/attacks/franklinreiter"
-	"github.com/sourcekris/goRsaTool/attacks/gmpecm"
-	"github.com/sourcekris/goRsaTool/attacks/hastads"
-	"github.com/sourcekris/goRsaTool/attacks/hastadsbroadcast"
-	"github.com/sourcekris/goRsaTool/attacks/knownprime"
-	"github.com/sourcekris/goRsaTool/attacks/londahl"
-	"github.com/sourcekris/goRsaTool/attacks/manysmallprimes"
-	"github.com/sourcekris/goRsaTool/attacks/notableprimes"
-	"github.com/sourcekris/goRsaTool/attacks/oraclemodulus"
-	"github.com/sourcekris/goRsaTool/attacks/partiald"
-	"github.com/sourcekris/goRsaTool/attacks/pastctfprimes"
-	"github.com/sourcekris/goRsaTool/attacks/pollardrhobrent"
-	"github.com/sourcekris/goRsaTool/attacks/pollardsp1"
-	"github.com/sourcekris/goRsaTool/attacks/pollardsrho"
-	"github.com/sourcekris/goRsaTool/attacks/qicheng"
-	"github.com/sourcekris/goRsaTool/attacks/smallfractions"
-	"github.com/sourcekris/goRsaTool/attacks/smallq"
-	"github.com/sourcekris/goRsaTool/attacks/squaren"
-	"github.com/sourcekris/goRsaTool/attacks/wiener"
-	"github.com/sourcekris/goRsaTool/attacks/wienermultiprime"
-	"github.com/sourcekris/goRsaTool/attacks/williamsp1"
 	"github.com/sourcekris/goRsaTool/keys"
 )
 
 // default timeout 3m0s
 const DefaultTimeout int = 180
 
-// SupportedAttacks stores the list of registered attacks we support.
-var SupportedAttacks *Attacks
-
-func init() {
-	SupportedAttacks = NewAttacks()
-	// TODO(sourcekris): Register them in each package init function.
-	SupportedAttacks.RegisterAttack("crtsolver", false, true, DefaultTimeout, crt.Attack)
-	SupportedAttacks.RegisterAttack("factordb", false, true, DefaultTimeout, factordb.Attack)
-	SupportedAttacks.RegisterAttack("hastads", false, true, DefaultTimeout, hastads.Attack)
-	SupportedAttacks.RegisterAttack("hastadsbroadcast", true, true, DefaultTimeout, hastadsbroadcast.Attack)
-	SupportedAttacks.RegisterAttack("commonfactors", true, true, DefaultTimeout, commonfactor.Attack)
-	SupportedAttacks.RegisterAttack("commonmodulus", true, true, DefaultTimeout, commonmodulus.Attack)
-	SupportedAttacks.RegisterAttack("partiald", false, false, DefaultTimeout, partiald.Attack)
-	SupportedAttacks.RegisterAttack("knownprime", false, false, DefaultTimeout, knownprime.Attack)
-	SupportedAttacks.RegisterAttack("brokenrsa", false, true, DefaultTimeout, brokenrsa.Attack)
-	SupportedAttacks.RegisterAttack("notableprimes", false, true, DefaultTimeout, notableprimes.Attack)
-	SupportedAttacks.RegisterAttack("pastctf", false, true, DefaultTimeout, pastctfprimes.Attack)
-	SupportedAttacks.RegisterAttack("smallq", false, true, DefaultTimeout, smallq.Attack)
-	SupportedAttacks.RegisterAttack("wiener", false, true, DefaultTimeout, wiener.Attack)
-	SupportedAttacks.RegisterAttack("wienermultiprime", false, true, DefaultTimeout, wienermultiprime.Attack)
-	SupportedAttacks.RegisterAttack("qicheng", false, true, DefaultTimeout, qicheng.Attack)
-	SupportedAttacks.RegisterAttack("fermat", false, true, DefaultTimeout, fermat.Attack)
-	SupportedAttacks.RegisterAttack("londahl", false, true, DefaultTimeout, londahl.Attack)
-	SupportedAttacks.RegisterAttack("smallfractions", false, true, DefaultTimeout, smallfractions.Attack)
-	SupportedAttacks.RegisterAttack("manysmallprimes", false, true, DefaultTimeout, manysmallprimes.Attack)
-	SupportedAttacks.RegisterAttack("ecm", false, true, DefaultTimeout, gmpecm.Attack)
-	SupportedAttacks.RegisterAttack("franklinreiter", true, true, DefaultTimeout, franklinreiter.Attack)
-	SupportedAttacks.RegisterAttack("pollardsp1", false, true, DefaultTimeout, pollardsp1.Attack)
-	SupportedAttacks.RegisterAttack("pollardsrho", false, true, 300, pollardsrho.Attack)
-	SupportedAttacks.RegisterAttack("pollardrhobrent", false, true, 300, pollardrhobrent.Attack)
-	SupportedAttacks.RegisterAttack("williamsp1", false, true, DefaultTimeout, williamsp1.Attack)
-	SupportedAttacks.RegisterAttack("defectivee", false, true, DefaultTimeout, defectivee.Attack)
-	SupportedAttacks.RegisterAttack("oraclemodulus", false, true, DefaultTimeout, oraclemodulus.Attack)
-	SupportedAttacks.RegisterAttack("squaren", false, true, DefaultTimeout, squaren.Attack)
-	SupportedAttacks.RegisterAttack("apbq", false, true, DefaultTimeout, apbq.Attack)
-
-	// Aliased attacks (names that point to attacks already in the above list).
-	SupportedAttacks.RegisterAttack("mersenne", false, false, DefaultTimeout, notableprimes.Attack)
-	SupportedAttacks.RegisterAttack("lucas", false, false, DefaultTimeout, notableprimes.Attack)
-	SupportedAttacks.RegisterAttack("novelty", false, false, DefaultTimeout, notableprimes.Attack)
-	SupportedAttacks.RegisterAttack("pastprimes", false, false, DefaultTimeout, pastctfprimes.Attack)
-	SupportedAttacks.RegisterAttack("pastctfprimes", false, false, DefaultTimeout, pastctfprimes.Attack)
-	SupportedAttacks.RegisterAttack("sexyprimes", false, false, DefaultTimeout, fermat.Attack)
-	SupportedAttacks.RegisterAttack("smalle", false, true, DefaultTimeout, hastads.Attack)
-}
-
-type attackFunc func([]*keys.RSA, chan error)
-
-// Attack encodes a single attack and what features it supports.
+// stopGrace is how long a cancelled attack is given to return before it is abandoned.
+const stopGrace = 2 * time.Second
+
+// SupportedAttacks stores the list of registered attacks we support. Attack packages add
+// themselves to it from their init functions using Register.
+var SupportedAttacks = NewAttacks()
+
+// Register adds at to SupportedAttacks. It is intended to be called from the init function of
+// the package implementing the attack and panics if the name or an alias is already taken.
+func Register(at *Attack) {
+	SupportedAttacks.Register(at)
+}
+
+// attackFunc is the signature every attack implements. Attacks must not modify the keys they are
+// given, instead they return what they recovered and Execute merges it into the keys. Attacks must
+// watch ctx in their hot loops and return ctx.Err() once it is done so that timed out or
+// interrupted attacks stop consuming CPU.
+type attackFunc func(context.Context, []*keys.RSA) (*keys.Result, error)
+
+// Category groups attacks by what they recover.
+type Category string
+
+// The categories of attack we support.
+const (
+	CategoryFactorization Category = "factorization"
+	CategoryLookup        Category = "lookup"
+	CategoryExponent      Category = "private exponent"
+	CategoryPlaintext     Category = "plaintext recovery"
+	CategoryModulus       Category = "modulus recovery"
+)
+
+// AnyNumberOfKeys is used as Attack.MaxKeys by attacks with no upper bound on the keys they take.
+const AnyNumberOfKeys = -1
+
+// Attack describes a single attack and what it needs to run.
 type Attack struct {
-	Name          string
-	SupportsMulti bool
-	Unnatended    bool
-	Timeout       int
-	F             attackFunc
+	// Name is the canonical name used with the -attack flag.
+	Name string
+	// Aliases are alternative names that select the same attack.
+	Aliases     []string
+	Description string
+	Category    Category
+	// Reference points to the paper or write-up the attack is based on.
+	Reference string
+	Requires  []Input
+	// MinKeys and MaxKeys bound the number of keys the attack works with. A zero MinKeys means
+	// one key and a zero MaxKeys means MinKeys.
+	MinKeys int
+	MaxKeys int
+	// Expects lists key features the attack cannot succeed without, the planner skips it when
+	// one is missing. Suits lists features that make the attack worth trying early.
+	Expects []Feature
+	Suits   []Feature
+	// MaxBits is the largest modulus the planner will schedule the attack for, zero means any.
+	MaxBits int
+	// Unnatended attacks are run by -attack all.
+	Unnatended bool
+	// Timeout in seconds, DefaultTimeout when zero.
+	Timeout int
+	// Params are the attack's tuning parameters, settable with -opt.
+	Params []*Param
+	F      attackFunc
+}
+
+// minKeys returns the least number of keys the attack works with.
+func (at *Attack) minKeys() int {
+	if at.MinKeys < 1 {
+		return 1
+	}
+
+	return at.MinKeys
+}
+
+// maxKeys returns the most keys the attack works with or AnyNumberOfKeys.
+func (at *Attack) maxKeys() int {
+	if at.MaxKeys == 0 {
+		return at.minKeys()
+	}
+
+	return at.MaxKeys
+}
+
+// SupportsMulti returns true if the attack works with more than one key.
+func (at *Attack) SupportsMulti() bool {
+	return at.maxKeys() != 1
+}
+
+// timeout returns the attack's timeout.
+func (at *Attack) timeout() time.Duration {
+	if at.Timeout <= 0 {
+		return time.Duration(DefaultTimeout) * time.Second
+	}
+
+	return time.Duration(at.Timeout) * time.Second
+}
+
+// Keys describes the number of keys the attack supports, e.g. "1", "2" or "2+".
+func (at *Attack) Keys() string {
+	min, max := at.minKeys(), at.maxKeys()
+	switch {
+	case max == AnyNumberOfKeys:
+		return fmt.Sprintf("%d+", min)
+	case min == max:
+		return fmt.Sprint(min)
+	default:
+		return fmt.Sprintf("%d-%d", min, max)
+	}
+}
+
+// Describe returns the full registry entry for the attack.
+func (at *Attack) Describe() string {
+	var b bytes.Buffer
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+
+	requires := "public key only"
+	if len(at.Requires) > 0 {
+		var rs []string
+		for _, r := range at.Requires {
+			rs = append(rs, string(r))
+		}
+		requires = strings.Join(rs, ", ")
+	}
+
+	fmt.Fprintf(w, "Name:\t%s\n", at.Name)
+	if len(at.Aliases) > 0 {
+		fmt.Fprintf(w, "Aliases:\t%s\n", strings.Join(at.Aliases, ", "))
+	}
+	fmt.Fprintf(w, "Description:\t%s\n", at.Description)
+	fmt.Fprintf(w, "Category:\t%s\n", at.Category)
+	if at.Reference != "" {
+		fmt.Fprintf(w, "Reference:\t%s\n", at.Reference)
+	}
+	fmt.Fprintf(w, "Requires:\t%s\n", requires)
+	fmt.Fprintf(w, "Keys:\t%s\n", at.Keys())
+	if len(at.Expects) > 0 {
+		fmt.Fprintf(w, "Expects:\t%s\n", joinFeatures(at.Expects))
+	}
+	if len(at.Suits) > 0 {
+		fmt.Fprintf(w, "Suits:\t%s\n", joinFeatures(at.Suits))
+	}
+	if at.MaxBits > 0 {
+		fmt.Fprintf(w, "Max bits:\t%d\n", at.MaxBits)
+	}
+	fmt.Fprintf(w, "Unattended:\t%t\n", at.Unnatended)
+	fmt.Fprintf(w, "Timeout:\t%v\n", at.timeout())
+	for i, p := range at.Params {
+		label := ""
+		if i == 0 {
+			label = "Params:"
+		}
+		set := ""
+		if p.value != nil {
+			set = fmt.Sprintf(", set to %v", p.value)
+		}
+		fmt.Fprintf(w, "%s\t%s (%s, default %v%s): %s\n", label, p.Name, p.Type(), p.Default, set, p.Description)
+	}
+	w.Flush()
+
+	return b.String()
 }
 
 // Attacks wraps a slice of Attack objects that are supported.
@@ -108,65 +187,169 @@ func NewAttacks() *Attacks {
 	return &Attacks{}
 }
 
-// RegisterAttack adds a new attack to the receiving Attacks.
-func (a *Attacks) RegisterAttack(name string, multi bool, unnatended bool, timeout int, f attackFunc) {
+// Register adds a new attack to the receiving Attacks. It panics if the attack has no name or
+// function, if its name or one of its aliases is already registered, or if it declares an invalid
+// parameter.
+func (a *Attacks) Register(at *Attack) {
+	if at == nil || at.Name == "" || at.F == nil {
+		panic("attacks: Register called with an incomplete attack")
+	}
+
+	for _, n := range append([]string{at.Name}, at.Aliases...) {
+		if n == "all" || a.Lookup(n) != nil {
+			panic("attacks: Register called twice for attack " + n)
+		}
+	}
+
+	for _, p := range at.Params {
+		if p.Name == TimeoutParam || strings.HasPrefix(p.Type(), "unsupported") {
+			panic("attacks: Register called with an invalid parameter " + at.Name + "." + p.Name)
+		}
+	}
+
+	a.Supported = append(a.Supported, at)
+}
+
+// Lookup returns the attack registered under name or one of its aliases, or nil.
+func (a *Attacks) Lookup(name string) *Attack {
 	if a == nil {
-		a = NewAttacks()
+		return nil
+	}
+
+	for _, at := range a.Supported {
+		if at.Name == name {
+			return at
+		}
+
+		for _, alias := range at.Aliases {
+			if alias == name {
+				return at
+			}
+		}
 	}
 
-	a.Supported = append(a.Supported, &Attack{name, multi, unnatended, timeout, f})
+	return nil
 }
 
 // IsSupported returns true if name attack is supported.
 func (a *Attacks) IsSupported(name string) bool {
-	for _, a := range a.Supported {
-		if a.Name == name {
-			return true
-		}
+	return a.Lookup(name) != nil
+}
+
+// SupportsMulti returns true if the attack supports multi-key attacks.
+func (a *Attacks) SupportsMulti(name string) bool {
+	if at := a.Lookup(name); at != nil {
+		return at.SupportsMulti()
 	}
 
 	return false
 }
 
-// SupportsMulti returns true if the attack supports multi-key attacks.
-func (a *Attacks) SupportsMulti(name string) bool {
-	for _, a := range a.Supported {
-		if a.Name == name {
-			return a.SupportsMulti
+// Table returns a table of the registered attacks sorted by name.
+func (a *Attacks) Table() string {
+	sorted := make([]*Attack, len(a.Supported))
+	copy(sorted, a.Supported)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	var b bytes.Buffer
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCATEGORY\tKEYS\tUNATTENDED\tTIMEOUT\tDESCRIPTION")
+	for _, at := range sorted {
+		name := at.Name
+		if len(at.Aliases) > 0 {
+			name = fmt.Sprintf("%s (%s)", name, strings.Join(at.Aliases, ", "))
 		}
+
+		unattended := "no"
+		if at.Unnatended {
+			unattended = "yes"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%v\t%s\n", name, at.Category, at.Keys(), unattended, at.timeout(), at.Description)
 	}
+	w.Flush()
 
-	return false
+	return b.String()
 }
 
-// Execute executes the named attack against t.
-func (a *Attacks) Execute(name string, t []*keys.RSA) error {
-	if SupportedAttacks == nil {
+// Execute executes the named attack against t. The attack is cancelled when ctx is done or when
+// the attack's timeout expires, whichever happens first. Whatever the attack recovers is merged
+// into t. A *SkipError is returned without running the attack if t does not provide the inputs it
+// requires.
+func (a *Attacks) Execute(ctx context.Context, name string, t []*keys.RSA) error {
+	if a == nil {
 		return errors.New("no attacks registered")
 	}
 
-	if !a.IsSupported(name) {
+	at := a.Lookup(name)
+	if at == nil {
 		return fmt.Errorf("unsupported attack: %v", name)
 	}
 
-	for _, a := range SupportedAttacks.Supported {
-		if a.Name == name {
-			ctx := context.Background()
-			ctx, cancel := context.WithTimeout(ctx, time.Duration(a.Timeout*int(time.Second)))
-			defer cancel()
+	r, err := at.run(ctx, t)
+	if err != nil || r == nil {
+		return err
+	}
 
-			ch := make(chan error)
+	return keys.Merge(t, r)
+}
 
-			go a.F(t, ch)
+// run checks that t provides what the attack needs and then runs it until it returns, its timeout
+// expires or ctx is done. The result is returned without being merged into t.
+func (at *Attack) run(ctx context.Context, t []*keys.RSA) (*keys.Result, error) {
+	if err := at.Check(t); err != nil {
+		return nil, err
+	}
 
-			select {
-			case result := <-ch:
-				return result
-			case <-ctx.Done():
-				return fmt.Errorf("%s failed to factorize the key in the given time", name)
-			}
+	ctx, cancel := context.WithTimeout(context.WithValue(ctx, attackNameKey{}, at.Name), at.timeout())
+	defer cancel()
+
+	ctx, untrack := track(ctx, at.Name)
+	defer untrack()
+
+	type outcome struct {
+		r   *keys.Result
+		err error
+	}
+
+	// Buffered so the attack can always deliver its result and exit, even once we
+	// have stopped listening.
+	ch := make(chan outcome, 1)
+	start := time.Now()
+
+	go func() {
+		r, err := at.F(ctx, t)
+		ch <- outcome{r, err}
+	}()
+
+	finish := func(o outcome) (*keys.Result, error) {
+		if o.err != nil || o.r == nil {
+			return nil, o.err
 		}
+
+		o.r.Attack = at.Name
+		o.r.Duration = time.Since(start)
+
+		return o.r, nil
 	}
 
-	return errors.New("attack not found")
+	select {
+	case o := <-ch:
+		return finish(o)
+	case <-ctx.Done():
+		// Give the attack a moment to notice and save its progress to any checkpoint. A result
+		// that arrives meanwhile, like a factorization finishing as the timeout fired, is kept.
+		select {
+		case o := <-ch:
+			if o.err == nil && o.r != nil {
+				return finish(o)
+			}
+		case <-time.After(stopGrace):
+		}
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%s failed to factorize the key in the given time", at.Name)
+		}
+		return nil, fmt.Errorf("%s was interrupted: %w", at.Name, ctx.Err())
+	}
 }
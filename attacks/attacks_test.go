@@ -0,0 +1,527 @@
+package attacks
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sourcekris/goRsaTool/keys"
+	"github.com/sourcekris/goRsaTool/ln"
+
+	fmp "github.com/sourcekris/goflint"
+)
+
+func TestRegister(t *testing.T) {
+	noop := func(_ context.Context, _ []*keys.RSA) (*keys.Result, error) { return nil, nil }
+
+	a := NewAttacks()
+	a.Register(&Attack{Name: "notableprimes", Aliases: []string{"mersenne", "lucas"}, F: noop})
+	a.Register(&Attack{Name: "commonfactors", MinKeys: 2, MaxKeys: AnyNumberOfKeys, F: noop})
+
+	tt := []struct {
+		name  string
+		want  string
+		multi bool
+	}{
+		{name: "notableprimes", want: "notableprimes"},
+		{name: "lucas", want: "notableprimes"},
+		{name: "commonfactors", want: "commonfactors", multi: true},
+		{name: "unknown"},
+	}
+
+	for _, tc := range tt {
+		at := a.Lookup(tc.name)
+		if tc.want == "" {
+			if at != nil {
+				t.Errorf("Lookup(%q) = %q want nil", tc.name, at.Name)
+			}
+			continue
+		}
+
+		if at == nil || at.Name != tc.want {
+			t.Errorf("Lookup(%q) = %v want %q", tc.name, at, tc.want)
+			continue
+		}
+
+		if got := a.SupportsMulti(tc.name); got != tc.multi {
+			t.Errorf("SupportsMulti(%q) = %t want %t", tc.name, got, tc.multi)
+		}
+	}
+
+	if len(a.Supported) != 2 {
+		t.Errorf("Register() got %d entries want 2", len(a.Supported))
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Register() expected a panic when registering a duplicate alias")
+		}
+	}()
+	a.Register(&Attack{Name: "mersenne", F: noop})
+}
+
+func TestCheck(t *testing.T) {
+	pub := keys.PrivateFromPublic(&keys.FMPPublicKey{N: fmp.NewFmpz(77), E: fmp.NewFmpz(3)})
+	bare, _ := keys.NewRSA(pub, nil, nil, "", false)
+	withCT, _ := keys.NewRSA(pub, []byte{1}, nil, "", false)
+	withHint, _ := keys.NewRSA(pub, []byte{1}, nil, "", false)
+	withHint.Hints = []*fmp.Fmpz{fmp.NewFmpz(1)}
+
+	tt := []struct {
+		name string
+		at   *Attack
+		ks   []*keys.RSA
+		want string
+	}{
+		{
+			name: "public key only attack always applies",
+			at:   &Attack{Name: "fermat"},
+			ks:   []*keys.RSA{bare},
+		},
+		{
+			name: "missing ciphertext",
+			at:   &Attack{Name: "hastads", Requires: []Input{InputCipherText}},
+			ks:   []*keys.RSA{bare},
+			want: "hastads skipped: needs ciphertext",
+		},
+		{
+			name: "ciphertext provided",
+			at:   &Attack{Name: "hastads", Requires: []Input{InputCipherText}},
+			ks:   []*keys.RSA{withCT},
+		},
+		{
+			name: "only used keys are checked",
+			at:   &Attack{Name: "hastads", Requires: []Input{InputCipherText}},
+			ks:   []*keys.RSA{withCT, bare},
+		},
+		{
+			name: "every used key is checked",
+			at:   &Attack{Name: "commonmodulus", Requires: []Input{InputCipherText}, MinKeys: 2},
+			ks:   []*keys.RSA{withCT, bare},
+			want: "commonmodulus skipped: needs ciphertext",
+		},
+		{
+			name: "too few keys and too few hints",
+			at:   &Attack{Name: "multi", Requires: []Input{InputHints}, MinKeys: 2, MaxKeys: AnyNumberOfKeys},
+			ks:   []*keys.RSA{withHint},
+			want: "multi skipped: needs 2 keys, two hints",
+		},
+	}
+
+	for _, tc := range tt {
+		err := tc.at.Check(tc.ks)
+		switch {
+		case tc.want == "" && err != nil:
+			t.Errorf("%s: Check() unexpected error: %v", tc.name, err)
+		case tc.want != "" && (err == nil || err.Error() != tc.want):
+			t.Errorf("%s: Check() = %v want %q", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	noop := func(_ context.Context, _ []*keys.RSA) (*keys.Result, error) { return nil, nil }
+
+	a := NewAttacks()
+	a.Register(&Attack{Name: "slow", Unnatended: true, Timeout: 300, F: noop})
+	a.Register(&Attack{Name: "quick", Unnatended: true, Timeout: 10, F: noop})
+	a.Register(&Attack{Name: "wiener", Unnatended: true, Expects: []Feature{FeatureLargeE}, F: noop})
+	a.Register(&Attack{Name: "hastads", Unnatended: true, Requires: []Input{InputCipherText}, Expects: []Feature{FeatureSmallE}, Suits: []Feature{FeatureSmallCipherText}, F: noop})
+	a.Register(&Attack{Name: "lattice", Unnatended: true, MaxBits: 64, F: noop})
+	a.Register(&Attack{Name: "manual", F: noop})
+
+	n := ln.FmpString("170141183460469231731687303715884105728") // 2^127
+	largeE, _ := keys.NewRSA(keys.PrivateFromPublic(&keys.FMPPublicKey{N: n, E: ln.FmpString("1267650600228229401496703205376")}), nil, nil, "", false)
+	smallE, _ := keys.NewRSA(keys.PrivateFromPublic(&keys.FMPPublicKey{N: n, E: fmp.NewFmpz(3)}), []byte{8}, nil, "", false)
+	tiny, _ := keys.NewRSA(keys.PrivateFromPublic(&keys.FMPPublicKey{N: fmp.NewFmpz(77), E: fmp.NewFmpz(5)}), nil, nil, "", false)
+
+	tt := []struct {
+		name        string
+		ks          []*keys.RSA
+		wantSteps   []string
+		wantSkipped []string
+	}{
+		{
+			name:        "large e runs wiener first",
+			ks:          []*keys.RSA{largeE},
+			wantSteps:   []string{"wiener", "quick", "slow"},
+			wantSkipped: []string{"hastads skipped: needs ciphertext", "lattice skipped: needs N of at most 64 bits"},
+		},
+		{
+			name:        "small e and small ciphertext runs hastads first",
+			ks:          []*keys.RSA{smallE},
+			wantSteps:   []string{"hastads", "quick", "slow"},
+			wantSkipped: []string{"wiener skipped: needs large e", "lattice skipped: needs N of at most 64 bits"},
+		},
+		{
+			name:        "small modulus",
+			ks:          []*keys.RSA{tiny},
+			wantSteps:   []string{"quick", "lattice", "slow"},
+			wantSkipped: []string{"wiener skipped: needs large e", "hastads skipped: needs ciphertext"},
+		},
+	}
+
+	for _, tc := range tt {
+		p := a.Plan(tc.ks)
+
+		var steps []string
+		for _, s := range p.Steps {
+			steps = append(steps, s.Attack.Name)
+		}
+		if strings.Join(steps, ",") != strings.Join(tc.wantSteps, ",") {
+			t.Errorf("%s: Plan() steps = %v want %v", tc.name, steps, tc.wantSteps)
+		}
+
+		var skipped []string
+		for _, o := range p.Skipped {
+			skipped = append(skipped, o.Err.Error())
+		}
+		if strings.Join(skipped, ",") != strings.Join(tc.wantSkipped, ",") {
+			t.Errorf("%s: Plan() skipped = %v want %v", tc.name, skipped, tc.wantSkipped)
+		}
+	}
+}
+
+func TestExecuteCancelsAttack(t *testing.T) {
+	stopped := make(chan struct{})
+	spin := func(ctx context.Context, _ []*keys.RSA) (*keys.Result, error) {
+		defer close(stopped)
+		for {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	tt := []struct {
+		name    string
+		timeout int
+		cancel  time.Duration
+	}{
+		{
+			name:    "attack stops when its timeout expires",
+			timeout: 1,
+		},
+		{
+			name:    "attack stops when the parent context is cancelled",
+			timeout: 60,
+			cancel:  10 * time.Millisecond,
+		},
+	}
+
+	k, _ := keys.NewRSA(keys.PrivateFromPublic(&keys.FMPPublicKey{N: fmp.NewFmpz(77), E: fmp.NewFmpz(3)}), nil, nil, "", false)
+
+	for _, tc := range tt {
+		stopped = make(chan struct{})
+		a := NewAttacks()
+		a.Register(&Attack{Name: "spin", Timeout: tc.timeout, F: spin})
+
+		ctx := context.Background()
+		if tc.cancel > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, tc.cancel)
+			defer cancel()
+		}
+
+		if err := a.Execute(ctx, "spin", []*keys.RSA{k}); err == nil {
+			t.Errorf("%s: Execute() expected an error got nil", tc.name)
+		}
+
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			t.Errorf("%s: attack kept running after Execute() returned", tc.name)
+		}
+	}
+}
+
+func TestExecuteKeepsLateResult(t *testing.T) {
+	// late finds the factors just after its timeout expires, within the grace period.
+	late := func(ctx context.Context, _ []*keys.RSA) (*keys.Result, error) {
+		<-ctx.Done()
+		return &keys.Result{Factors: []*fmp.Fmpz{fmp.NewFmpz(7), fmp.NewFmpz(11)}}, nil
+	}
+
+	a := NewAttacks()
+	a.Register(&Attack{Name: "late", Timeout: 1, F: late})
+
+	k, _ := keys.NewRSA(keys.PrivateFromPublic(&keys.FMPPublicKey{N: fmp.NewFmpz(77), E: fmp.NewFmpz(7)}), nil, nil, "", false)
+	if err := a.Execute(context.Background(), "late", []*keys.RSA{k}); err != nil {
+		t.Errorf("Execute() failed: %v", err)
+	}
+
+	if k.Key.D == nil || len(k.Results) != 1 || k.Results[0].Attack != "late" {
+		t.Errorf("Execute() got d %v results %v want the late factors merged", k.Key.D, k.Results)
+	}
+}
+
+func TestExecuteUnattended(t *testing.T) {
+	slow := func(ctx context.Context, _ []*keys.RSA) (*keys.Result, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	fail := func(_ context.Context, _ []*keys.RSA) (*keys.Result, error) {
+		return nil, errors.New("fail failed")
+	}
+	win := func(_ context.Context, _ []*keys.RSA) (*keys.Result, error) {
+		time.Sleep(10 * time.Millisecond)
+		return &keys.Result{PlainTexts: [][]byte{[]byte("flag")}}, nil
+	}
+
+	a := NewAttacks()
+	a.Register(&Attack{Name: "slow", Unnatended: true, Timeout: 60, F: slow})
+	a.Register(&Attack{Name: "fail", Unnatended: true, Timeout: 60, F: fail})
+	a.Register(&Attack{Name: "manual", Timeout: 60, F: win})
+	a.Register(&Attack{Name: "win", Unnatended: true, Timeout: 60, F: win})
+	a.Register(&Attack{Name: "needy", Unnatended: true, Requires: []Input{InputCipherText}, Timeout: 60, F: win})
+
+	k, _ := keys.NewRSA(keys.PrivateFromPublic(&keys.FMPPublicKey{N: fmp.NewFmpz(77), E: fmp.NewFmpz(3)}), nil, nil, "", false)
+	r := a.ExecuteUnattended(context.Background(), []*keys.RSA{k}, 3)
+
+	if r.Winner != "win" {
+		t.Errorf("ExecuteUnattended() winner = %q want %q", r.Winner, "win")
+	}
+
+	if string(k.PlainText) != "flag" {
+		t.Errorf("ExecuteUnattended() did not copy the winning result back: got %q", k.PlainText)
+	}
+
+	if len(r.Outcomes) != 4 {
+		t.Fatalf("ExecuteUnattended() got %d outcomes want 4", len(r.Outcomes))
+	}
+
+	if o := r.Outcomes[3]; o.Started || o.skipped() == nil {
+		t.Errorf("ExecuteUnattended() expected needy attack to be skipped, got %+v", o)
+	}
+
+	if got, want := r.Skipped(), "skipped: needs ciphertext (needy)\n"; got != want {
+		t.Errorf("Report.Skipped() = %q want %q", got, want)
+	}
+
+	if errs := r.Errors(); len(errs) != 2 {
+		t.Errorf("Report.Errors() got %d errors want 2 (slow and fail): %v", len(errs), errs)
+	}
+
+	if o := r.Outcomes[0]; !o.Started || !errors.Is(o.Err, context.Canceled) {
+		t.Errorf("ExecuteUnattended() expected slow attack to be cancelled, got %+v", o)
+	}
+}
+
+func TestExecuteUnattendedCofactors(t *testing.T) {
+	// trial returns the smallest factor of the modulus, so it finds one prime per run.
+	trial := func(_ context.Context, ks []*keys.RSA) (*keys.Result, error) {
+		for i := int64(2); i < 1000; i++ {
+			p := fmp.NewFmpz(i)
+			if new(fmp.Fmpz).Mod(ks[0].Key.N, p).Equals(fmp.NewFmpz(0)) && !p.Equals(ks[0].Key.N) {
+				return &keys.Result{Factors: []*fmp.Fmpz{p}}, nil
+			}
+		}
+		return nil, errors.New("trial failed")
+	}
+
+	a := NewAttacks()
+	a.Register(&Attack{Name: "trial", Category: CategoryFactorization, Unnatended: true, Timeout: 60, F: trial})
+
+	// n = 11 * 13 * 17, phi = 10 * 12 * 16 = 1920.
+	k, _ := keys.NewRSA(keys.PrivateFromPublic(&keys.FMPPublicKey{N: fmp.NewFmpz(2431), E: fmp.NewFmpz(7)}), nil, nil, "", false)
+	r := a.ExecuteUnattended(context.Background(), []*keys.RSA{k}, 1)
+
+	if r.Winner != "trial (cofactor)" {
+		t.Errorf("ExecuteUnattended() winner = %q want %q", r.Winner, "trial (cofactor)")
+	}
+
+	if len(k.Key.Primes) != 3 || k.Key.D == nil {
+		t.Fatalf("ExecuteUnattended() did not fully factor the key: primes %v d %v", k.Key.Primes, k.Key.D)
+	}
+
+	ed := new(fmp.Fmpz).Mul(k.Key.D, k.Key.PublicKey.E)
+	if !ed.Mod(ed, fmp.NewFmpz(1920)).Equals(fmp.NewFmpz(1)) {
+		t.Errorf("ExecuteUnattended() d = %v is not the inverse of e mod phi(n)", k.Key.D)
+	}
+}
+
+func TestSetOption(t *testing.T) {
+	noop := func(_ context.Context, _ []*keys.RSA) (*keys.Result, error) { return nil, nil }
+
+	bound := &Param{Name: "b1", Default: int64(65536)}
+	verbose := &Param{Name: "verbose", Default: false}
+	a := NewAttacks()
+	a.Register(&Attack{Name: "pollardsp1", Aliases: []string{"p1"}, Params: []*Param{bound, verbose}, F: noop})
+
+	tt := []struct {
+		opt     string
+		wantErr bool
+	}{
+		{opt: "pollardsp1.b1=1000000"},
+		{opt: "p1.verbose=true"},
+		{opt: "pollardsp1.timeout=10m"},
+		{opt: "pollardsp1.b1=lots", wantErr: true},
+		{opt: "pollardsp1.b2=1", wantErr: true},
+		{opt: "fermat.b1=1", wantErr: true},
+		{opt: "pollardsp1.timeout=1ms", wantErr: true},
+		{opt: "pollardsp1.b1", wantErr: true},
+		{opt: "b1=1", wantErr: true},
+	}
+
+	for _, tc := range tt {
+		err := a.SetOption(tc.opt)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("SetOption(%q) = %v want error %t", tc.opt, err, tc.wantErr)
+		}
+	}
+
+	if got := bound.Int(); got != 1000000 {
+		t.Errorf("SetOption() b1 = %d want 1000000", got)
+	}
+
+	if !verbose.Bool() {
+		t.Errorf("SetOption() verbose = false want true")
+	}
+
+	if got := a.Lookup("pollardsp1").timeout(); got != 10*time.Minute {
+		t.Errorf("SetOption() timeout = %v want 10m", got)
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	// count resumes from the last saved count and saves the next one.
+	count := func(ctx context.Context, ks []*keys.RSA) (*keys.Result, error) {
+		slot := CheckpointSlot(ctx, ks[0].Key.N)
+		i, _ := slot.Resume().Int64("i")
+		i++
+
+		s := State{}
+		s.SetInt64("i", i)
+		slot.Save(s)
+
+		return &keys.Result{Iterations: i}, nil
+	}
+
+	a := NewAttacks()
+	a.Register(&Attack{Name: "count", F: count})
+
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	k, _ := keys.NewRSA(keys.PrivateFromPublic(&keys.FMPPublicKey{N: fmp.NewFmpz(77), E: fmp.NewFmpz(3)}), nil, nil, "", false)
+	other, _ := keys.NewRSA(keys.PrivateFromPublic(&keys.FMPPublicKey{N: fmp.NewFmpz(91), E: fmp.NewFmpz(3)}), nil, nil, "", false)
+
+	if slot := CheckpointSlot(context.Background(), k.Key.N); slot != nil {
+		t.Errorf("CheckpointSlot() without a checkpoint = %v want nil", slot)
+	}
+
+	if err := a.Execute(WithCheckpoint(context.Background(), NewCheckpoint(path)), "count", []*keys.RSA{k}); err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+
+	for _, want := range []int64{2, 3} {
+		cp, err := LoadCheckpoint(path)
+		if err != nil {
+			t.Fatalf("LoadCheckpoint() failed: %v", err)
+		}
+
+		if err := a.Execute(WithCheckpoint(context.Background(), cp), "count", []*keys.RSA{k}); err != nil {
+			t.Fatalf("Execute() failed: %v", err)
+		}
+
+		if got := k.Results[len(k.Results)-1].Iterations; got != want {
+			t.Errorf("Execute() after resume got iteration %d want %d", got, want)
+		}
+	}
+
+	cp, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint() failed: %v", err)
+	}
+
+	if err := a.Execute(WithCheckpoint(context.Background(), cp), "count", []*keys.RSA{other}); err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+
+	if got := other.Results[0].Iterations; got != 1 {
+		t.Errorf("Execute() on another modulus got iteration %d want 1", got)
+	}
+
+	slot := CheckpointSlot(context.WithValue(WithCheckpoint(context.Background(), cp), attackNameKey{}, "count"), k.Key.N)
+	slot.Clear()
+	slot = CheckpointSlot(context.WithValue(WithCheckpoint(context.Background(), cp), attackNameKey{}, "count"), other.Key.N)
+	slot.Clear()
+	if cp.Saved() || cp.Err() != nil {
+		t.Errorf("Clear() left progress saved or failed: %v", cp.Err())
+	}
+}
+
+func TestProgress(t *testing.T) {
+	var (
+		m       = NewMonitor()
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+
+	// work reports half of its work done then waits to be released.
+	work := func(ctx context.Context, ks []*keys.RSA) (*keys.Result, error) {
+		p := ProgressFrom(ctx)
+		p.Start("widgets", 10)
+		p.Add(5)
+		close(started)
+		<-release
+		return nil, errors.New("no luck")
+	}
+
+	a := NewAttacks()
+	a.Register(&Attack{Name: "work", F: work})
+
+	k, _ := keys.NewRSA(keys.PrivateFromPublic(&keys.FMPPublicKey{N: fmp.NewFmpz(77), E: fmp.NewFmpz(3)}), nil, nil, "", false)
+
+	done := make(chan error)
+	go func() {
+		done <- a.Execute(WithMonitor(context.Background(), m), "work", []*keys.RSA{k})
+	}()
+
+	<-started
+	st := m.Status()
+	if len(st) != 1 || !strings.HasPrefix(st[0], "work: 5 of 10 widgets (50%), ") {
+		t.Errorf("Status() while running = %q want one work line at 50%%", st)
+	}
+
+	close(release)
+	<-done
+
+	if st := m.Status(); len(st) != 0 {
+		t.Errorf("Status() once finished = %q want none", st)
+	}
+
+	ProgressFrom(context.Background()).Add(1)
+
+	for _, tc := range []struct {
+		name string
+		tr   *Tracker
+		want string
+	}{
+		{
+			name: "unknown total",
+			tr:   &Tracker{Name: "fermat", unit: "iterations", done: 1500000, start: time.Now().Add(-10 * time.Second)},
+			want: "fermat: 1.5M iterations, 150k/s",
+		},
+		{
+			name: "known total",
+			tr:   &Tracker{Name: "smallq", unit: "primes", done: 250, total: 1000, start: time.Now().Add(-10 * time.Second)},
+			want: "smallq: 250 of 1k primes (25%), 25/s, ETA 30s",
+		},
+		{
+			name: "resumed",
+			tr:   &Tracker{Name: "smallq", unit: "primes", done: 250, resumed: 500, total: 1000, start: time.Now().Add(-10 * time.Second)},
+			want: "smallq: 750 of 1k primes (75%), 25/s, ETA 10s",
+		},
+		{
+			name: "not started",
+			tr:   &Tracker{Name: "ecm", start: time.Now().Add(-time.Minute)},
+			want: "ecm: running for 1m0s",
+		},
+	} {
+		if got := tc.tr.String(); got != tc.want {
+			t.Errorf("%s: Tracker.String() = %q want %q", tc.name, got, tc.want)
+		}
+	}
+}
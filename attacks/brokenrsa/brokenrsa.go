@@ -5,8 +5,11 @@
 package brokenrsa
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 
@@ -15,23 +18,44 @@ import (
 
 const name = "brokenrsa"
 
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "brokenrsa",
+		Description: "Decrypt ciphertexts produced by multiplying by e instead of exponentiating, c = m*e mod n.",
+		Reference:   "https://ctftime.org/task/16900",
+		Category:    attacks.CategoryPlaintext,
+		Requires:    []attacks.Input{attacks.InputCipherText},
+		Unnatended:  true,
+		F:           Attack,
+	})
+}
+
 // Attack implements the brokenrsa method against ciphertext in multiple keys.
-func Attack(ks []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ks []*keys.RSA) (*keys.Result, error) {
 
 	k := ks[0]
 	if k.CipherText == nil {
-		ch <- fmt.Errorf("invalid arguments for attack %s: this attack requires the ciphertext", name)
-		return
+		return nil, fmt.Errorf("invalid arguments for attack %s: this attack requires the ciphertext", name)
 	}
 	d, u, _ := ln.XGCD(k.Key.PublicKey.E, k.Key.N)
 	if !d.Equals(ln.BigOne) {
-		ch <- fmt.Errorf("n and e were not coprime so %s attack will not work: GCE(e,n) == %v", name, d)
-		return
+		return nil, fmt.Errorf("n and e were not coprime so %s attack will not work: GCE(e,n) == %v", name, d)
 	}
 
 	ct := ln.BytesToNumber(k.CipherText)
 	pt := new(fmp.Fmpz).Mul(ct, u)
-	k.PlainText = ln.NumberToBytes(pt.Mod(pt, k.Key.N))
+	m := ln.NumberToBytes(pt.Mod(pt, k.Key.N))
+
+	// c*e^-1 mod n is only the plaintext if the ciphertext really was made by multiplying, so
+	// without a crib or flag format to confirm it the result is just a candidate.
+	confirmed := k.FlagFormat != nil && k.FlagFormat.Match(m)
+	if k.KnownPlainText != nil {
+		confirmed = bytes.HasPrefix(m, k.KnownPlainText)
+	}
+
+	if !confirmed {
+		return &keys.Result{Candidates: [][]byte{m}}, nil
+	}
 
-	ch <- nil
+	return &keys.Result{PlainTexts: [][]byte{m}}, nil
 }
@@ -1,6 +1,8 @@
 package brokenrsa
 
 import (
+	"context"
+	"regexp"
 	"testing"
 
 	"github.com/sourcekris/goRsaTool/keys"
@@ -10,20 +12,48 @@ import (
 
 func TestAttack(t *testing.T) {
 	tt := []struct {
-		name    string
-		n       *fmp.Fmpz
-		e       *fmp.Fmpz
-		ct      *fmp.Fmpz
-		want    string
-		wantErr bool
+		name string
+		n    *fmp.Fmpz
+		e    *fmp.Fmpz
+		ct   *fmp.Fmpz
+		kpt  []byte
+		flag *regexp.Regexp
+		want string
+		// wantCandidate is the best candidate expected when the plaintext cannot be confirmed.
+		wantCandidate string
+		wantErr       bool
 	}{
 		{
-			name: "valid test case should get ciphertext",
+			name: "valid test case with kpt should get plaintext",
 			n:    ln.FmpString("5496273377454199065242669248583423666922734652724977923256519661692097814683426757178129328854814879115976202924927868808744465886633837487140240744798219"),
 			e:    ln.FmpString("431136"),
 			ct:   ln.FmpString("2390642180797714842071565779994548288162547531430992356948054123172912061708724438750151247823692618495310950448700556438560565499149411218448809600"),
+			kpt:  []byte("this is"),
 			want: "this is a test this is a test this is a test this is a test",
 		},
+		{
+			name: "valid test case with a flag format should get plaintext",
+			n:    ln.FmpString("5496273377454199065242669248583423666922734652724977923256519661692097814683426757178129328854814879115976202924927868808744465886633837487140240744798219"),
+			e:    ln.FmpString("431136"),
+			ct:   ln.FmpString("2390642180797714842071565779994548288162547531430992356948054123172912061708724438750151247823692618495310950448700556438560565499149411218448809600"),
+			flag: regexp.MustCompile(`a test`),
+			want: "this is a test this is a test this is a test this is a test",
+		},
+		{
+			name:          "without a crib the result is only a candidate",
+			n:             ln.FmpString("5496273377454199065242669248583423666922734652724977923256519661692097814683426757178129328854814879115976202924927868808744465886633837487140240744798219"),
+			e:             ln.FmpString("431136"),
+			ct:            ln.FmpString("2390642180797714842071565779994548288162547531430992356948054123172912061708724438750151247823692618495310950448700556438560565499149411218448809600"),
+			wantCandidate: "this is a test this is a test this is a test this is a test",
+		},
+		{
+			name:          "a crib that does not match leaves a candidate",
+			n:             ln.FmpString("5496273377454199065242669248583423666922734652724977923256519661692097814683426757178129328854814879115976202924927868808744465886633837487140240744798219"),
+			e:             ln.FmpString("431136"),
+			ct:            ln.FmpString("2390642180797714842071565779994548288162547531430992356948054123172912061708724438750151247823692618495310950448700556438560565499149411218448809600"),
+			kpt:           []byte("flag{"),
+			wantCandidate: "this is a test this is a test this is a test this is a test",
+		},
 		{
 			name:    "invalid case, e is a factor of n",
 			n:       ln.FmpString("145089264118764276482000175726681870278495712"),
@@ -48,9 +78,13 @@ func TestAttack(t *testing.T) {
 		if tc.ct != nil {
 			k.CipherText = ln.NumberToBytes(tc.ct)
 		}
-		ch := make(chan error)
-		go Attack([]*keys.RSA{k}, ch)
-		err := <-ch
+		k.KnownPlainText = tc.kpt
+		k.FlagFormat = tc.flag
+
+		r, err := Attack(context.Background(), []*keys.RSA{k})
+		if err == nil {
+			err = keys.Merge([]*keys.RSA{k}, r)
+		}
 		if err != nil && !tc.wantErr {
 			t.Errorf("Attack() failed: %s expected no error got error: %v", tc.name, err)
 		}
@@ -58,6 +92,10 @@ func TestAttack(t *testing.T) {
 		if string(k.PlainText) != tc.want && !tc.wantErr {
 			t.Errorf("Attack() failed: %s got/want mismatch %s/%s", tc.name, string(k.PlainText), tc.want)
 		}
+
+		if tc.wantCandidate != "" && (len(k.Candidates) == 0 || string(k.Candidates[0].PlainText) != tc.wantCandidate) {
+			t.Errorf("Attack() failed: %s got candidates %v want the candidate %s", tc.name, k.Candidates, tc.wantCandidate)
+		}
 	}
 
 }
@@ -0,0 +1,227 @@
+package attacks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	fmp "github.com/sourcekris/goflint"
+)
+
+// CheckpointInterval is how often a running attack's progress is written to the checkpoint file.
+var CheckpointInterval = 30 * time.Second
+
+// State is the progress of an attack against one modulus, e.g. its loop counters and current
+// values, stored as decimal strings.
+type State map[string]string
+
+// SetInt stores z under key.
+func (s State) SetInt(key string, z *fmp.Fmpz) {
+	s[key] = z.String()
+}
+
+// Int returns the integer stored under key or nil if it is missing or invalid.
+func (s State) Int(key string) *fmp.Fmpz {
+	v, ok := s[key]
+	if !ok {
+		return nil
+	}
+
+	z, ok := new(fmp.Fmpz).SetString(v, 10)
+	if !ok {
+		return nil
+	}
+
+	return z
+}
+
+// SetInt64 stores i under key.
+func (s State) SetInt64(key string, i int64) {
+	s[key] = strconv.FormatInt(i, 10)
+}
+
+// Int64 returns the int64 stored under key, or zero and false if it is missing or invalid.
+func (s State) Int64(key string) (int64, bool) {
+	i, err := strconv.ParseInt(s[key], 10, 64)
+	return i, err == nil
+}
+
+// checkpointEntry is how a single attack's state is stored in the checkpoint file.
+type checkpointEntry struct {
+	Attack string `json:"attack"`
+	N      string `json:"n"`
+	State  State  `json:"state"`
+}
+
+// Checkpoint saves the progress of long running attacks to a file so that an interrupted or timed
+// out run can be resumed later. The state of each attack is kept per modulus.
+type Checkpoint struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]*checkpointEntry
+	err     error
+}
+
+// NewCheckpoint returns an empty checkpoint that is saved to path.
+func NewCheckpoint(path string) *Checkpoint {
+	return &Checkpoint{path: path, entries: make(map[string]*checkpointEntry)}
+}
+
+// LoadCheckpoint reads the checkpoint saved at path, further progress is saved back to path.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading checkpoint: %w", err)
+	}
+
+	var es []*checkpointEntry
+	if err := json.Unmarshal(b, &es); err != nil {
+		return nil, fmt.Errorf("failed parsing checkpoint %s: %w", path, err)
+	}
+
+	c := NewCheckpoint(path)
+	for _, e := range es {
+		c.entries[e.Attack+"/"+e.N] = e
+	}
+
+	return c, nil
+}
+
+// Path returns the file the checkpoint is saved to.
+func (c *Checkpoint) Path() string {
+	return c.path
+}
+
+// Saved returns true if the checkpoint holds progress that a later run can resume.
+func (c *Checkpoint) Saved() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries) > 0
+}
+
+// Err returns the first error encountered writing the checkpoint file.
+func (c *Checkpoint) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
+
+// write saves the checkpoint to its file, it must be called with c.mu held. The file is replaced
+// atomically so an interrupted write never loses the previous checkpoint.
+func (c *Checkpoint) write() {
+	es := make([]*checkpointEntry, 0, len(c.entries))
+	for _, e := range c.entries {
+		es = append(es, e)
+	}
+	sort.Slice(es, func(i, j int) bool {
+		if es[i].Attack != es[j].Attack {
+			return es[i].Attack < es[j].Attack
+		}
+		return es[i].N < es[j].N
+	})
+
+	b, err := json.MarshalIndent(es, "", "  ")
+	if err == nil {
+		tmp := filepath.Join(filepath.Dir(c.path), "."+filepath.Base(c.path)+".tmp")
+		if err = os.WriteFile(tmp, b, 0600); err == nil {
+			err = os.Rename(tmp, c.path)
+		}
+	}
+
+	if err != nil && c.err == nil {
+		c.err = fmt.Errorf("failed writing checkpoint: %w", err)
+	}
+}
+
+type checkpointKey struct{}
+
+// attackNameKey is the context key holding the name of the running attack.
+type attackNameKey struct{}
+
+// WithCheckpoint returns a context that makes attacks run with it save their progress to c.
+func WithCheckpoint(ctx context.Context, c *Checkpoint) context.Context {
+	return context.WithValue(ctx, checkpointKey{}, c)
+}
+
+// Slot is the part of a checkpoint belonging to one attack and modulus. A nil *Slot is valid and
+// does nothing, so attacks don't need to check whether checkpointing is enabled.
+type Slot struct {
+	c     *Checkpoint
+	key   string
+	entry checkpointEntry
+	saved time.Time
+}
+
+// CheckpointSlot returns the slot the running attack should save its progress against n to, or nil
+// if the run has no checkpoint.
+func CheckpointSlot(ctx context.Context, n *fmp.Fmpz) *Slot {
+	c, _ := ctx.Value(checkpointKey{}).(*Checkpoint)
+	name, _ := ctx.Value(attackNameKey{}).(string)
+	if c == nil || name == "" {
+		return nil
+	}
+
+	e := checkpointEntry{Attack: name, N: n.String()}
+	return &Slot{c: c, key: e.Attack + "/" + e.N, entry: e, saved: time.Now()}
+}
+
+// Resume returns the state saved by a previous run or nil if there is none.
+func (s *Slot) Resume() State {
+	if s == nil {
+		return nil
+	}
+
+	s.c.mu.Lock()
+	defer s.c.mu.Unlock()
+
+	if e, ok := s.c.entries[s.key]; ok {
+		return e.State
+	}
+
+	return nil
+}
+
+// Due returns true when it is time to save the attack's progress again.
+func (s *Slot) Due() bool {
+	return s != nil && time.Since(s.saved) >= CheckpointInterval
+}
+
+// Save records st as the attack's progress and writes the checkpoint file.
+func (s *Slot) Save(st State) {
+	if s == nil {
+		return
+	}
+
+	s.saved = time.Now()
+	s.c.mu.Lock()
+	defer s.c.mu.Unlock()
+
+	e := s.entry
+	e.State = st
+	s.c.entries[s.key] = &e
+	s.c.write()
+}
+
+// Clear removes the attack's progress once it has finished, there is nothing left to resume.
+func (s *Slot) Clear() {
+	if s == nil {
+		return
+	}
+
+	s.c.mu.Lock()
+	defer s.c.mu.Unlock()
+
+	if _, ok := s.c.entries[s.key]; ok {
+		delete(s.c.entries, s.key)
+		s.c.write()
+	}
+}
@@ -1,8 +1,10 @@
 package commonfactor
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 
@@ -12,23 +14,37 @@ import (
 // name is the name of this attack.
 const name = "common factors"
 
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "commonfactors",
+		Description: "Factor moduli that share a prime by taking their pairwise GCDs.",
+		Category:    attacks.CategoryFactorization,
+		MinKeys:     2,
+		MaxKeys:     attacks.AnyNumberOfKeys,
+		Suits:       []attacks.Feature{attacks.FeatureMultipleKeys},
+		Unnatended:  true,
+		F:           Attack,
+	})
+}
+
 // Attack implements the common factors method against moduli in multiple keys.
-func Attack(ks []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ks []*keys.RSA) (*keys.Result, error) {
 	for _, i := range ks {
 		for _, j := range ks {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
 			if i.Key.N == j.Key.N {
 				continue
 			}
 
 			g := new(fmp.Fmpz).GCD(i.Key.N, j.Key.N)
 			if g.Cmp(ln.BigOne) > 0 {
-				i.PackGivenP(g)
-				j.PackGivenP(g)
-
-				ch <- nil
-				return
+				// Merge applies g to every key it divides, including both i and j.
+				return &keys.Result{Factors: []*fmp.Fmpz{g}}, nil
 			}
 		}
 	}
-	ch <- fmt.Errorf("%s was unable to factor the keys", name)
+	return nil, fmt.Errorf("%s was unable to factor the keys", name)
 }
@@ -1,6 +1,7 @@
 package commonfactor
 
 import (
+	"context"
 	"testing"
 
 	"github.com/sourcekris/goRsaTool/keys"
@@ -36,9 +37,10 @@ func TestAttack(t *testing.T) {
 			E: fmp.NewFmpz(3),
 		}), nil, nil, "", false)
 
-		ch := make(chan error)
-		go Attack([]*keys.RSA{k1, k2}, ch)
-		err := <-ch
+		r, err := Attack(context.Background(), []*keys.RSA{k1, k2})
+		if err == nil {
+			err = keys.Merge([]*keys.RSA{k1, k2}, r)
+		}
 		if err != nil {
 			t.Errorf("Attack() failed: %s expected no error got error: %v", tc.name, err)
 		}
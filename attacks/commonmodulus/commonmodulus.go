@@ -1,8 +1,10 @@
 package commonmodulus
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 
@@ -12,21 +14,32 @@ import (
 // name is the name of this attack.
 const name = "common modulus"
 
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "commonmodulus",
+		Description: "Recover a message encrypted under one modulus with two coprime exponents.",
+		Category:    attacks.CategoryPlaintext,
+		Reference:   "G. Simmons, \"A Weak Privacy Protocol Using the RSA Crypto Algorithm\", Cryptologia 1983",
+		Requires:    []attacks.Input{attacks.InputCipherText},
+		MinKeys:     2,
+		Suits:       []attacks.Feature{attacks.FeatureMultipleKeys},
+		Unnatended:  true,
+		F:           Attack,
+	})
+}
+
 // Attack implements the common modulus attack against two keys.
-func Attack(ks []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ks []*keys.RSA) (*keys.Result, error) {
 	if len(ks) != 2 {
-		ch <- fmt.Errorf("%s attack expects exactly 2 keys - got %d keys", name, len(ks))
-		return
+		return nil, fmt.Errorf("%s attack expects exactly 2 keys - got %d keys", name, len(ks))
 	}
 
 	if ks[0].CipherText == nil || ks[1].CipherText == nil {
-		ch <- fmt.Errorf("%s attack requires each key be associated with a ciphertext", name)
-		return
+		return nil, fmt.Errorf("%s attack requires each key be associated with a ciphertext", name)
 	}
 
 	if !ks[0].Key.N.Equals(ks[1].Key.N) {
-		ch <- fmt.Errorf("%s attack requires that both keys share the same modulus", name)
-		return
+		return nil, fmt.Errorf("%s attack requires that both keys share the same modulus", name)
 	}
 
 	c1 := ln.BytesToNumber(ks[0].CipherText)
@@ -48,7 +61,7 @@ func Attack(ks []*keys.RSA, ch chan error) {
 		p2 = new(fmp.Fmpz).ModInverse(new(fmp.Fmpz).Exp(c2, new(fmp.Fmpz).Mul(v, ln.BigNOne), n), n)
 	}
 
-	ks[0].PlainText = ln.NumberToBytes(new(fmp.Fmpz).Root(new(fmp.Fmpz).Mul(p1, p2).ModZ(n), int32(g.GetInt())))
+	pt := ln.NumberToBytes(new(fmp.Fmpz).Root(new(fmp.Fmpz).Mul(p1, p2).ModZ(n), int32(g.GetInt())))
 
-	ch <- nil
+	return &keys.Result{PlainTexts: [][]byte{pt}}, nil
 }
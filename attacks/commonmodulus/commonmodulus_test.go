@@ -1,6 +1,7 @@
 package commonmodulus
 
 import (
+	"context"
 	"testing"
 
 	"github.com/sourcekris/goRsaTool/keys"
@@ -37,9 +38,10 @@ func TestAttack(t *testing.T) {
 			E: tc.e2,
 		}), ln.NumberToBytes(tc.c2), nil, "", false)
 
-		ch := make(chan error)
-		go Attack([]*keys.RSA{k1, k2}, ch)
-		err := <-ch
+		r, err := Attack(context.Background(), []*keys.RSA{k1, k2})
+		if err == nil {
+			err = keys.Merge([]*keys.RSA{k1, k2}, r)
+		}
 		if err != nil {
 			t.Errorf("Attack() failed: %s expected no error got error: %v", tc.name, err)
 		}
@@ -1,8 +1,10 @@
 package crt
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 
@@ -12,14 +14,24 @@ import (
 // name is the name of this attack.
 const name = "crt solver"
 
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "crtsolver",
+		Description: "Decrypt the ciphertext given the primes and CRT exponents dp and dq.",
+		Category:    attacks.CategoryPlaintext,
+		Requires:    []attacks.Input{attacks.InputCipherText, attacks.InputCRTValues},
+		Unnatended:  true,
+		F:           Attack,
+	})
+}
+
 // Attack solves for a plaintext given a ciphertext and the CRT components Dp, Dq, p, q.
-func Attack(ks []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ks []*keys.RSA) (*keys.Result, error) {
 	k := ks[0]
 
 	// We need values in the precomputed portion of the key for this attack.
 	if k.Key.Precomputed == nil {
-		ch <- fmt.Errorf("%s failed - Precomputed values (Dp, Dq, etc) is not in key %s", name, k.KeyFilename)
-		return
+		return nil, fmt.Errorf("%s failed - Precomputed values (Dp, Dq, etc) is not in key %s", name, k.KeyFilename)
 	}
 
 	// If we only got 1 prime, deduce the 2nd prime from N/p = q
@@ -28,13 +40,11 @@ func Attack(ks []*keys.RSA, ch chan error) {
 	}
 
 	if len(k.Key.Primes) < 2 {
-		ch <- fmt.Errorf("%s failed - need two primes", name)
-		return
+		return nil, fmt.Errorf("%s failed - need two primes", name)
 	}
 
 	if k.CipherText == nil {
-		ch <- fmt.Errorf("%s failed - no ciphertext provided", name)
-		return
+		return nil, fmt.Errorf("%s failed - no ciphertext provided", name)
 	}
 
 	pp := new(fmp.Fmpz).Sub(k.Key.Primes[0], ln.BigOne)
@@ -52,12 +62,9 @@ func Attack(ks []*keys.RSA, ch chan error) {
 	d := ln.SolveCRT(mrs)
 	n := new(fmp.Fmpz).Mul(k.Key.Primes[0], k.Key.Primes[1])
 
-	k.PlainText = ln.NumberToBytes(new(fmp.Fmpz).Exp(ln.BytesToNumber(k.CipherText), d, n))
-
-	if len(k.PlainText) > 0 {
-		ch <- nil
-		return
+	if pt := ln.NumberToBytes(new(fmp.Fmpz).Exp(ln.BytesToNumber(k.CipherText), d, n)); len(pt) > 0 {
+		return &keys.Result{PlainTexts: [][]byte{pt}}, nil
 	}
 
-	ch <- fmt.Errorf("%s failed", name)
+	return nil, fmt.Errorf("%s failed", name)
 }
@@ -1,6 +1,7 @@
 package crt
 
 import (
+	"context"
 	"testing"
 
 	"github.com/sourcekris/goRsaTool/keys"
@@ -54,9 +55,10 @@ func TestAttack(t *testing.T) {
 			CipherText:  ln.NumberToBytes(tc.c),
 			KeyFilename: tc.name,
 		}
-		ch := make(chan error)
-		go Attack([]*keys.RSA{k}, ch)
-		err := <-ch
+		r, err := Attack(context.Background(), []*keys.RSA{k})
+		if err == nil {
+			err = keys.Merge([]*keys.RSA{k}, r)
+		}
 		if err != nil {
 			t.Errorf("%s failed - got unexpected error: %v", tc.name, err)
 		}
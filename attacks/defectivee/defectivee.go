@@ -9,18 +9,40 @@ package defectivee
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
+	"github.com/sourcekris/goRsaTool/plaintext"
 
 	fmp "github.com/sourcekris/goflint"
 	mp "github.com/sourcekris/mathparse"
 )
 
-const name = "defective e"
+const (
+	name = "defective e"
+	// maxCandidates is the most plaintext candidates returned when there is no crib.
+	maxCandidates = 10
+)
+
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "defectivee",
+		Description: "Recover the plaintext when e is not coprime with phi.",
+		Category:    attacks.CategoryPlaintext,
+		Reference:   "https://github.com/cscosu/buckeyectf-2021/tree/master/crypto/defective_rsa/solve",
+		Requires:    []attacks.Input{attacks.InputCipherText, attacks.InputPrime},
+		Suits:       []attacks.Feature{attacks.FeatureKnownPlainText},
+		Unnatended:  true,
+		Params:      []*attacks.Param{rounds},
+		F:           Attack,
+	})
+}
 
-var rounds int64 = 500
+// rounds is the number of candidate bases tried when looking for roots of unity.
+var rounds = &attacks.Param{Name: "rounds", Description: "The number of bases tried when searching for roots of unity.", Default: int64(500)}
 
 func rootsOfUnity(e, phi, n *fmp.Fmpz, rounds int64) ([]*fmp.Fmpz, *fmp.Fmpz) {
 	var (
@@ -52,14 +74,13 @@ OUTER:
 }
 
 // Attack implements the defectivee method against RSA given at least one prime.
-func Attack(ks []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ks []*keys.RSA) (*keys.Result, error) {
 
 	var p, q *fmp.Fmpz
 
 	k := ks[0]
 	if k.Key.Primes == nil {
-		ch <- fmt.Errorf("%s attack requires the modulus to already be factored, provide at least one prime with -p flag", name)
-		return
+		return nil, fmt.Errorf("%s attack requires the modulus to already be factored, provide at least one prime with -p flag", name)
 	}
 
 	if len(k.Key.Primes) == 1 {
@@ -67,17 +88,10 @@ func Attack(ks []*keys.RSA, ch chan error) {
 		q = new(fmp.Fmpz).Div(k.Key.N, p)
 
 		if new(fmp.Fmpz).Mul(p, q).Cmp(k.Key.N) != 0 {
-			ch <- fmt.Errorf("%s failed. n is not the product of primes p and q", name)
-			return
+			return nil, fmt.Errorf("%s failed. n is not the product of primes p and q", name)
 		}
 	}
 
-	// TODO: Use a hueristic instead like, "are all of the bytes in m considered printable?"
-	if k.KnownPlainText == nil {
-		ch <- fmt.Errorf("%s requires a crib, part of the plaintext, so we know when our solution is found (e.g. CTF flag format)", name)
-		return
-	}
-
 	e := new(fmp.Fmpz).Set(k.Key.PublicKey.E)
 	n := new(fmp.Fmpz).Set(k.Key.N)
 	c := new(fmp.Fmpz).Set(ln.BytesToNumber(k.CipherText))
@@ -86,12 +100,11 @@ func Attack(ks []*keys.RSA, ch chan error) {
 	d := new(fmp.Fmpz).ModInverse(phi, e)
 
 	if d.Cmp(ln.BigZero) != 0 {
-		ch <- fmt.Errorf("%s failed: e is probably co-prime to phi(n) since there exists an inverse modulus of e, phi(n): %v", name, d)
-		return
+		return nil, fmt.Errorf("%s failed: e is probably co-prime to phi(n) since there exists an inverse modulus of e, phi(n): %v", name, d)
 	}
 
 	// Find e'th roots of unity modulo n.
-	roots, phiCoprime := rootsOfUnity(e, phi, n, rounds)
+	roots, phiCoprime := rootsOfUnity(e, phi, n, rounds.Int())
 
 	// Use phiCoprime to get one possible plaintext for c.
 	d = new(fmp.Fmpz).ModInverse(e, phiCoprime)
@@ -99,28 +112,62 @@ func Attack(ks []*keys.RSA, ch chan error) {
 
 	testC := new(fmp.Fmpz).Exp(m, e, n)
 	if testC.Cmp(c) != 0 {
-		ch <- fmt.Errorf("%s failed to find a possible plaintext for the given ciphertext and key", name)
-		return
+		return nil, fmt.Errorf("%s failed to find a possible plaintext for the given ciphertext and key", name)
 	}
 
-	// Maybe the first m is the right one? If so pack the key and return.
-	if bytes.HasPrefix(ln.NumberToBytes(m), k.KnownPlainText) {
-		k.Key.D = new(fmp.Fmpz).Set(d)
-		k.PlainText = ln.NumberToBytes(m)
-		k.Key.Primes = append(k.Key.Primes, q)
-		ch <- nil
+	// Without a crib any root matching the flag format, or failing that any printable root, is a
+	// candidate and the candidates are ranked by how much they look like text.
+	match := func(pt []byte) bool {
+		switch {
+		case k.KnownPlainText != nil:
+			return bytes.HasPrefix(pt, k.KnownPlainText)
+		case k.FlagFormat != nil:
+			return k.FlagFormat.Match(pt)
+		default:
+			return plaintext.Printable(pt)
+		}
+	}
+
+	// Maybe the first m is the right one?
+	var pts [][]byte
+	if pt := ln.NumberToBytes(m); match(pt) {
+		pts = append(pts, pt)
 	}
 
-	// Search the roots for a plaintext matching our crib.
+	// Search the roots for every plaintext matching our crib.
 	for _, root := range roots {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		mt := new(fmp.Fmpz).Mul(m, root).ModZ(n)
-		if bytes.HasPrefix(ln.NumberToBytes(mt), k.KnownPlainText) {
-			k.Key.D = new(fmp.Fmpz).Set(d)
-			k.PlainText = ln.NumberToBytes(mt)
-			k.Key.Primes = append(k.Key.Primes, q)
-			ch <- nil
+		if pt := ln.NumberToBytes(mt); match(pt) {
+			pts = append(pts, pt)
 		}
 	}
 
-	ch <- nil
+	if len(pts) == 0 {
+		return nil, fmt.Errorf("%s failed to find a plaintext matching the crib, flag format or being printable", name)
+	}
+
+	// Printable roots are only candidates, nothing tells which of them is the plaintext so they are
+	// not reported as the plaintext or with d, which would end the other attacks.
+	if k.KnownPlainText == nil && k.FlagFormat == nil {
+		r := &keys.Result{Iterations: int64(len(roots))}
+		for i, c := range plaintext.Rank(pts, nil) {
+			if i == maxCandidates {
+				break
+			}
+			r.Candidates = append(r.Candidates, c.PlainText)
+		}
+
+		return r, nil
+	}
+
+	r := &keys.Result{D: d, Factors: []*fmp.Fmpz{p, q}, Iterations: int64(len(roots))}
+	for _, c := range plaintext.Rank(pts, k.FlagFormat) {
+		r.PlainTexts = append(r.PlainTexts, c.PlainText)
+	}
+
+	return r, nil
 }
@@ -1,6 +1,8 @@
 package defectivee
 
 import (
+	"context"
+	"regexp"
 	"testing"
 
 	"github.com/sourcekris/goRsaTool/keys"
@@ -10,14 +12,17 @@ import (
 
 func TestAttack(t *testing.T) {
 	tt := []struct {
-		name    string
-		n       *fmp.Fmpz
-		p       *fmp.Fmpz
-		e       *fmp.Fmpz
-		ct      *fmp.Fmpz
-		kpt     []byte
-		want    string
-		wantErr bool
+		name string
+		n    *fmp.Fmpz
+		p    *fmp.Fmpz
+		e    *fmp.Fmpz
+		ct   *fmp.Fmpz
+		kpt  []byte
+		flag *regexp.Regexp
+		want string
+		// wantCandidate is the best candidate expected when the plaintext cannot be confirmed.
+		wantCandidate string
+		wantErr       bool
 	}{
 		{
 			name: "valid test case should get ciphertext",
@@ -38,13 +43,21 @@ func TestAttack(t *testing.T) {
 			wantErr: true,
 		},
 		{
-			name:    "test case without kpt should fail",
-			n:       ln.FmpString("168272588646770966877299988249949386707730640776720529400931912376687869273888817277014902477908929418867183677528815678576475469941650076986589240977287539474147398609072130842805456080239193915659119341791091450526391012975537938548738613273826665145980658413212111508448978246386654194004067968706171374073"),
-			p:       ln.FmpString("13013195056445077675245767987987229724588379930923318266833492046660374216223334270611792324721132438307229159984813414250922197169316235737830919431103659"),
-			e:       ln.FmpString("100"),
-			ct:      ln.FmpString("2536072596735405513004321180336671392201446145691544525658443473848104743281278364580324721238865873217702884067306856569406059869172045956521348858084998514527555980415205217073019437355422966248344183944699168548887273804385919216488597207667402462509907219285121314528666853710860436030055903562805252516"),
-			want:    "easyctf{m0dul4r_fuN!}",
-			wantErr: true,
+			name:          "test case without kpt should rank the printable roots as candidates only",
+			n:             ln.FmpString("168272588646770966877299988249949386707730640776720529400931912376687869273888817277014902477908929418867183677528815678576475469941650076986589240977287539474147398609072130842805456080239193915659119341791091450526391012975537938548738613273826665145980658413212111508448978246386654194004067968706171374073"),
+			p:             ln.FmpString("13013195056445077675245767987987229724588379930923318266833492046660374216223334270611792324721132438307229159984813414250922197169316235737830919431103659"),
+			e:             ln.FmpString("100"),
+			ct:            ln.FmpString("2536072596735405513004321180336671392201446145691544525658443473848104743281278364580324721238865873217702884067306856569406059869172045956521348858084998514527555980415205217073019437355422966248344183944699168548887273804385919216488597207667402462509907219285121314528666853710860436030055903562805252516"),
+			wantCandidate: "easyctf{m0dul4r_fuN!}",
+		},
+		{
+			name: "test case with a flag format",
+			n:    ln.FmpString("168272588646770966877299988249949386707730640776720529400931912376687869273888817277014902477908929418867183677528815678576475469941650076986589240977287539474147398609072130842805456080239193915659119341791091450526391012975537938548738613273826665145980658413212111508448978246386654194004067968706171374073"),
+			p:    ln.FmpString("13013195056445077675245767987987229724588379930923318266833492046660374216223334270611792324721132438307229159984813414250922197169316235737830919431103659"),
+			e:    ln.FmpString("100"),
+			ct:   ln.FmpString("2536072596735405513004321180336671392201446145691544525658443473848104743281278364580324721238865873217702884067306856569406059869172045956521348858084998514527555980415205217073019437355422966248344183944699168548887273804385919216488597207667402462509907219285121314528666853710860436030055903562805252516"),
+			flag: regexp.MustCompile(`easyctf\{.*\}`),
+			want: "easyctf{m0dul4r_fuN!}",
 		},
 		{
 			name:    "test case without ct should fail",
@@ -81,13 +94,16 @@ func TestAttack(t *testing.T) {
 			k.KnownPlainText = tc.kpt
 		}
 
+		k.FlagFormat = tc.flag
+
 		if tc.p != nil {
 			k.Key.Primes = append(k.Key.Primes, tc.p)
 		}
 
-		ch := make(chan error)
-		go Attack([]*keys.RSA{k}, ch)
-		err := <-ch
+		r, err := Attack(context.Background(), []*keys.RSA{k})
+		if err == nil {
+			err = keys.Merge([]*keys.RSA{k}, r)
+		}
 		if err != nil && !tc.wantErr {
 			t.Errorf("Attack() failed: %s expected no error got error: %v", tc.name, err)
 		}
@@ -95,6 +111,10 @@ func TestAttack(t *testing.T) {
 		if string(k.PlainText) != tc.want && !tc.wantErr {
 			t.Errorf("Attack() failed: %s got/want mismatch %s/%s", tc.name, string(k.PlainText), tc.want)
 		}
+
+		if tc.wantCandidate != "" && (k.Key.D != nil || len(k.Candidates) == 0 || string(k.Candidates[0].PlainText) != tc.wantCandidate) {
+			t.Errorf("Attack() failed: %s got d %v candidates %v want only the candidate %s", tc.name, k.Key.D, k.Candidates, tc.wantCandidate)
+		}
 	}
 
 }
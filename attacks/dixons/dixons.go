@@ -1,8 +1,10 @@
 package dixons
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 
@@ -18,14 +20,21 @@ var (
 )
 
 // Attack implements the Dixon's factorization method.
-func Attack(ks []*keys.RSA) error {
+func Attack(ctx context.Context, ks []*keys.RSA) error {
 	var (
 		k = ks[0]
 		n = k.Key.PublicKey.N
 		i = new(fmp.Fmpz).Sqrt(n)
 	)
 
+	progress := attacks.ProgressFrom(ctx)
+	progress.Start("iterations", 0)
 	for i.Cmp(n) < 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		progress.Add(1)
+
 		for _, j := range base {
 			lhs := new(fmp.Fmpz).ExpXIM(i, 2, n)
 			rhs := new(fmp.Fmpz).ExpXIM(j, 2, n)
@@ -1,6 +1,7 @@
 package dixons
 
 import (
+	"context"
 	"testing"
 
 	"github.com/sourcekris/goRsaTool/keys"
@@ -32,7 +33,7 @@ func TestAttack(t *testing.T) {
 		}
 
 		k, _ := keys.NewRSA(keys.PrivateFromPublic(fmpPubKey), nil, nil, "", false)
-		err := Attack([]*keys.RSA{k})
+		err := Attack(context.Background(), []*keys.RSA{k})
 		if err != nil {
 			t.Errorf("Attack() failed: %s expected no error got error: %v", tc.name, err)
 		}
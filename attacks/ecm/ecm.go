@@ -4,11 +4,13 @@
 package ecm
 
 import (
+	"context"
 	"fmt"
 	"log"
 
 	"github.com/jbarham/primegen"
 
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 
@@ -151,7 +153,7 @@ func (p point) Mul(k uint64, n, a, res *fmp.Fmpz) point {
 // }
 
 // Attack implements the ECM factorization method.
-func Attack(ks []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ks []*keys.RSA) (*keys.Result, error) {
 	var (
 		k     = ks[0]
 		res   = new(fmp.Fmpz)
@@ -162,8 +164,16 @@ func Attack(ks []*keys.RSA, ch chan error) {
 		log.Printf("%s attempt beginning", name)
 	}
 
+	progress := attacks.ProgressFrom(ctx)
+	progress.Start("curves", 0)
+
 	pg := primegen.New()
-	for {
+	for curves := int64(1); ; curves++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		progress.Add(1)
+
 		a := ln.GetRand(state, k.Key.N)
 		if new(fmp.Fmpz).ExpXI(a, 3).MulI(4).AddI(27).ModZ(k.Key.N).IsZero() {
 			// n divides 4a^3+27 - curve has repeating factors, so skip it.
@@ -172,6 +182,10 @@ func Attack(ks []*keys.RSA, ch chan error) {
 
 		p := point{fmp.NewFmpz(0), fmp.NewFmpz(1)}
 		for {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
 			p = p.Mul(pg.Next(), k.Key.N, a, res)
 			if p.Zero() {
 				// this curve didn't work
@@ -179,9 +193,7 @@ func Attack(ks []*keys.RSA, ch chan error) {
 			}
 
 			if res != nil && res.Cmp(ln.BigZero) > 0 {
-				k.PackGivenP(res)
-				ch <- nil
-				return
+				return &keys.Result{Factors: []*fmp.Fmpz{res}, Iterations: curves}, nil
 			}
 		}
 	}
@@ -1,6 +1,7 @@
 package ecm
 
 import (
+	"context"
 	"testing"
 
 	"github.com/sourcekris/goRsaTool/keys"
@@ -30,9 +31,10 @@ func TestAttack(t *testing.T) {
 		}
 
 		k, _ := keys.NewRSA(keys.PrivateFromPublic(fmpPubKey), nil, nil, "", false)
-		ch := make(chan error)
-		go Attack([]*keys.RSA{k}, ch)
-		err := <-ch
+		r, err := Attack(context.Background(), []*keys.RSA{k})
+		if err == nil {
+			err = keys.Merge([]*keys.RSA{k}, r)
+		}
 		if err != nil {
 			t.Errorf("Attack() failed: %s expected no error got error: %v", tc.name, err)
 		}
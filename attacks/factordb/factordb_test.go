@@ -1,6 +1,7 @@
 package factordb
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"strings"
@@ -15,7 +16,7 @@ import (
 
 var jsonBlob string
 
-func askertest(hc *http.Client, url string) (*http.Response, error) {
+func askertest(ctx context.Context, hc *http.Client, url string) (*http.Response, error) {
 	return &http.Response{
 		StatusCode: 200,
 		Body:       io.NopCloser(strings.NewReader(jsonBlob)),
@@ -56,9 +57,10 @@ func TestFactorDB(t *testing.T) {
 		jsonBlob = tc.jb
 
 		k, _ := keys.NewRSA(keys.PrivateFromPublic(fmpPubKey), nil, nil, "", false)
-		ch := make(chan error)
-		go Attack([]*keys.RSA{k}, ch)
-		err := <-ch
+		r, err := Attack(context.Background(), []*keys.RSA{k})
+		if err == nil {
+			err = keys.Merge([]*keys.RSA{k}, r)
+		}
 		if err != nil && !tc.wantErr {
 			t.Errorf("Attack() failed: %s expected no error got error: %v", tc.name, err)
 		}
@@ -1,8 +1,10 @@
 package fermat
 
 import (
+	"context"
 	"log"
 
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 
@@ -12,31 +14,75 @@ import (
 // name is the name of this attack.
 const name = "fermat factorization"
 
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "fermat",
+		Aliases:     []string{"sexyprimes"},
+		Description: "Fermat factorization of moduli whose primes are close together.",
+		Category:    attacks.CategoryFactorization,
+		Reference:   "https://en.wikipedia.org/wiki/Fermat%27s_factorization_method",
+		Unnatended:  true,
+		F:           Attack,
+	})
+}
+
 // Attack implements the Fermat Factorization attack.
-func Attack(ts []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ts []*keys.RSA) (*keys.Result, error) {
 	t := ts[0]
 	if t.Key.D != nil {
 		// Key already factored.
-		ch <- nil
-		return
+		return nil, nil
+	}
+
+	var (
+		a    = new(fmp.Fmpz).Sqrt(t.Key.N)
+		i    int64
+		slot = attacks.CheckpointSlot(ctx, t.Key.N)
+	)
+
+	// Resume from the offset a previous run reached.
+	if s := slot.Resume(); s != nil && s.Int("a") != nil {
+		a = s.Int("a")
+		i, _ = s.Int64("iterations")
 	}
 
-	a := new(fmp.Fmpz).Sqrt(t.Key.N)
 	b := new(fmp.Fmpz).Set(a)
 	b2 := new(fmp.Fmpz).Mul(a, a)
 	b2.Sub(b2, t.Key.N)
 
+	state := func() attacks.State {
+		s := attacks.State{}
+		s.SetInt("a", a)
+		s.SetInt64("iterations", i)
+		return s
+	}
+
 	if t.Verbose {
 		log.Printf("%s attempt beginning", name)
 	}
+	progress := attacks.ProgressFrom(ctx)
+	progress.Start("iterations", 0)
+	progress.Resume(i)
+
 	c := new(fmp.Fmpz).Mul(b, b)
-	for !c.Equals(b2) {
+	for ; !c.Equals(b2); i++ {
+		if err := ctx.Err(); err != nil {
+			slot.Save(state())
+			return nil, err
+		}
+
+		if slot.Due() {
+			slot.Save(state())
+		}
+
+		progress.Add(1)
 		a.Add(a, ln.BigOne)
 		b2.Mul(a, a).Sub(b2, t.Key.N)
 		b.Sqrt(b2)
 		c.Mul(b, b)
 	}
 
-	t.PackGivenP(new(fmp.Fmpz).Add(a, b))
-	ch <- nil
+	slot.Clear()
+
+	return &keys.Result{Factors: []*fmp.Fmpz{new(fmp.Fmpz).Add(a, b)}, Iterations: i}, nil
 }
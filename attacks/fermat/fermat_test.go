@@ -1,6 +1,7 @@
 package fermat
 
 import (
+	"context"
 	"testing"
 
 	"github.com/sourcekris/goRsaTool/keys"
@@ -26,11 +27,11 @@ func TestAttack(t *testing.T) {
 	}
 
 	for _, tc := range tt {
-		ch := make(chan error)
 		k, _ := keys.NewRSA(keys.PrivateFromPublic(&keys.FMPPublicKey{N: tc.n, E: tc.e}), nil, nil, "", false)
-		go Attack([]*keys.RSA{k}, ch)
-
-		err := <-ch
+		r, err := Attack(context.Background(), []*keys.RSA{k})
+		if err == nil {
+			err = keys.Merge([]*keys.RSA{k}, r)
+		}
 		if err != nil {
 			t.Errorf("Attack() failed: %s expected no error got error: %v", tc.name, err)
 		}
@@ -1,9 +1,11 @@
 package franklinreiter
 
 import (
+	"context"
 	"fmt"
 	"log"
 
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 
@@ -13,6 +15,21 @@ import (
 // name is the name of this attack.
 const name = "franklin reiter related message attack"
 
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "franklinreiter",
+		Description: "Recover two messages with a known linear relation encrypted under the same key.",
+		Category:    attacks.CategoryPlaintext,
+		Reference:   "D. Coppersmith, M. Franklin, J. Patarin, M. Reiter, \"Low-Exponent RSA with Related Messages\", EUROCRYPT 1996",
+		Requires:    []attacks.Input{attacks.InputCipherText, attacks.InputKnownPlainText},
+		MinKeys:     1,
+		MaxKeys:     2,
+		Suits:       []attacks.Feature{attacks.FeatureMultipleKeys, attacks.FeatureKnownPlainText},
+		Unnatended:  true,
+		F:           Attack,
+	})
+}
+
 type sigAttack struct {
 	cs []*fmp.Fmpz
 	ss []*fmp.Fmpz
@@ -26,22 +43,22 @@ var (
 )
 
 // attempt runs the attack attempt itself.
-func (s *sigAttack) attempt(v bool) []byte {
+func (s *sigAttack) attempt(ctx context.Context, v bool) ([]byte, error) {
 	// f = (x-s1+s2)^e - c1
-	ctx := modctx(s.n)
-	f := modpoly(ctx).SetCoeffUI(1, 1)
-	f.Sub(f, modpoly(ctx).SetCoeff(0, s.ss[1])).Add(f, modpoly(ctx).SetCoeff(0, s.ss[0])).Pow(f, s.e)
-	f.Sub(f, modpoly(ctx).SetCoeff(0, s.cs[0]))
+	mctx := modctx(s.n)
+	f := modpoly(mctx).SetCoeffUI(1, 1)
+	f.Sub(f, modpoly(mctx).SetCoeff(0, s.ss[1])).Add(f, modpoly(mctx).SetCoeff(0, s.ss[0])).Pow(f, s.e)
+	f.Sub(f, modpoly(mctx).SetCoeff(0, s.cs[0]))
 
 	// g = x^e-c2
-	g := modpoly(ctx).SetCoeffUI(1, 1)
-	g.Pow(g, s.e).Sub(g, modpoly(ctx).SetCoeff(0, s.cs[1]))
+	g := modpoly(mctx).SetCoeffUI(1, 1)
+	g.Pow(g, s.e).Sub(g, modpoly(mctx).SetCoeff(0, s.cs[1]))
 
 	a := modpoly(modctx(f.GetMod())).Set(f)
 	b := modpoly(modctx(g.GetMod())).Set(g)
 
-	zero := modpoly(ctx).Zero()
-	rp := modpoly(ctx)
+	zero := modpoly(mctx).Zero()
+	rp := modpoly(mctx)
 
 	if v {
 		log.Printf("%s beginning, this can sometimes crash, try it again if it does.", name)
@@ -49,15 +66,19 @@ func (s *sigAttack) attempt(v bool) []byte {
 
 	var r *fmp.FmpzModPoly
 	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		_, r = a.DivRem(b)
 
 		if r.Equal(zero) {
 			co0 := rp.GetCoeff(0)
 			co1 := rp.GetCoeff(1)
 
-			q, _ := modpoly(ctx).SetCoeff(0, ln.BigOne).DivRem(modpoly(ctx).SetCoeff(0, co1))
+			q, _ := modpoly(mctx).SetCoeff(0, ln.BigOne).DivRem(modpoly(mctx).SetCoeff(0, co1))
 			q.MulScalar(q, ln.BigNOne).MulScalar(q, co0)
-			return ln.NumberToBytes(q.GetCoeff(0))
+			return ln.NumberToBytes(q.GetCoeff(0)), nil
 		}
 
 		rp.Set(r)
@@ -66,34 +87,45 @@ func (s *sigAttack) attempt(v bool) []byte {
 	}
 }
 
-// Attack implements the franklin reiter related message attack against two keys.
-func Attack(ks []*keys.RSA, ch chan error) {
-	if len(ks) != 2 {
-		ch <- fmt.Errorf("%s requires exactly 2 keys to work - got %d", name, len(ks))
-		return
-	}
+// Attack implements the franklin reiter related message attack against two ciphertexts of one key,
+// or against two keys sharing a modulus.
+func Attack(ctx context.Context, ks []*keys.RSA) (*keys.Result, error) {
+	var cs, ss [][]byte
+	switch len(ks) {
+	case 1:
+		cs, ss = ks[0].AllCipherTexts(), ks[0].KnownPlainTexts
+		if len(cs) < 2 || len(ss) < 2 {
+			return nil, fmt.Errorf("%s requires a key with 2 ciphertexts and their known plaintext components, or 2 keys", name)
+		}
+	case 2:
+		if ks[0].KnownPlainText == nil || ks[1].KnownPlainText == nil {
+			return nil, fmt.Errorf("%s requires each key has a corresponding known plaintext component", name)
+		}
 
-	if ks[0].KnownPlainText == nil || ks[1].KnownPlainText == nil {
-		ch <- fmt.Errorf("%s requires each key has a corresponding known plaintext component", name)
-		return
-	}
+		if ks[0].CipherText == nil || ks[1].CipherText == nil {
+			return nil, fmt.Errorf("%s requires each key has a corresponding ciphertext", name)
+		}
 
-	if ks[0].CipherText == nil || ks[1].CipherText == nil {
-		ch <- fmt.Errorf("%s requires each key has a corresponding ciphertext", name)
-		return
+		cs = [][]byte{ks[0].CipherText, ks[1].CipherText}
+		ss = [][]byte{ks[0].KnownPlainText, ks[1].KnownPlainText}
+	default:
+		return nil, fmt.Errorf("%s requires 1 or 2 keys to work - got %d", name, len(ks))
 	}
 
 	sa := &sigAttack{n: ks[0].Key.N, e: ks[0].Key.PublicKey.E.GetInt()}
 	for i := 0; i < 2; i++ {
-		sa.ss = append(sa.ss, ln.BytesToNumber(ks[i].KnownPlainText))
-		sa.cs = append(sa.cs, ln.BytesToNumber(ks[i].CipherText))
+		sa.ss = append(sa.ss, ln.BytesToNumber(ss[i]))
+		sa.cs = append(sa.cs, ln.BytesToNumber(cs[i]))
+	}
+
+	res, err := sa.attempt(ctx, ks[0].Verbose)
+	if err != nil {
+		return nil, err
 	}
 
-	if res := sa.attempt(ks[0].Verbose); res != nil {
-		ks[0].PlainText = res
-		ch <- nil
-		return
+	if res != nil {
+		return &keys.Result{PlainTexts: [][]byte{res}}, nil
 	}
 
-	ch <- fmt.Errorf("%s failed to recover the plaintext", name)
+	return nil, fmt.Errorf("%s failed to recover the plaintext", name)
 }
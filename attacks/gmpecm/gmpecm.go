@@ -1,8 +1,10 @@
 package gmpecm
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/ln"
 
 	"github.com/sourcekris/goRsaTool/keys"
@@ -14,8 +16,19 @@ import (
 // name is the name of this attack.
 const name = "gmp-ecm elliptic curve factorization"
 
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "ecm",
+		Description: "Elliptic curve factorization using GMP-ECM.",
+		Category:    attacks.CategoryFactorization,
+		Reference:   "H. W. Lenstra, \"Factoring Integers with Elliptic Curves\", Annals of Mathematics 1987",
+		Unnatended:  true,
+		F:           Attack,
+	})
+}
+
 // Attack implements the elliptic curve factorization attack against public keys.
-func Attack(ks []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ks []*keys.RSA) (*keys.Result, error) {
 	var (
 		k   = ks[0]
 		res = new(ecm.Mpz)
@@ -23,27 +36,22 @@ func Attack(ks []*keys.RSA, ch chan error) {
 
 	n, ok := new(ecm.Mpz).SetString(k.Key.N.String(), 10)
 	if !ok {
-		ch <- fmt.Errorf("%s failed to construct an Mpz from modulus", name)
-		return
+		return nil, fmt.Errorf("%s failed to construct an Mpz from modulus", name)
 	}
 
 	p := ecm.NewParams()
 	res, err := p.Factor(n)
 	if err != nil {
-		ch <- err
-		return
+		return nil, err
 	}
 
 	ff, ok := new(fmp.Fmpz).SetString(res.String(), 10)
 	if !ok {
-		ch <- fmt.Errorf("%s failed to parse the factor: %q", name, res.String())
-		return
+		return nil, fmt.Errorf("%s failed to parse the factor: %q", name, res.String())
 	}
 	if ff.Cmp(ln.BigZero) > 0 {
-		k.PackGivenP(ff)
-		ch <- nil
-		return
+		return &keys.Result{Factors: []*fmp.Fmpz{ff}}, nil
 	}
 
-	ch <- fmt.Errorf("%s was unable to factor the key", name)
+	return nil, fmt.Errorf("%s was unable to factor the key", name)
 }
@@ -1,9 +1,11 @@
 package hastads
 
 import (
+	"context"
 	"fmt"
 	"log"
 
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 
@@ -13,17 +15,30 @@ import (
 // name is the name of this attack.
 const name = "hastads"
 
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "hastads",
+		Aliases:     []string{"smalle"},
+		Description: "Recover the plaintext of a small exponent ciphertext by taking integer roots.",
+		Category:    attacks.CategoryPlaintext,
+		Reference:   "J. Hastad, \"Solving Simultaneous Modular Equations of Low Degree\", SIAM J. Comput. 1988",
+		Requires:    []attacks.Input{attacks.InputCipherText},
+		Expects:     []attacks.Feature{attacks.FeatureSmallE},
+		Suits:       []attacks.Feature{attacks.FeatureSmallCipherText},
+		Unnatended:  true,
+		F:           Attack,
+	})
+}
+
 // Attack implements the Hastads attack.
-func Attack(ts []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ts []*keys.RSA) (*keys.Result, error) {
 	t := ts[0]
 	if t.Key.D != nil || t.Key.PublicKey.E.Cmp(ln.BigEleven) > 0 {
-		ch <- nil
-		return
+		return nil, nil
 	}
 
 	if t.CipherText == nil {
-		ch <- fmt.Errorf("%s failed - ciphertext needs to be provided for this attack", name)
-		return
+		return nil, fmt.Errorf("%s failed - ciphertext needs to be provided for this attack", name)
 	}
 
 	c := ln.BytesToNumber(t.CipherText)
@@ -36,7 +51,14 @@ func Attack(ts []*keys.RSA, ch chan error) {
 	m := new(fmp.Fmpz)
 	pow := new(fmp.Fmpz)
 	original := new(fmp.Fmpz).Set(c)
+	progress := attacks.ProgressFrom(ctx)
+	progress.Start("multiples of n", 0)
 	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		progress.Add(1)
+
 		m.Root(c, int32(t.Key.PublicKey.E.Int64()))
 		pow.Exp(m, t.Key.PublicKey.E, t.Key.N)
 
@@ -47,6 +69,5 @@ func Attack(ts []*keys.RSA, ch chan error) {
 		c.Add(c, t.Key.N)
 	}
 
-	t.PlainText = ln.NumberToBytes(pt)
-	ch <- nil
+	return &keys.Result{PlainTexts: [][]byte{ln.NumberToBytes(pt)}}, nil
 }
@@ -1,8 +1,10 @@
 package hastadsbroadcast
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 
@@ -12,23 +14,36 @@ import (
 // name is the name of this attack.
 const name = "hastads broadcast"
 
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "hastadsbroadcast",
+		Description: "Recover a message encrypted to e or more keys with the same small exponent.",
+		Category:    attacks.CategoryPlaintext,
+		Reference:   "J. Hastad, \"Solving Simultaneous Modular Equations of Low Degree\", SIAM J. Comput. 1988",
+		Requires:    []attacks.Input{attacks.InputCipherText},
+		MinKeys:     2,
+		MaxKeys:     attacks.AnyNumberOfKeys,
+		Expects:     []attacks.Feature{attacks.FeatureSmallE},
+		Suits:       []attacks.Feature{attacks.FeatureMultipleKeys},
+		Unnatended:  true,
+		F:           Attack,
+	})
+}
+
 // Attack implements the hastads broadcast attack against three keys and their ciphertexts.
-func Attack(ks []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ks []*keys.RSA) (*keys.Result, error) {
 	// Check key parameters are compatible with the attack.
 	if len(ks) < 2 {
-		ch <- fmt.Errorf("%s attack requires 2+ public keys, got: %d", name, len(ks))
-		return
+		return nil, fmt.Errorf("%s attack requires 2+ public keys, got: %d", name, len(ks))
 	}
 	for _, k := range ks {
 		if k.CipherText == nil {
-			ch <- fmt.Errorf("%s failed - supply ciphertext for each key", name)
-			return
+			return nil, fmt.Errorf("%s failed - supply ciphertext for each key", name)
 		}
 
 		// It's possible this works for other small primes though.
 		if k.Key.PublicKey.E.Cmp(ln.BigThree) > 0 {
-			ch <- fmt.Errorf("%s failed - exponents should be 3 but key exponent is: %v", name, k.Key.PublicKey.E)
-			return
+			return nil, fmt.Errorf("%s failed - exponents should be 3 but key exponent is: %v", name, k.Key.PublicKey.E)
 		}
 	}
 
@@ -46,10 +61,8 @@ func Attack(ks []*keys.RSA, ch chan error) {
 
 	test := new(fmp.Fmpz).ExpXIM(solution, k, ks[0].Key.N)
 	if test.Equals(ln.BytesToNumber(ks[0].CipherText)) {
-		ks[0].PlainText = ln.NumberToBytes(solution)
-		ch <- nil
-		return
+		return &keys.Result{PlainTexts: [][]byte{ln.NumberToBytes(solution)}}, nil
 	}
 
-	ch <- fmt.Errorf("%s attack failed", name)
+	return nil, fmt.Errorf("%s attack failed", name)
 }
@@ -1,6 +1,7 @@
 package hastadsbroadcast
 
 import (
+	"context"
 	"testing"
 
 	"github.com/sourcekris/goRsaTool/keys"
@@ -54,9 +55,10 @@ func TestAttack(t *testing.T) {
 			}),
 		})
 
-		ch := make(chan error)
-		go Attack(ks, ch)
-		err := <-ch
+		r, err := Attack(context.Background(), ks)
+		if err == nil {
+			err = keys.Merge(ks, r)
+		}
 		if err != nil {
 			t.Errorf("%s failed - got unexpected error: %v", tc.name, err)
 		}
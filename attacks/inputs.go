@@ -0,0 +1,101 @@
+package attacks
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sourcekris/goRsaTool/keys"
+)
+
+// Input is a piece of information, beyond the public key, that an attack needs to work.
+type Input string
+
+// The inputs an attack can require.
+const (
+	InputCipherText        Input = "ciphertext"
+	InputKnownPlainText    Input = "known plaintext"
+	InputPrime             Input = "a known prime"
+	InputDLSB              Input = "LSBs of d"
+	InputCRTValues         Input = "CRT values"
+	InputHints             Input = "two hints"
+	InputOracleCipherTexts Input = "oracle ciphertexts of 2, 3, 4 and 9"
+	InputPastPrimes        Input = "past primes file"
+)
+
+// satisfiedBy returns true if k provides the input.
+func (i Input) satisfiedBy(k *keys.RSA) bool {
+	switch i {
+	case InputCipherText:
+		return len(k.CipherText) > 0
+	case InputKnownPlainText:
+		return len(k.KnownPlainText) > 0
+	case InputPrime:
+		return len(k.Key.Primes) > 0
+	case InputDLSB:
+		return len(k.DLSB) > 0
+	case InputCRTValues:
+		return k.Key.Precomputed != nil && k.Key.Precomputed.Dp != nil && k.Key.Precomputed.Dq != nil
+	case InputHints:
+		return len(k.Hints) >= 2
+	case InputOracleCipherTexts:
+		for _, m := range []int{2, 3, 4, 9} {
+			if _, ok := k.OracleCiphertexts[m]; !ok {
+				return false
+			}
+		}
+		return true
+	case InputPastPrimes:
+		if k.PastPrimesFile == "" {
+			return false
+		}
+		_, err := os.Stat(k.PastPrimesFile)
+		return err == nil
+	}
+
+	return false
+}
+
+// SkipError is returned instead of running an attack whose prerequisites are not met.
+type SkipError struct {
+	Name string
+	// Needs describes each missing prerequisite, e.g. "ciphertext" or "2 keys".
+	Needs []string
+}
+
+func (e *SkipError) Error() string {
+	return fmt.Sprintf("%s skipped: needs %s", e.Name, strings.Join(e.Needs, ", "))
+}
+
+// Check returns a *SkipError if ks does not provide what the attack needs, or nil if the attack
+// can run. Only the keys the attack will use are checked.
+func (at *Attack) Check(ks []*keys.RSA) error {
+	var needs []string
+	if min := at.minKeys(); len(ks) < min {
+		need := "a key"
+		if min > 1 {
+			need = fmt.Sprintf("%d keys", min)
+		}
+		needs = append(needs, need)
+	}
+
+	used := ks
+	if max := at.maxKeys(); max != AnyNumberOfKeys && len(used) > max {
+		used = used[:max]
+	}
+
+	for _, i := range at.Requires {
+		for _, k := range used {
+			if !i.satisfiedBy(k) {
+				needs = append(needs, string(i))
+				break
+			}
+		}
+	}
+
+	if needs != nil {
+		return &SkipError{Name: at.Name, Needs: needs}
+	}
+
+	return nil
+}
@@ -38,7 +38,8 @@ func pkcs1v15Encode(msg []byte, n int) []byte {
 func getMagic(jwt []byte, e *fmp.Fmpz) (*fmp.Fmpz, error) {
 	var header, payload, sig string
 
-	sj := strings.Split(string(jwt), ".")
+	// JWT files are text, ignore the line ending an editor may have added.
+	sj := strings.Split(strings.TrimSpace(string(jwt)), ".")
 	if sj == nil || len(sj) != 3 {
 		return nil, errors.New("unable to parse JWT")
 	}
@@ -56,15 +57,15 @@ func getMagic(jwt []byte, e *fmp.Fmpz) (*fmp.Fmpz, error) {
 	return new(fmp.Fmpz).ExpXY(sigNum, e).SubZ(padNum), nil
 }
 
-// Attack calculates an RSA modulus given two known JWTs. If no public exponent is given then it
+// Recover calculates an RSA modulus given two known JWTs. If no public exponent is given then it
 // is assumed to be 65537.
-func Attack(jwtlist []string, exp string) error {
+func Recover(jwtlist []string, exp string) (*keys.RSA, error) {
 	var (
 		magics []*fmp.Fmpz
 	)
 
 	if len(jwtlist) != 2 {
-		return fmt.Errorf("JWT mode requires 2 JWTs")
+		return nil, fmt.Errorf("JWT mode requires 2 JWTs")
 	}
 
 	e, ok := new(fmp.Fmpz).SetString(exp, 10)
@@ -75,11 +76,11 @@ func Attack(jwtlist []string, exp string) error {
 	for _, j := range jwtlist {
 		jwt, err := utils.ReadBinary(j)
 		if err != nil {
-			return fmt.Errorf("error reading JWTs %s: %v", j, err)
+			return nil, fmt.Errorf("error reading JWTs %s: %v", j, err)
 		}
 		m, err := getMagic(jwt, e)
 		if err != nil {
-			return fmt.Errorf("error reading JWTs %s: %v", j, err)
+			return nil, fmt.Errorf("error reading JWTs %s: %v", j, err)
 		}
 		magics = append(magics, m)
 	}
@@ -88,10 +89,19 @@ func Attack(jwtlist []string, exp string) error {
 
 	k, err := keys.NewRSA(keys.PrivateFromPublic(&keys.FMPPublicKey{N: res, E: e}), nil, nil, "", false)
 	if err != nil {
-		return fmt.Errorf("%s failed to construct rsa key once modulus was recovered", name)
+		return nil, fmt.Errorf("%s failed to construct rsa key once modulus was recovered", name)
+	}
+
+	return k, nil
+}
+
+// Attack prints the public key found by Recover encoded in format f.
+func Attack(jwtlist []string, exp string, f keys.Format) error {
+	k, err := Recover(jwtlist, exp)
+	if err != nil {
+		return err
 	}
 
 	fmt.Println("Recovered public key:")
-	fmt.Println(keys.EncodeFMPPublicKey(k.Key.PublicKey))
-	return nil
+	return utils.PrintPublicKey(k.Key.PublicKey, f)
 }
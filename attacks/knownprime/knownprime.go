@@ -1,9 +1,11 @@
 package knownprime
 
 import (
+	"context"
 	"fmt"
 	"log"
 
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 	fmp "github.com/sourcekris/goflint"
@@ -12,30 +14,36 @@ import (
 // name is the name of this attack.
 const name = "knownprime"
 
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "knownprime",
+		Description: "Build the private key from one known prime factor.",
+		Category:    attacks.CategoryFactorization,
+		Requires:    []attacks.Input{attacks.InputPrime},
+		F:           Attack,
+	})
+}
+
 // Attack implements the knownprime attack.
-func Attack(ts []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ts []*keys.RSA) (*keys.Result, error) {
 	t := ts[0]
 	if t.Key.D != nil {
-		ch <- nil
-		return
+		return nil, nil
 	}
 
 	if t.Key.Primes == nil {
-		ch <- fmt.Errorf("%s attack failed, no prime provided. Use the -p flag or provide a 'p = ' field in the key", name)
-		return
+		return nil, fmt.Errorf("%s attack failed, no prime provided. Use the -p flag or provide a 'p = ' field in the key", name)
 	}
 
 	// Sanity check the prime is actually a factor of n.
 	f := new(fmp.Fmpz).Mod(t.Key.N, t.Key.Primes[0])
 	if f.Cmp(ln.BigZero) != 0 {
-		ch <- fmt.Errorf("provided prime is not a factor of n: p %% n = %v", f)
-		return
+		return nil, fmt.Errorf("provided prime is not a factor of n: p %% n = %v", f)
 	}
 
 	if t.Verbose {
 		log.Printf("%s attempt beginning", name)
 	}
 
-	t.PackGivenP(t.Key.Primes[0])
-	ch <- nil
+	return &keys.Result{Factors: []*fmp.Fmpz{t.Key.Primes[0]}}, nil
 }
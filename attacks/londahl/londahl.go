@@ -4,10 +4,12 @@
 package londahl
 
 import (
+	"context"
 	"fmt"
 	"hash/fnv"
 	"log"
 
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 
@@ -17,6 +19,21 @@ import (
 // name is the name of this attack.
 const name = "londahl"
 
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "londahl",
+		Description: "Factor moduli whose primes are close using a baby-step giant-step search for phi.",
+		Category:    attacks.CategoryFactorization,
+		Reference:   "https://github.com/grocid/CTF/tree/master/IceCTF/2016#l33tcrypt",
+		Unnatended:  true,
+		Params:      []*attacks.Param{bound},
+		F:           Attack,
+	})
+}
+
+// bound is the boundary for the londahl attack, it is the size of the baby step lookup table.
+var bound = &attacks.Param{Name: "b", Description: "The number of baby steps, bounds how far phi can be from its approximation.", Default: int64(20000000)}
+
 func factorizeNPhi(n, phi *fmp.Fmpz) (*fmp.Fmpz, *fmp.Fmpz) {
 	m := new(fmp.Fmpz).Sub(n, phi).AddI(1)
 	i := new(fmp.Fmpz).Root(new(fmp.Fmpz).Sub(new(fmp.Fmpz).ExpXI(m, 2), new(fmp.Fmpz).Mul(n, ln.BigFour)), 2)
@@ -37,17 +54,15 @@ func storeInt(z, n *fmp.Fmpz, m map[uint64]int64, i int64) {
 }
 
 // Attack implements the Londahl attack.
-func Attack(ts []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ts []*keys.RSA) (*keys.Result, error) {
 	t := ts[0]
 	if t.Key.D != nil {
-		ch <- nil
-		return
+		return nil, nil
 	}
 	// Create a pointer where we can store the result.
 	p := new(fmp.Fmpz)
 
-	// Boundary for the londahl attack.
-	var b int64 = 20000000
+	b := bound.Int()
 
 	if t.Verbose {
 		log.Printf("%s attempt beginning", name)
@@ -56,8 +71,16 @@ func Attack(ts []*keys.RSA, ch chan error) {
 	var lookup = make(map[uint64]int64)
 	phiApprox := new(fmp.Fmpz).Add(new(fmp.Fmpz).Sub(t.Key.N, new(fmp.Fmpz).Mul(new(fmp.Fmpz).Root(t.Key.N, 2), ln.BigTwo)), ln.BigOne)
 	// Generate a lookup table, store just the fnv hash of the integer to save memory.
+	progress := attacks.ProgressFrom(ctx)
+	progress.Start("baby steps", b+1)
+
 	z := fmp.NewFmpz(1)
 	for i := int64(0); i <= b; i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		progress.Add(1)
+
 		storeInt(z, t.Key.N, lookup, i)
 		z = z.Lsh(1).ModZ(t.Key.N)
 	}
@@ -65,22 +88,57 @@ func Attack(ts []*keys.RSA, ch chan error) {
 	mu := new(fmp.Fmpz).ModInverse(new(fmp.Fmpz).Pow(ln.BigTwo, phiApprox, t.Key.N), t.Key.N)
 	fac := new(fmp.Fmpz).ExpXIM(ln.BigTwo, int(b), t.Key.N)
 
-	for i := int64(0); i <= b; i++ {
+	// The lookup table is cheap to rebuild so only the giant steps are checkpointed, and only
+	// resumed if the bound has not changed.
+	var (
+		start int64
+		slot  = attacks.CheckpointSlot(ctx, t.Key.N)
+	)
+	if s := slot.Resume(); s != nil && s.Int("mu") != nil {
+		if sb, _ := s.Int64("b"); sb == b {
+			start, _ = s.Int64("i")
+			mu = s.Int("mu")
+		}
+	}
+
+	save := func(i int64) {
+		s := attacks.State{}
+		s.SetInt64("b", b)
+		s.SetInt64("i", i)
+		s.SetInt("mu", mu)
+		slot.Save(s)
+	}
+
+	progress.Start("giant steps", b+1)
+	progress.Resume(start)
+
+	for i := start; i <= b; i++ {
+		if err := ctx.Err(); err != nil {
+			save(i)
+			return nil, err
+		}
+		progress.Add(1)
+
+		if slot.Due() {
+			save(i)
+		}
+
 		h := fnv.New64()
 		h.Write(mu.Bytes())
 		if v, ok := lookup[h.Sum64()]; ok {
 			phi := new(fmp.Fmpz).Add(phiApprox, fmp.NewFmpz(v-(i*b)))
 			r1, _ := factorizeNPhi(t.Key.N, phi)
 			if r1 != nil {
+				slot.Clear()
 				p.Set(r1)
-				t.PackGivenP(p)
-				ch <- nil
-				return
+				return &keys.Result{Factors: []*fmp.Fmpz{p}}, nil
 			}
 		}
 
 		mu = mu.Mul(mu, fac).ModZ(t.Key.N)
 	}
 
-	ch <- fmt.Errorf("%s failed to recover the private key", name)
+	slot.Clear()
+
+	return nil, fmt.Errorf("%s failed to recover the private key", name)
 }
@@ -1,9 +1,12 @@
 package manysmallprimes
 
 import (
+	"context"
+	"fmt"
 	"log"
 
 	"github.com/jbarham/primegen"
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 
@@ -13,42 +16,63 @@ import (
 // name is the name of this attack.
 const name = "manysmallprimes"
 
-// Attack iterates small primes until we timeout and test them as factors of N.
-func Attack(ts []*keys.RSA, ch chan error) {
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "manysmallprimes",
+		Description: "Factor multi-prime moduli made up of many small primes.",
+		Category:    attacks.CategoryFactorization,
+		Unnatended:  true,
+		F:           Attack,
+	})
+}
+
+// Attack iterates small primes until we timeout and test them as factors of N. It stops once the
+// primes found multiply to N, or when it found NumPrimes of them if the key says how many there
+// are. Cofactor keys don't.
+func Attack(ctx context.Context, ts []*keys.RSA) (*keys.Result, error) {
 
 	var (
 		p         = primegen.New()
 		primeList []*fmp.Fmpz
+		product   = fmp.NewFmpz(1)
 		t         = ts[0]
 	)
 
 	if t.Key.D != nil {
-		ch <- nil
-		return
+		return nil, nil
 	}
 
 	if t.Verbose {
 		log.Printf("%s attempt beginning", name)
 	}
 
+	progress := attacks.ProgressFrom(ctx)
+	progress.Start("primes", 0)
+
 	for {
 		pc := new(fmp.Fmpz)
 		modp := new(fmp.Fmpz)
 		for {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			progress.Add(1)
+
 			pc.SetUint64(p.Next())
 			if modp.Mod(t.Key.N, pc).Equals(ln.BigZero) {
 				primeList = append(primeList, new(fmp.Fmpz).Set(pc))
-				if len(primeList) == t.NumPrimes {
+				product.MulZ(pc)
+
+				if product.Equals(t.Key.N) {
 					if t.Verbose {
 						log.Printf("found these primes %v", primeList)
 					}
-					if err := t.PackMultiPrime(primeList); err != nil {
 
-						ch <- err
-						return
-					}
-					ch <- nil
-					return
+					return &keys.Result{Factors: primeList}, nil
+				}
+
+				if len(primeList) == t.NumPrimes {
+					return nil, fmt.Errorf("%s failed - product of the %d primes found does not equal N", name, len(primeList))
 				}
 			}
 		}
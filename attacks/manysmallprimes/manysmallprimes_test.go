@@ -1,6 +1,7 @@
 package manysmallprimes
 
 import (
+	"context"
 	"testing"
 
 	"github.com/sourcekris/goRsaTool/keys"
@@ -45,6 +46,21 @@ func TestAttack(t *testing.T) {
 				ln.FmpString("44633"),
 			},
 		},
+		{
+			name: "cofactor key with an unknown number of primes expected to factor",
+			n:    ln.FmpString("32783767296202020287911964765021565179"),
+			e:    fmp.NewFmpz(65537),
+			want: []*fmp.Fmpz{
+				ln.FmpString("42953"),
+				ln.FmpString("42821"),
+				ln.FmpString("49919"),
+				ln.FmpString("62477"),
+				ln.FmpString("64231"),
+				ln.FmpString("46171"),
+				ln.FmpString("43177"),
+				ln.FmpString("44633"),
+			},
+		},
 		{
 			name:    "vulnerable key with 8 primes but we ask for only 6",
 			n:       ln.FmpString("32783767296202020287911964765021565179"),
@@ -58,9 +74,10 @@ func TestAttack(t *testing.T) {
 	for _, tc := range tt {
 		k, _ := keys.NewRSA(keys.PrivateFromPublic(&keys.FMPPublicKey{N: tc.n, E: tc.e}), nil, nil, "", false)
 		k.NumPrimes = tc.numP
-		ch := make(chan error)
-		go Attack([]*keys.RSA{k}, ch)
-		err := <-ch
+		r, err := Attack(context.Background(), []*keys.RSA{k})
+		if err == nil {
+			err = keys.Merge([]*keys.RSA{k}, r)
+		}
 		if err != nil && !tc.wantErr {
 			t.Errorf("Attack() failed: %s expected no error got error: %v", tc.name, err)
 		}
@@ -73,8 +90,8 @@ func TestAttack(t *testing.T) {
 			t.Errorf("Attack() failed: %s d not found", tc.name)
 		}
 
-		if len(k.Key.Primes) != tc.numP && !tc.wantErr {
-			t.Errorf("Attack() failed: %s returned wrong number of primes, wanted %d got %d", tc.name, tc.numP, len(k.Key.Primes))
+		if len(k.Key.Primes) != len(tc.want) && !tc.wantErr {
+			t.Errorf("Attack() failed: %s returned wrong number of primes, wanted %d got %d", tc.name, len(tc.want), len(k.Key.Primes))
 		}
 
 		if !tc.wantErr && !utils.FoundP(tc.want[0], k.Key.Primes) {
@@ -1,9 +1,11 @@
 package notableprimes
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 
@@ -13,8 +15,20 @@ import (
 // name is the name of this attack.
 const name = "notable primes"
 
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "notableprimes",
+		Aliases:     []string{"mersenne", "lucas", "novelty"},
+		Description: "Try Mersenne primes, Lucas primes and novelty primes as factors.",
+		Category:    attacks.CategoryFactorization,
+		Unnatended:  true,
+		Params:      []*attacks.Param{maxnoveltylen},
+		F:           Attack,
+	})
+}
+
 // maxnoveltylen is the maximum number of digits to test for a 31337 prime.
-const maxnoveltylen = 2000
+var maxnoveltylen = &attacks.Param{Name: "maxnoveltylen", Description: "The most digits to try for primes of the form 31337 and 13337.", Default: int64(2000)}
 
 // mersenneExponents lists the 6th to 51st mersenne prime number exponents.
 var mersenneExponents = []int{17, 19, 31, 61, 89, 107, 127, 521, 607, 1279, 2203, 2281, 3217, 4253,
@@ -48,34 +62,38 @@ func lucasNumber(n int) *fmp.Fmpz {
 // TODO(kris): Add phi, GF and other notable primes.
 
 // Attack checks the key modulus to see if it factors with any notable primes.
-func Attack(ks []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ks []*keys.RSA) (*keys.Result, error) {
 	k := ks[0]
 
 	// Test for primes of the form 313333337.
-	for i := 0; i < (maxnoveltylen - 4); i++ {
-		p, _ := new(fmp.Fmpz).SetString("3133"+strings.Repeat("3", i)+"7", 10)
+	for i := int64(0); i < (maxnoveltylen.Int() - 4); i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		p, _ := new(fmp.Fmpz).SetString("3133"+strings.Repeat("3", int(i))+"7", 10)
 		if p.Cmp(k.Key.N) > 0 {
 			break
 		}
 
 		if new(fmp.Fmpz).Mod(k.Key.N, p).Equals(ln.BigZero) {
-			k.PackGivenP(p)
-			ch <- nil
-			return
+			return &keys.Result{Factors: []*fmp.Fmpz{p}}, nil
 		}
 	}
 
 	// Test for primes of the form 133333337.
-	for i := 0; i < (maxnoveltylen - 4); i++ {
-		p, _ := new(fmp.Fmpz).SetString("133"+strings.Repeat("3", i)+"7", 10)
+	for i := int64(0); i < (maxnoveltylen.Int() - 4); i++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		p, _ := new(fmp.Fmpz).SetString("133"+strings.Repeat("3", int(i))+"7", 10)
 		if p.Cmp(k.Key.N) > 0 {
 			break
 		}
 
 		if new(fmp.Fmpz).Mod(k.Key.N, p).Equals(ln.BigZero) {
-			k.PackGivenP(p)
-			ch <- nil
-			return
+			return &keys.Result{Factors: []*fmp.Fmpz{p}}, nil
 		}
 	}
 
@@ -88,9 +106,7 @@ func Attack(ks []*keys.RSA, ch chan error) {
 		}
 
 		if new(fmp.Fmpz).Mod(k.Key.N, mp).Equals(ln.BigZero) {
-			k.PackGivenP(mp)
-			ch <- nil
-			return
+			return &keys.Result{Factors: []*fmp.Fmpz{mp}}, nil
 		}
 	}
 
@@ -102,11 +118,9 @@ func Attack(ks []*keys.RSA, ch chan error) {
 		}
 
 		if new(fmp.Fmpz).Mod(k.Key.N, lnum).Equals(ln.BigZero) {
-			k.PackGivenP(lnum)
-			ch <- nil
-			return
+			return &keys.Result{Factors: []*fmp.Fmpz{lnum}}, nil
 		}
 	}
 
-	ch <- fmt.Errorf("%s was unable to factor the key", name)
+	return nil, fmt.Errorf("%s was unable to factor the key", name)
 }
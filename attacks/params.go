@@ -0,0 +1,141 @@
+package attacks
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Param is a named tuning parameter of an attack, e.g. a search bound, that can be changed from
+// the command line with -opt attack.name=value.
+type Param struct {
+	Name        string
+	Description string
+	// Default is the value used when the parameter is not set. Its type is the parameter's type
+	// and must be one of int64, bool or time.Duration.
+	Default interface{}
+	value   interface{}
+}
+
+// Type returns the name of the parameter's type, prefixed with "unsupported" when Default is not
+// one of the supported types.
+func (p *Param) Type() string {
+	switch p.Default.(type) {
+	case int64:
+		return "int"
+	case bool:
+		return "bool"
+	case time.Duration:
+		return "duration"
+	}
+
+	return fmt.Sprintf("unsupported %T", p.Default)
+}
+
+// Set parses s according to the parameter's type and uses it in place of the default.
+func (p *Param) Set(s string) error {
+	var (
+		v   interface{}
+		err error
+	)
+
+	switch p.Default.(type) {
+	case int64:
+		v, err = strconv.ParseInt(s, 0, 64)
+	case bool:
+		v, err = strconv.ParseBool(s)
+	case time.Duration:
+		v, err = time.ParseDuration(s)
+	default:
+		return fmt.Errorf("parameter %s has unsupported type %s", p.Name, p.Type())
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid %s value %q for parameter %s", p.Type(), s, p.Name)
+	}
+	p.value = v
+
+	return nil
+}
+
+// Value returns the value set for the parameter or its default.
+func (p *Param) Value() interface{} {
+	if p.value != nil {
+		return p.value
+	}
+
+	return p.Default
+}
+
+// Int returns the value of an int parameter.
+func (p *Param) Int() int64 {
+	v, _ := p.Value().(int64)
+	return v
+}
+
+// Bool returns the value of a bool parameter.
+func (p *Param) Bool() bool {
+	v, _ := p.Value().(bool)
+	return v
+}
+
+// Duration returns the value of a duration parameter.
+func (p *Param) Duration() time.Duration {
+	v, _ := p.Value().(time.Duration)
+	return v
+}
+
+// TimeoutParam is the name of the parameter every attack accepts to override its timeout.
+const TimeoutParam = "timeout"
+
+// param returns the attack's parameter called name or nil.
+func (at *Attack) param(name string) *Param {
+	for _, p := range at.Params {
+		if p.Name == name {
+			return p
+		}
+	}
+
+	return nil
+}
+
+// SetOption sets an attack parameter from an option of the form attack.name=value where attack is
+// an attack name or alias. Every attack also accepts a timeout duration, e.g. fermat.timeout=10m.
+func (a *Attacks) SetOption(opt string) error {
+	kv := strings.SplitN(opt, "=", 2)
+	if len(kv) != 2 {
+		return fmt.Errorf("invalid option %q: expected attack.name=value", opt)
+	}
+
+	an := strings.SplitN(kv[0], ".", 2)
+	if len(an) != 2 {
+		return fmt.Errorf("invalid option %q: expected attack.name=value", opt)
+	}
+
+	at := a.Lookup(an[0])
+	if at == nil {
+		return fmt.Errorf("invalid option %q: unsupported attack %s", opt, an[0])
+	}
+
+	if an[1] == TimeoutParam {
+		d, err := time.ParseDuration(kv[1])
+		if err != nil || d < time.Second {
+			return fmt.Errorf("invalid option %q: timeout must be a duration of at least 1s", opt)
+		}
+		at.Timeout = int(d / time.Second)
+
+		return nil
+	}
+
+	p := at.param(an[1])
+	if p == nil {
+		return fmt.Errorf("invalid option %q: %s has no parameter %s, use -describe %s to list them", opt, at.Name, an[1], at.Name)
+	}
+
+	if err := p.Set(kv[1]); err != nil {
+		return fmt.Errorf("invalid option %q: %w", opt, err)
+	}
+
+	return nil
+}
@@ -2,9 +2,11 @@
 package partiald
 
 import (
+	"context"
 	"fmt"
 	"log"
 
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 	fmp "github.com/sourcekris/goflint"
@@ -13,19 +15,28 @@ import (
 // name is the name of this attack.
 const name = "partiald"
 
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "partiald",
+		Description: "Recover d given its least significant bits and a small exponent.",
+		Category:    attacks.CategoryExponent,
+		Reference:   "D. Boneh, G. Durfee, Y. Frankel, \"An Attack on RSA Given a Small Fraction of the Private Key Bits\", ASIACRYPT 1998",
+		Requires:    []attacks.Input{attacks.InputDLSB},
+		F:           Attack,
+	})
+}
+
 // Attack implements the Partial D attack.
-func Attack(ts []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ts []*keys.RSA) (*keys.Result, error) {
 
 	// Validate all the parameters are sane.
 	t := ts[0]
 	if t.Key.D != nil {
-		ch <- nil
-		return
+		return nil, nil
 	}
 
 	if t.DLSB == nil {
-		ch <- fmt.Errorf("%s failed - supply the LSB of 'd' using the -partiald flag or a 'd0 = ' field in the key", name)
-		return
+		return nil, fmt.Errorf("%s failed - supply the LSB of 'd' using the -partiald flag or a 'd0 = ' field in the key", name)
 	}
 
 	if t.Verbose {
@@ -43,8 +54,20 @@ func Attack(ts []*keys.RSA, ch chan error) {
 	a = a.Div(a, t.Key.PublicKey.E)
 	abits := t.Key.N.BitLen() - a.BitLen()
 
-	// Do the attack...
+	// Do the attack, k runs up to e which only gives a total when e fits an int64.
+	var total int64
+	if t.Key.PublicKey.E.BitLen() < 64 {
+		total = t.Key.PublicKey.E.Int64()
+	}
+
+	progress := attacks.ProgressFrom(ctx)
+	progress.Start("values of k", total)
 	for k := fmp.NewFmpz(1); k.Cmp(t.Key.PublicKey.E) <= 0; k.Add(k, ln.BigOne) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		progress.Add(1)
+
 		// Approximate d.
 		d := new(fmp.Fmpz).Mul(k, t.Key.N)
 		d = d.Add(d, ln.BigOne)
@@ -63,12 +86,10 @@ func Attack(ts []*keys.RSA, ch chan error) {
 			// test by encrypting and decrypting the integer 2.
 			m := new(fmp.Fmpz).Pow(new(fmp.Fmpz).Pow(ln.BigTwo, t.Key.PublicKey.E, t.Key.N), d, t.Key.N)
 			if m.Cmp(ln.BigTwo) == 0 {
-				t.PackGivenP(ln.FindPGivenD(d, t.Key.PublicKey.E, t.Key.N))
-				ch <- nil
-				return
+				return &keys.Result{Factors: []*fmp.Fmpz{ln.FindPGivenD(d, t.Key.PublicKey.E, t.Key.N)}, D: new(fmp.Fmpz).Set(d)}, nil
 			}
 		}
 	}
 
-	ch <- fmt.Errorf("%s failed to recover the private key", name)
+	return nil, fmt.Errorf("%s failed to recover the private key", name)
 }
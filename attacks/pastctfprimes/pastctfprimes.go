@@ -2,10 +2,12 @@ package pastctfprimes
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
 	"strings"
 
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 
@@ -15,20 +17,30 @@ import (
 // name is the name of this attack.
 const name = "past ctf primes"
 
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "pastctf",
+		Aliases:     []string{"pastprimes", "pastctfprimes"},
+		Description: "Try primes seen in past CTF challenges as factors.",
+		Category:    attacks.CategoryLookup,
+		Requires:    []attacks.Input{attacks.InputPastPrimes},
+		Unnatended:  true,
+		F:           Attack,
+	})
+}
+
 // Attack implements the PastCTFPrimes attack.
-func Attack(ts []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ts []*keys.RSA) (*keys.Result, error) {
 	t := ts[0]
 	if t.Key.D != nil {
-		ch <- nil
-		return
+		return nil, nil
 	}
 
 	var primes []fmp.Fmpz
 
 	file, err := os.Open(t.PastPrimesFile)
 	if err != nil {
-		ch <- err
-		return
+		return nil, err
 	}
 
 	defer file.Close()
@@ -45,14 +57,20 @@ func Attack(ts []*keys.RSA, ch chan error) {
 
 	modp := new(fmp.Fmpz)
 
-	for _, p := range primes {
+	progress := attacks.ProgressFrom(ctx)
+	progress.Start("primes", int64(len(primes)))
+
+	for i, p := range primes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		progress.Add(1)
+
 		modp = modp.Mod(t.Key.N, &p)
 		if modp.Equals(ln.BigZero) {
-			t.PackGivenP(&p)
-			ch <- nil
-			return
+			return &keys.Result{Factors: []*fmp.Fmpz{&p}, Iterations: int64(i + 1)}, nil
 		}
 	}
 
-	ch <- fmt.Errorf("%s attack failed", name)
+	return nil, fmt.Errorf("%s attack failed", name)
 }
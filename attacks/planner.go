@@ -0,0 +1,210 @@
+package attacks
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sourcekris/goRsaTool/keys"
+	"github.com/sourcekris/goRsaTool/ln"
+
+	fmp "github.com/sourcekris/goflint"
+)
+
+// Feature is a property of the keys under attack that makes some attacks more or less likely to
+// succeed.
+type Feature string
+
+// The features the planner detects.
+const (
+	// FeatureLargeE is set when e is larger than the square root of N, so d may be small enough
+	// for the Wiener family of attacks.
+	FeatureLargeE Feature = "large e"
+	// FeatureSmallE is set when e is smaller than the usual 65537.
+	FeatureSmallE Feature = "small e"
+	// FeatureSmallCipherText is set when the ciphertext is much shorter than N, a sign that m^e
+	// may not have wrapped the modulus.
+	FeatureSmallCipherText Feature = "small ciphertext"
+	FeatureHints           Feature = "hints"
+	FeatureKnownPlainText  Feature = "known plaintext"
+	FeatureMultipleKeys    Feature = "multiple keys"
+)
+
+// joinFeatures returns fs as a comma separated list.
+func joinFeatures(fs []Feature) string {
+	var ss []string
+	for _, f := range fs {
+		ss = append(ss, string(f))
+	}
+
+	return strings.Join(ss, ", ")
+}
+
+// smallCipherTextMargin is how many bits shorter than N a ciphertext must be before it is
+// considered small. A random residue is this much shorter with probability 2^-32.
+const smallCipherTextMargin = 32
+
+// Features summarises the keys under attack.
+type Features struct {
+	Keys int
+	// Bits is the bit length of the first key's modulus.
+	Bits           int
+	EBits          int
+	CipherTextBits int
+	set            map[Feature]bool
+}
+
+// Has returns true if the feature was detected.
+func (f *Features) Has(ft Feature) bool {
+	return f.set[ft]
+}
+
+// String lists the detected features.
+func (f *Features) String() string {
+	var fs []Feature
+	for _, ft := range []Feature{FeatureLargeE, FeatureSmallE, FeatureSmallCipherText, FeatureHints, FeatureKnownPlainText, FeatureMultipleKeys} {
+		if f.Has(ft) {
+			fs = append(fs, ft)
+		}
+	}
+
+	if fs == nil {
+		return "none"
+	}
+
+	return joinFeatures(fs)
+}
+
+// KeyFeatures inspects ks and returns the features the planner orders attacks by. The modulus,
+// exponent and ciphertext are taken from the first key.
+func KeyFeatures(ks []*keys.RSA) *Features {
+	f := &Features{Keys: len(ks), set: make(map[Feature]bool)}
+	if len(ks) == 0 || ks[0].Key.N == nil || ks[0].Key.PublicKey.E == nil {
+		return f
+	}
+
+	k := ks[0]
+	f.Bits = k.Key.N.BitLen()
+	f.EBits = k.Key.PublicKey.E.BitLen()
+
+	f.set[FeatureLargeE] = f.EBits > f.Bits/2
+	f.set[FeatureSmallE] = k.Key.PublicKey.E.Cmp(fmp.NewFmpz(65537)) < 0
+	f.set[FeatureMultipleKeys] = len(ks) > 1
+	f.set[FeatureHints] = len(k.Hints) > 0
+	f.set[FeatureKnownPlainText] = len(k.KnownPlainText) > 0
+
+	if len(k.CipherText) > 0 {
+		f.CipherTextBits = ln.BytesToNumber(k.CipherText).BitLen()
+		f.set[FeatureSmallCipherText] = f.CipherTextBits+smallCipherTextMargin < f.Bits
+	}
+
+	return f
+}
+
+// Step is a single attack in a Plan along with the features that promoted it.
+type Step struct {
+	Attack  *Attack
+	Reasons []Feature
+}
+
+// Plan is the ordered list of unattended attacks to run against a set of keys.
+type Plan struct {
+	Features *Features
+	Steps    []*Step
+	// Skipped holds an outcome for every unattended attack left out of the plan, its Err is a
+	// *SkipError explaining why.
+	Skipped []*Outcome
+}
+
+// Plan looks at ks before anything runs and decides which unattended attacks are worth trying and
+// in what order. Attacks whose inputs are missing, that expect a feature ks lacks, or whose
+// MaxBits is exceeded are skipped. The rest are ordered so attacks suited to the detected
+// features run first and, within that, quicker attacks run before slower ones.
+func (a *Attacks) Plan(ks []*keys.RSA) *Plan {
+	p := &Plan{Features: KeyFeatures(ks)}
+
+	for _, at := range a.Supported {
+		if !at.Unnatended {
+			continue
+		}
+
+		if err := at.Check(ks); err != nil {
+			p.Skipped = append(p.Skipped, &Outcome{Name: at.Name, Err: err})
+			continue
+		}
+
+		if err := at.suits(p.Features); err != nil {
+			p.Skipped = append(p.Skipped, &Outcome{Name: at.Name, Err: err})
+			continue
+		}
+
+		s := &Step{Attack: at}
+		for _, ft := range append(append([]Feature(nil), at.Expects...), at.Suits...) {
+			if p.Features.Has(ft) {
+				s.Reasons = append(s.Reasons, ft)
+			}
+		}
+		p.Steps = append(p.Steps, s)
+	}
+
+	sort.SliceStable(p.Steps, func(i, j int) bool {
+		si, sj := p.Steps[i], p.Steps[j]
+		if len(si.Reasons) != len(sj.Reasons) {
+			return len(si.Reasons) > len(sj.Reasons)
+		}
+
+		return si.Attack.timeout() < sj.Attack.timeout()
+	})
+
+	return p
+}
+
+// suits returns a *SkipError if the attack cannot succeed against keys with features f.
+func (at *Attack) suits(f *Features) error {
+	var needs []string
+	for _, ft := range at.Expects {
+		if !f.Has(ft) {
+			needs = append(needs, string(ft))
+		}
+	}
+
+	if at.MaxBits > 0 && f.Bits > at.MaxBits {
+		needs = append(needs, fmt.Sprintf("N of at most %d bits", at.MaxBits))
+	}
+
+	if needs != nil {
+		return &SkipError{Name: at.Name, Needs: needs}
+	}
+
+	return nil
+}
+
+// String describes the plan, the detected features, the attacks in the order they will be
+// scheduled and what was skipped.
+func (p *Plan) String() string {
+	var b bytes.Buffer
+
+	keyCount := "1 key"
+	if p.Features.Keys != 1 {
+		keyCount = fmt.Sprintf("%d keys", p.Features.Keys)
+	}
+	fmt.Fprintf(&b, "planning for %s, %d bit N, %d bit e\n", keyCount, p.Features.Bits, p.Features.EBits)
+	fmt.Fprintf(&b, "features: %s\n", p.Features)
+
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	for i, s := range p.Steps {
+		reasons := "-"
+		if len(s.Reasons) > 0 {
+			reasons = joinFeatures(s.Reasons)
+		}
+		fmt.Fprintf(w, "  %d\t%s\t%v\t%s\n", i+1, s.Attack.Name, s.Attack.timeout(), reasons)
+	}
+	w.Flush()
+
+	r := &Report{Outcomes: p.Skipped}
+	b.WriteString(r.Skipped())
+
+	return b.String()
+}
@@ -1,6 +1,9 @@
 package pollardrhobrent
 
 import (
+	"context"
+
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 
@@ -10,9 +13,21 @@ import (
 // name is the name of this attack.
 const name = "brents variant of pollard rho factorization"
 
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "pollardrhobrent",
+		Description: "Brent's variant of Pollard's rho factorization.",
+		Category:    attacks.CategoryFactorization,
+		Reference:   "R. Brent, \"An Improved Monte Carlo Factorization Algorithm\", BIT 1980",
+		Unnatended:  true,
+		Timeout:     300,
+		F:           Attack,
+	})
+}
+
 // Attack conducts Pollard's Rho method Richard Brent variant for factoring
 // large composites. See: https://maths-people.anu.edu.au/~brent/pd/rpb051i.pdf
-func Attack(kks []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, kks []*keys.RSA) (*keys.Result, error) {
 	kk := kks[0]
 
 	var (
@@ -20,25 +35,81 @@ func Attack(kks []*keys.RSA, ch chan error) {
 		ys    = new(fmp.Fmpz)
 		g     = new(fmp.Fmpz).Set(kk.Key.N)
 		state = new(fmp.FlintRandT)
+		slot  = attacks.CheckpointSlot(ctx, kk.Key.N)
+		saved = slot.Resume()
+		steps = attacks.ProgressFrom(ctx)
 	)
 
+	steps.Start("steps", 0)
+
 	for g.Equals(kk.Key.N) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		y := ln.GetRand(state, kk.Key.N)
 		c := ln.GetRand(state, kk.Key.N)
 		m := ln.GetRand(state, kk.Key.N)
 		r := fmp.NewFmpz(1)
 		q := fmp.NewFmpz(1)
+		k := fmp.NewFmpz(0)
 		g.SetInt64(1)
 
+		// midRound is true when a checkpoint restored x and k part way through a round so the
+		// round's setup must be skipped.
+		var midRound bool
+		if saved != nil && saved.Int("y") != nil && saved.Int("c") != nil && saved.Int("m") != nil && saved.Int("r") != nil && saved.Int("q") != nil {
+			y, c, m, r, q = saved.Int("y"), saved.Int("c"), saved.Int("m"), saved.Int("r"), saved.Int("q")
+			if saved.Int("x") != nil && saved.Int("k") != nil {
+				x.Set(saved.Int("x"))
+				k = saved.Int("k")
+				midRound = true
+			}
+		}
+		saved = nil
+
+		save := func(mid bool) {
+			s := attacks.State{}
+			s.SetInt("y", y)
+			s.SetInt("c", c)
+			s.SetInt("m", m)
+			s.SetInt("r", r)
+			s.SetInt("q", q)
+			if mid {
+				s.SetInt("x", x)
+				s.SetInt("k", k)
+			}
+			slot.Save(s)
+		}
+
 		for g.Equals(ln.BigOne) {
-			x.Set(y)
-			k := fmp.NewFmpz(0)
+			if err := ctx.Err(); err != nil {
+				save(false)
+				return nil, err
+			}
+
 			counter := fmp.NewFmpz(0)
-			for counter.Cmp(r) < 0 {
-				y.Mul(y, y).Add(y, c).Mod(y, kk.Key.N)
-				counter.Add(counter, ln.BigOne)
+			if !midRound {
+				x.Set(y)
+				k.SetInt64(0)
+				for counter.Cmp(r) < 0 {
+					y.Mul(y, y).Add(y, c).Mod(y, kk.Key.N)
+					counter.Add(counter, ln.BigOne)
+					steps.Add(1)
+				}
 			}
+			midRound = false
+
 			for k.Cmp(r) < 0 && g.Equals(ln.BigOne) {
+				if err := ctx.Err(); err != nil {
+					save(true)
+					return nil, err
+				}
+
+				if slot.Due() {
+					save(true)
+				}
+
 				ys = new(fmp.Fmpz).Set(y)
 				min := ln.FmpzMin(m, new(fmp.Fmpz).Sub(r, k))
 				counter.Set(ln.BigZero)
@@ -46,6 +117,7 @@ func Attack(kks []*keys.RSA, ch chan error) {
 					y.Mul(y, y).Add(y, c).Mod(y, kk.Key.N)
 					q.Mul(q, new(fmp.Fmpz).Abs(new(fmp.Fmpz).Sub(x, y))).Mod(q, kk.Key.N)
 					counter.Add(counter, ln.BigOne)
+					steps.Add(1)
 				}
 				g = ln.FindGcd(q, kk.Key.N)
 				k.Add(k, m)
@@ -55,6 +127,10 @@ func Attack(kks []*keys.RSA, ch chan error) {
 
 		if g.Equals(kk.Key.N) {
 			for {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+
 				ys.Mul(ys, ys).Add(ys, c).Mod(ys, kk.Key.N)
 				g = ln.FindGcd(new(fmp.Fmpz).Abs(new(fmp.Fmpz).Sub(x, ys)), kk.Key.N)
 				if g.Cmp(ln.BigOne) > 0 {
@@ -64,6 +140,7 @@ func Attack(kks []*keys.RSA, ch chan error) {
 		}
 	}
 
-	kk.PackGivenP(g)
-	ch <- nil
+	slot.Clear()
+
+	return &keys.Result{Factors: []*fmp.Fmpz{g}}, nil
 }
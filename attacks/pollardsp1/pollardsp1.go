@@ -1,8 +1,10 @@
 package pollardsp1
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 
@@ -12,9 +14,21 @@ import (
 // name is the name of this attack.
 const name = "pollard's p-1 factorization"
 
-const (
-	startA = 7
-	startB = 65536
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "pollardsp1",
+		Description: "Pollard's p-1 factorization for primes where p-1 is smooth.",
+		Category:    attacks.CategoryFactorization,
+		Reference:   "J. Pollard, \"Theorems on Factorization and Primality Testing\", 1974",
+		Unnatended:  true,
+		Params:      []*attacks.Param{startA, startB},
+		F:           Attack,
+	})
+}
+
+var (
+	startA = &attacks.Param{Name: "a", Description: "The base raised to each prime power.", Default: int64(7)}
+	startB = &attacks.Param{Name: "b1", Description: "The smoothness bound, primes up to b1 are used.", Default: int64(65536)}
 )
 
 // primeSieve finds Fmpz type primes less than argument n.
@@ -22,18 +36,26 @@ var primeSieve = ln.SieveOfEratosthenesFmp
 
 // Attack implements the Pollards P minus 1 factorization technique. This technique was used in
 // BostonKeyParty 2017 challenge "RSA Buffet".
-func Attack(ks []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ks []*keys.RSA) (*keys.Result, error) {
 	k := ks[0]
 	// Solution based on https://github.com/HackThisSite/ Python solution.
 	// Solution is derived from the work here: https://math.berkeley.edu/~sagrawal/su14_math55/notes_pollard.pdf
 	n := k.Key.N
 
-	primes := primeSieve(startB)
+	primes := primeSieve(int(startB.Int()))
+
+	a := fmp.NewFmpz(startA.Int())
+	b := fmp.NewFmpz(startB.Int())
 
-	a := fmp.NewFmpz(int64(startA))
-	b := fmp.NewFmpz(int64(startB))
+	progress := attacks.ProgressFrom(ctx)
+	progress.Start("primes", int64(len(primes)))
 
 	for _, x := range primes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		progress.Add(1)
+
 		tmp := fmp.NewFmpz(int64(1))
 		for tmp.Cmp(b) < 0 {
 			a.Exp(a, x, n)
@@ -42,16 +64,13 @@ func Attack(ks []*keys.RSA, ch chan error) {
 	}
 	d := ln.FindGcd(a.Sub(a, ln.BigOne), n)
 	if d.Equals(n) {
-		ch <- fmt.Errorf("%s failed - unable to factor key with a of: %d (try another a?)", name, startA)
-		return
+		return nil, fmt.Errorf("%s failed - unable to factor key with a of: %d (try another a?)", name, startA.Int())
 	}
 
 	if d.Cmp(ln.BigOne) > 0 {
 		// Success
-		k.PackGivenP(d)
-		ch <- nil
-		return
+		return &keys.Result{Factors: []*fmp.Fmpz{d}}, nil
 	}
 
-	ch <- fmt.Errorf("%s attack failed - unable to factor key with b of: %d", name, startB)
+	return nil, fmt.Errorf("%s attack failed - unable to factor key with b of: %d", name, startB.Int())
 }
@@ -1,6 +1,9 @@
 package pollardsrho
 
 import (
+	"context"
+
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 
@@ -10,8 +13,20 @@ import (
 // name is the name of this attack.
 const name = "pollard's rho"
 
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "pollardsrho",
+		Description: "Pollard's rho factorization for moduli with a small prime.",
+		Category:    attacks.CategoryFactorization,
+		Reference:   "J. Pollard, \"A Monte Carlo Method for Factorization\", BIT 1975",
+		Unnatended:  true,
+		Timeout:     300,
+		F:           Attack,
+	})
+}
+
 // Attack uses Pollard's Rho factorization method.
-func Attack(ks []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ks []*keys.RSA) (*keys.Result, error) {
 	k := ks[0]
 	var (
 		state = new(fmp.FlintRandT)
@@ -19,16 +34,47 @@ func Attack(ks []*keys.RSA, ch chan error) {
 		c     = ln.GetRand(state, k.Key.N)
 		y     = new(fmp.Fmpz).Set(x)
 		g     = fmp.NewFmpz(1)
+		i     int64
+		slot  = attacks.CheckpointSlot(ctx, k.Key.N)
 	)
 
-	for g.Equals(ln.BigOne) {
+	// Resume the walk where a previous run left it.
+	if s := slot.Resume(); s != nil && s.Int("x") != nil && s.Int("y") != nil && s.Int("c") != nil {
+		x, y, c = s.Int("x"), s.Int("y"), s.Int("c")
+		i, _ = s.Int64("iterations")
+	}
+
+	save := func() {
+		s := attacks.State{}
+		s.SetInt("x", x)
+		s.SetInt("y", y)
+		s.SetInt("c", c)
+		s.SetInt64("iterations", i)
+		slot.Save(s)
+	}
+
+	progress := attacks.ProgressFrom(ctx)
+	progress.Start("iterations", 0)
+	progress.Resume(i)
+
+	for ; g.Equals(ln.BigOne); i++ {
+		if err := ctx.Err(); err != nil {
+			save()
+			return nil, err
+		}
+
+		if slot.Due() {
+			save()
+		}
+
+		progress.Add(1)
 		x.Mul(x, x).Mod(x, k.Key.N).Add(x, c).Mod(x, k.Key.N)
 		y.Mul(y, y).Mod(y, k.Key.N).Add(y, c).Mod(y, k.Key.N)
 		y.Mul(y, y).Mod(y, k.Key.N).Add(y, c).Mod(y, k.Key.N)
 		g = ln.FindGcd(new(fmp.Fmpz).Abs(new(fmp.Fmpz).Sub(x, y)), k.Key.N)
 	}
 
-	k.PackGivenP(g)
+	slot.Clear()
 
-	ch <- nil
+	return &keys.Result{Factors: []*fmp.Fmpz{g}, Iterations: i}, nil
 }
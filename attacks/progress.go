@@ -0,0 +1,179 @@
+package attacks
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Progress is how an attack reports the work it has done. Attacks get theirs from their context
+// with ProgressFrom, it is always safe to use even when nobody is watching.
+type Progress interface {
+	// Start names the unit of work, e.g. "primes" or "curves", and sets the estimated total
+	// amount of work. A total of zero means the total is unknown. Attacks that work in phases
+	// call Start again at the beginning of each phase, which resets the work done.
+	Start(unit string, total int64)
+	// Add records n more units of work done.
+	Add(n int64)
+	// Resume records n units of work done by an earlier run resumed from a checkpoint, they count
+	// towards the work done but not the rate. Call it after Start.
+	Resume(n int64)
+}
+
+// nopProgress discards progress reports when nothing is monitoring the attack.
+type nopProgress struct{}
+
+func (nopProgress) Start(string, int64) {}
+func (nopProgress) Add(int64)           {}
+func (nopProgress) Resume(int64)        {}
+
+type progressKey struct{}
+
+// ProgressFrom returns the Progress an attack should report to.
+func ProgressFrom(ctx context.Context) Progress {
+	if p, ok := ctx.Value(progressKey{}).(Progress); ok {
+		return p
+	}
+
+	return nopProgress{}
+}
+
+// Tracker is the Progress of one running attack.
+type Tracker struct {
+	// done is first so it is 64-bit aligned for atomic access on 32-bit platforms.
+	done  int64
+	Name  string
+	start time.Time
+
+	mu      sync.Mutex
+	unit    string
+	total   int64
+	resumed int64
+}
+
+// Start implements Progress.
+func (t *Tracker) Start(unit string, total int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.unit, t.total, t.resumed = unit, total, 0
+	t.start = time.Now()
+	atomic.StoreInt64(&t.done, 0)
+}
+
+// Add implements Progress.
+func (t *Tracker) Add(n int64) {
+	atomic.AddInt64(&t.done, n)
+}
+
+// Resume implements Progress.
+func (t *Tracker) Resume(n int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.resumed = n
+}
+
+// String returns a status line with the work done, the rate and, when the total is known, the
+// estimated time remaining, e.g. "fermat: 1.2M of 5M iterations (24%), 40k/s, ETA 1m40s".
+func (t *Tracker) String() string {
+	t.mu.Lock()
+	unit, total, start, resumed := t.unit, t.total, t.start, t.resumed
+	t.mu.Unlock()
+
+	var (
+		done    = atomic.LoadInt64(&t.done)
+		elapsed = time.Since(start)
+		rate    = float64(done) / elapsed.Seconds()
+	)
+	done += resumed
+
+	if unit == "" {
+		return fmt.Sprintf("%s: running for %v", t.Name, elapsed.Round(time.Second))
+	}
+
+	if total <= 0 {
+		return fmt.Sprintf("%s: %s %s, %s/s", t.Name, si(float64(done)), unit, si(rate))
+	}
+
+	eta := "unknown"
+	if rate > 0 && done < total {
+		eta = time.Duration(float64(total-done) / rate * float64(time.Second)).Round(time.Second).String()
+	}
+
+	return fmt.Sprintf("%s: %s of %s %s (%d%%), %s/s, ETA %s", t.Name, si(float64(done)), si(float64(total)), unit, done*100/total, si(rate), eta)
+}
+
+// si formats f with an SI suffix, e.g. 1.2M.
+func si(f float64) string {
+	for _, s := range []struct {
+		suffix string
+		scale  float64
+	}{{"G", 1e9}, {"M", 1e6}, {"k", 1e3}} {
+		if f >= s.scale {
+			return fmt.Sprintf("%.3g%s", f/s.scale, s.suffix)
+		}
+	}
+
+	return fmt.Sprintf("%.3g", f)
+}
+
+// Monitor keeps track of the attacks that are running so their progress can be displayed.
+type Monitor struct {
+	mu      sync.Mutex
+	running map[*Tracker]bool
+}
+
+// NewMonitor returns a Monitor with nothing running.
+func NewMonitor() *Monitor {
+	return &Monitor{running: make(map[*Tracker]bool)}
+}
+
+type monitorKey struct{}
+
+// WithMonitor returns a context that makes attacks run with it report their progress to m.
+func WithMonitor(ctx context.Context, m *Monitor) context.Context {
+	return context.WithValue(ctx, monitorKey{}, m)
+}
+
+// track registers a running attack and returns a context carrying its Tracker along with a
+// function to call once the attack is done. It does nothing if ctx has no Monitor.
+func track(ctx context.Context, name string) (context.Context, func()) {
+	m, _ := ctx.Value(monitorKey{}).(*Monitor)
+	if m == nil {
+		return ctx, func() {}
+	}
+
+	t := &Tracker{Name: name, start: time.Now()}
+	m.mu.Lock()
+	m.running[t] = true
+	m.mu.Unlock()
+
+	return context.WithValue(ctx, progressKey{}, t), func() {
+		m.mu.Lock()
+		delete(m.running, t)
+		m.mu.Unlock()
+	}
+}
+
+// Status returns a status line for every running attack sorted by attack name.
+func (m *Monitor) Status() []string {
+	m.mu.Lock()
+	ts := make([]*Tracker, 0, len(m.running))
+	for t := range m.running {
+		ts = append(ts, t)
+	}
+	m.mu.Unlock()
+
+	sort.Slice(ts, func(i, j int) bool { return ts[i].Name < ts[j].Name })
+
+	var lines []string
+	for _, t := range ts {
+		lines = append(lines, t.String())
+	}
+
+	return lines
+}
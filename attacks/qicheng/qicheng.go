@@ -1,8 +1,10 @@
 package qicheng
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 	fmp "github.com/sourcekris/goflint"
@@ -11,6 +13,17 @@ import (
 // name is the name of this attack.
 const name = "qicheng factorization"
 
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "qicheng",
+		Description: "Factor moduli with a prime p where 4p-1 has a small squarefree part.",
+		Category:    attacks.CategoryFactorization,
+		Reference:   "Q. Cheng, \"A New Class of Unsafe Primes\", IACR ePrint 2002/109",
+		Unnatended:  true,
+		F:           Attack,
+	})
+}
+
 var (
 	state = new(fmp.FlintRandT)
 	nTwo  = fmp.NewFmpz(-2)
@@ -183,7 +196,7 @@ func (e *Curve) Poly(n, x *fmp.Fmpz, r *Integers) *fmp.Fmpz {
 }
 
 // Attack implements the Qi Cheng attack.
-func Attack(ks []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ks []*keys.RSA) (*keys.Result, error) {
 	k := ks[0]
 	js := []*fmp.Fmpz{
 		fmp.NewFmpz(0),
@@ -196,8 +209,15 @@ func Attack(ks []*keys.RSA, ch chan error) {
 	R := NewIntegers(k.Key.N)
 	attempts := 20
 
+	progress := attacks.ProgressFrom(ctx)
+	progress.Start("curves", int64(attempts*len(js)))
 	for i := 0; i < attempts; i++ {
 		for _, j := range js {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			progress.Add(1)
+
 			var E *Curve
 			if j.Equals(ln.BigZero) {
 				a := R.RandomElement()
@@ -216,12 +236,10 @@ func Attack(ks []*keys.RSA, ch chan error) {
 			g := ln.FindGcd(z, k.Key.N)
 
 			if g.Cmp(ln.BigOne) > 0 {
-				k.PackGivenP(g)
-				ch <- nil
-				return
+				return &keys.Result{Factors: []*fmp.Fmpz{g}}, nil
 			}
 		}
 	}
 
-	ch <- fmt.Errorf("%s attack failed - no factors found", name)
+	return nil, fmt.Errorf("%s attack failed - no factors found", name)
 }
@@ -0,0 +1,293 @@
+package attacks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sourcekris/goRsaTool/keys"
+
+	fmp "github.com/sourcekris/goflint"
+)
+
+// Outcome records how a single attack fared during an unattended run.
+type Outcome struct {
+	Name     string
+	Err      error
+	Duration time.Duration
+	// Started is false when the run finished before the attack was scheduled.
+	Started bool
+}
+
+// Report summarises an unattended run.
+type Report struct {
+	// Winner is the name of the attack that recovered the key or plaintext, if any.
+	Winner   string
+	Outcomes []*Outcome
+}
+
+// skipped returns the outcome's *SkipError if the attack was skipped, otherwise nil.
+func (o *Outcome) skipped() *SkipError {
+	var se *SkipError
+	if errors.As(o.Err, &se) {
+		return se
+	}
+
+	return nil
+}
+
+// Errors returns the errors of every attack that ran and failed.
+func (r *Report) Errors() []error {
+	var errs []error
+	for _, o := range r.Outcomes {
+		if o.Err != nil && o.skipped() == nil {
+			errs = append(errs, o.Err)
+		}
+	}
+
+	return errs
+}
+
+// Skipped returns a summary of the attacks that were skipped grouped by what they needed, so the
+// user can see which extra inputs would unlock more attacks.
+func (r *Report) Skipped() string {
+	var (
+		needs []string
+		names = make(map[string][]string)
+	)
+
+	for _, o := range r.Outcomes {
+		se := o.skipped()
+		if se == nil {
+			continue
+		}
+
+		for _, n := range se.Needs {
+			if _, ok := names[n]; !ok {
+				needs = append(needs, n)
+			}
+			names[n] = append(names[n], o.Name)
+		}
+	}
+
+	var b bytes.Buffer
+	for _, n := range needs {
+		fmt.Fprintf(&b, "skipped: needs %s (%s)\n", n, strings.Join(names[n], ", "))
+	}
+
+	return b.String()
+}
+
+// Status describes how the attack of o fared: skipped, not started, won, cancelled, failed or
+// finished without a result.
+func (r *Report) Status(o *Outcome) string {
+	switch {
+	case o.skipped() != nil:
+		return "skipped"
+	case !o.Started:
+		return "not started"
+	case o.Name == r.Winner:
+		return "won"
+	case errors.Is(o.Err, context.Canceled):
+		return "cancelled"
+	case o.Err != nil:
+		return "failed"
+	default:
+		return "finished without a result"
+	}
+}
+
+// String returns a table describing which attack won and how long the others ran.
+func (r *Report) String() string {
+	var b bytes.Buffer
+	if r.Winner != "" {
+		fmt.Fprintf(&b, "key recovered by attack: %s\n", r.Winner)
+	} else {
+		fmt.Fprintln(&b, "no attack recovered the key")
+	}
+
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	for _, o := range r.Outcomes {
+		if o.skipped() != nil {
+			// Summarised by Skipped below.
+			continue
+		}
+
+		status := r.Status(o)
+		if status == "failed" {
+			status = o.Err.Error()
+		}
+
+		fmt.Fprintf(w, "  %s\t%v\t%s\n", o.Name, o.Duration.Round(time.Millisecond), status)
+	}
+	w.Flush()
+	b.WriteString(r.Skipped())
+
+	return b.String()
+}
+
+// Solved returns true if any of the keys has had its private exponent or a plaintext recovered.
+func Solved(ks []*keys.RSA) bool {
+	for _, k := range ks {
+		if k.Key.D != nil || len(k.PlainText) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// copyKeys returns copies of ks so that concurrent attacks don't modify each other's keys.
+func copyKeys(ks []*keys.RSA) []*keys.RSA {
+	c := make([]*keys.RSA, len(ks))
+	for i, k := range ks {
+		c[i] = k.Copy()
+	}
+
+	return c
+}
+
+// ExecuteUnattended plans and runs the unattended attacks against t, see Plan and ExecutePlan.
+func (a *Attacks) ExecuteUnattended(ctx context.Context, t []*keys.RSA, workers int) *Report {
+	return a.ExecutePlan(ctx, a.Plan(t), t, workers)
+}
+
+// ExecutePlan runs the steps of p against t in order using a pool of workers. The attacks p
+// skipped are recorded in the report. Each attack works on its own copy of the keys and its result
+// is merged into t as soon as it finishes. The first attack to recover a private key or plaintext
+// cancels every other attack. If attacks only managed to partially factor a key, the remaining
+// composite cofactors are sent to the factoring attacks.
+func (a *Attacks) ExecutePlan(ctx context.Context, p *Plan, t []*keys.RSA, workers int) *Report {
+	if workers < 1 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		report = &Report{}
+		jobs   = make(chan int)
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		queue  []*Attack
+	)
+
+	for _, s := range p.Steps {
+		queue = append(queue, s.Attack)
+		report.Outcomes = append(report.Outcomes, &Outcome{Name: s.Attack.Name})
+	}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				o := report.Outcomes[i]
+				mu.Lock()
+				ks := copyKeys(t)
+				mu.Unlock()
+
+				start := time.Now()
+				r, err := queue[i].run(ctx, ks)
+
+				mu.Lock()
+				o.Started = true
+				o.Duration = time.Since(start)
+				if err == nil && r != nil {
+					err = keys.Merge(t, r)
+				}
+				o.Err = err
+				if err == nil && r != nil && report.Winner == "" && Solved(t) {
+					report.Winner = o.Name
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i := range queue {
+		if ctx.Err() != nil {
+			break
+		}
+
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	// Skipped outcomes are appended last so report.Outcomes[i] matches queue[i] during the run.
+	for _, o := range p.Skipped {
+		report.Outcomes = append(report.Outcomes, &Outcome{Name: o.Name, Err: o.Err})
+	}
+
+	if report.Winner == "" {
+		a.factorCofactors(ctx, t, workers, report)
+	}
+
+	return report
+}
+
+// factorCofactors sends the composite cofactors of partially factored keys in t to the unattended
+// single key factoring attacks. Whatever they find refines the keys' factorizations and the rounds
+// continue until every key is fully factored or a round makes no progress.
+func (a *Attacks) factorCofactors(ctx context.Context, t []*keys.RSA, workers int, report *Report) {
+	fa := NewAttacks()
+	for _, at := range a.Supported {
+		if at.Unnatended && !at.SupportsMulti() && (at.Category == CategoryFactorization || at.Category == CategoryLookup) {
+			fa.Supported = append(fa.Supported, at)
+		}
+	}
+
+	for progress := true; progress && ctx.Err() == nil; {
+		progress = false
+		for _, k := range t {
+			f := k.Factorization
+			if k.Key.D != nil || f == nil || f.Complete() {
+				continue
+			}
+
+			// Refining k changes f.Composites so range over a copy.
+			for _, c := range append([]*fmp.Fmpz(nil), f.Composites...) {
+				ck, err := keys.NewRSA(keys.PrivateFromPublic(&keys.FMPPublicKey{N: c, E: k.Key.PublicKey.E}), nil, nil, k.PastPrimesFile, k.Verbose)
+				if err != nil {
+					continue
+				}
+
+				r := fa.ExecuteUnattended(ctx, []*keys.RSA{ck}, workers)
+				for _, o := range r.Outcomes {
+					o.Name += " (cofactor)"
+				}
+				report.Outcomes = append(report.Outcomes, r.Outcomes...)
+
+				if ck.Factorization == nil {
+					continue
+				}
+
+				k.Results = append(k.Results, ck.Results...)
+				for _, d := range append(ck.Factorization.Primes, ck.Factorization.Composites...) {
+					if k.Refine(d) {
+						progress = true
+					}
+				}
+
+				if k.Factorization.Complete() {
+					// The product of a complete factorization is always N.
+					_ = k.PackMultiPrime(k.Factorization.Primes)
+					if report.Winner == "" {
+						report.Winner = ck.Results[len(ck.Results)-1].Attack + " (cofactor)"
+					}
+					break
+				}
+			}
+		}
+	}
+}
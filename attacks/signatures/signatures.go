@@ -17,16 +17,17 @@ const name = "recover n from sigs and plaintexts"
 // Note this attack is different to the other attack packages in that it is called directly from
 // rsatool.go:main.
 
-// Attack calculates an RSA modulus given two known plaintexts and two signatures generated with
-// that modulus. Optionally a exponent may be provided but if e is nil then 65537 will be used.
-func Attack(ptlist, siglist []string, exp string) error {
+// Recover calculates an RSA modulus given two known plaintexts and two signatures generated with
+// that modulus. Optionally a exponent may be provided but if e is nil then 65537 will be used. The
+// signature files are decoded from encoding sigEnc and the plaintext files from ptEnc.
+func Recover(ptlist, siglist []string, exp string, sigEnc, ptEnc utils.Encoding) (*keys.RSA, error) {
 	var (
 		sigs []*fmp.Fmpz
 		pts  []*fmp.Fmpz
 	)
 
 	if len(ptlist) != 2 || len(siglist) != 2 {
-		return fmt.Errorf("signature mode requires exactly 2 plaintexts and 2 signatures")
+		return nil, fmt.Errorf("signature mode requires exactly 2 plaintexts and 2 signatures")
 	}
 
 	e, ok := new(fmp.Fmpz).SetString(exp, 10)
@@ -35,17 +36,17 @@ func Attack(ptlist, siglist []string, exp string) error {
 	}
 
 	for _, s := range siglist {
-		d, err := utils.ReadBinary(s)
+		d, err := utils.ReadEncoded(s, sigEnc)
 		if err != nil {
-			return fmt.Errorf("error reading signature %s: %v", s, err)
+			return nil, fmt.Errorf("error reading signature %s: %v", s, err)
 		}
 		sigs = append(sigs, ln.BytesToNumber(d))
 	}
 
 	for _, p := range ptlist {
-		d, err := utils.ReadBinary(p)
+		d, err := utils.ReadEncoded(p, ptEnc)
 		if err != nil {
-			return fmt.Errorf("error reading plaintext %s: %v", p, err)
+			return nil, fmt.Errorf("error reading plaintext %s: %v", p, err)
 		}
 		pts = append(pts, ln.BytesToNumber(d))
 	}
@@ -56,15 +57,24 @@ func Attack(ptlist, siglist []string, exp string) error {
 	)
 
 	if res.Cmp(sigs[0]) < 0 || res.Cmp(sigs[1]) < 0 {
-		return fmt.Errorf("%s failed to recover the modulus, the resulting modulus was smaller than one of the signatures", name)
+		return nil, fmt.Errorf("%s failed to recover the modulus, the resulting modulus was smaller than one of the signatures", name)
 	}
 
 	k, err := keys.NewRSA(keys.PrivateFromPublic(&keys.FMPPublicKey{N: res, E: e}), nil, nil, "", false)
 	if err != nil {
-		return fmt.Errorf("%s failed to construct rsa key once modulus was recovered", name)
+		return nil, fmt.Errorf("%s failed to construct rsa key once modulus was recovered", name)
+	}
+
+	return k, nil
+}
+
+// Attack prints the public key found by Recover encoded in format f.
+func Attack(ptlist, siglist []string, exp string, f keys.Format, sigEnc, ptEnc utils.Encoding) error {
+	k, err := Recover(ptlist, siglist, exp, sigEnc, ptEnc)
+	if err != nil {
+		return err
 	}
 
 	fmt.Println("Recovered public key:")
-	fmt.Println(keys.EncodeFMPPublicKey(k.Key.PublicKey))
-	return nil
+	return utils.PrintPublicKey(k.Key.PublicKey, f)
 }
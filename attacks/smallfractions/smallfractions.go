@@ -1,8 +1,10 @@
 package smallfractions
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 
@@ -12,8 +14,21 @@ import (
 // name is the name of this attack.
 const name = "small fractions"
 
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "smallfractions",
+		Description: "Factor moduli where p/q is close to a fraction with a small numerator and denominator.",
+		Category:    attacks.CategoryFactorization,
+		Reference:   "D. Coppersmith, \"Small Solutions to Polynomial Equations\", J. Cryptology 1997",
+		MaxBits:     2048,
+		Unnatended:  true,
+		Params:      []*attacks.Param{depth},
+		F:           Attack,
+	})
+}
+
 // depth is the max size of the numerator and denominator to test to.
-const depth int64 = 50
+var depth = &attacks.Param{Name: "depth", Description: "The largest numerator and denominator to try.", Default: int64(50)}
 
 // Which fmp initializer to use so we can swap out the constructor
 // for debugging.
@@ -25,18 +40,27 @@ var (
 )
 
 // Attack implements SmallFractions attack.
-func Attack(ks []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ks []*keys.RSA) (*keys.Result, error) {
 	k := ks[0]
 	if k.Key.D != nil {
-		ch <- nil
+		return nil, nil
 	}
 
 	var num, den int64
 
 	n := fmpz(0).Set(k.Key.N)
-	ctx := fmp.NewFmpzModCtx(n)
-	for den = 2; den < depth+1; den++ {
+	mctx := fmp.NewFmpzModCtx(n)
+	// Every numerator below every denominator from 2 to depth is tried.
+	progress := attacks.ProgressFrom(ctx)
+	progress.Start("fraction pairs", depth.Int()*(depth.Int()-1)/2)
+
+	for den = 2; den < depth.Int()+1; den++ {
 		for num = 1; num < den; num++ {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			progress.Add(1)
+
 			g := fmpz(0).GCD(fmpz(num), fmpz(den))
 
 			if g.Equals(ln.BigOne) {
@@ -47,8 +71,8 @@ func Attack(ks []*keys.RSA, ch chan error) {
 				X.Div(X, ln.BigTwo)
 
 				// f = x - phint
-				f := modpoly(ctx).SetCoeffUI(1, 1)
-				f.Sub(f, modpoly(ctx).SetCoeff(0, phint))
+				f := modpoly(mctx).SetCoeffUI(1, 1)
+				f.Sub(f, modpoly(mctx).SetCoeff(0, phint))
 
 				// Copy f to type FmpzPoly.
 				fp := poly()
@@ -120,9 +144,7 @@ func Attack(ks []*keys.RSA, ch chan error) {
 								continue
 							}
 							if fmpz(0).Mod(k.Key.N, pp).Equals(ln.BigZero) {
-								k.PackGivenP(pp)
-								ch <- nil
-								return
+								return &keys.Result{Factors: []*fmp.Fmpz{pp}}, nil
 							}
 						}
 					}
@@ -131,5 +153,5 @@ func Attack(ks []*keys.RSA, ch chan error) {
 		}
 	}
 
-	ch <- fmt.Errorf("%s did not find the factors", name)
+	return nil, fmt.Errorf("%s did not find the factors", name)
 }
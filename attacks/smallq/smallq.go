@@ -1,9 +1,11 @@
 package smallq
 
 import (
+	"context"
 	"log"
 
 	"github.com/jbarham/primegen"
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 
@@ -13,6 +15,16 @@ import (
 // name is the name of this attack.
 const name = "small q"
 
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "smallq",
+		Description: "Trial divide the modulus by small primes.",
+		Category:    attacks.CategoryFactorization,
+		Unnatended:  true,
+		F:           Attack,
+	})
+}
+
 func chk(p, n *fmp.Fmpz) (bool, *fmp.Fmpz) {
 	zz := new(fmp.Fmpz).Set(p)
 	if new(fmp.Fmpz).Mod(n, zz).Equals(ln.BigZero) {
@@ -22,25 +34,59 @@ func chk(p, n *fmp.Fmpz) (bool, *fmp.Fmpz) {
 }
 
 // Attack iterate small primes until we timeout and test them as factors of N.
-func Attack(ts []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ts []*keys.RSA) (*keys.Result, error) {
 	t := ts[0]
 	if t.Key.D != nil {
-		ch <- nil
-		return
+		return nil, nil
 	}
 
 	if t.Verbose {
 		log.Printf("%s attempt beginning", name)
 	}
 
-	pc := new(fmp.Fmpz)
-	pr := primegen.New()
-	for {
+	var (
+		pc    = new(fmp.Fmpz)
+		pr    = primegen.New()
+		slot  = attacks.CheckpointSlot(ctx, t.Key.N)
+		start = int64(1)
+	)
+
+	// Resume with the prime after the last one a previous run tested.
+	if s := slot.Resume(); s != nil {
+		if p, ok := s.Int64("prime"); ok {
+			pc.SetInt64(p)
+			pr.SkipTo(uint64(p) + 1)
+			start, _ = s.Int64("iterations")
+			start++
+		}
+	}
+
+	state := func(i int64) attacks.State {
+		s := attacks.State{}
+		s.SetInt64("prime", pc.Int64())
+		s.SetInt64("iterations", i)
+		return s
+	}
+
+	progress := attacks.ProgressFrom(ctx)
+	progress.Start("primes", 0)
+	progress.Resume(start - 1)
+
+	for i := start; ; i++ {
+		if err := ctx.Err(); err != nil {
+			slot.Save(state(i - 1))
+			return nil, err
+		}
+
 		pc.SetUint64(pr.Next())
+		progress.Add(1)
 		if res, pp := chk(pc, t.Key.N); res {
-			t.PackGivenP(pp)
-			ch <- nil
-			return
+			slot.Clear()
+			return &keys.Result{Factors: []*fmp.Fmpz{pp}, Iterations: i}, nil
+		}
+
+		if slot.Due() {
+			slot.Save(state(i))
 		}
 	}
 }
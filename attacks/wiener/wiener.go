@@ -1,8 +1,10 @@
 package wiener
 
 import (
+	"context"
 	"log"
 
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/attacks/wiener2"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
@@ -12,15 +14,26 @@ import (
 
 const name = "wiener"
 
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "wiener",
+		Description: "Recover a small private exponent from the continued fraction expansion of e/n.",
+		Category:    attacks.CategoryExponent,
+		Reference:   "M. Wiener, \"Cryptanalysis of Short RSA Secret Exponents\", IEEE Trans. Inf. Theory 1990",
+		Expects:     []attacks.Feature{attacks.FeatureLargeE},
+		Unnatended:  true,
+		F:           Attack,
+	})
+}
+
 // Attack implements the Wiener attack on an RSA public key and this implementation is based on the
 // python implementation of the algorithm by Pablo Celayes:
 // https://github.com/pablocelayes/rsa-wiener-attack
-func Attack(ts []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ts []*keys.RSA) (*keys.Result, error) {
 	t := ts[0]
 	if t.Key.D != nil {
 		// Key already factored.
-		ch <- nil
-		return
+		return nil, nil
 	}
 
 	frac := ln.RationalToContfract(t.Key.PublicKey.E, t.Key.N)
@@ -28,7 +41,15 @@ func Attack(ts []*keys.RSA, ch chan error) {
 
 	z := new(fmp.Fmpz)
 
+	progress := attacks.ProgressFrom(ctx)
+	progress.Start("convergents", int64(len(convergants)))
+
 	for _, g := range convergants {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		progress.Add(1)
+
 		k := g[0]
 		d := g[1]
 
@@ -40,9 +61,7 @@ func Attack(ts []*keys.RSA, ch chan error) {
 				ts := ln.IsPerfectSquare(discr)
 				if !ts.Equals(ln.BigNOne) && z.Add(s, ts).Mod(z, ln.BigTwo).Equals(ln.BigZero) {
 					// We found d, pack the private key.
-					t.PackGivenP(ln.FindPGivenD(d, t.Key.PublicKey.E, t.Key.N))
-					ch <- nil
-					return
+					return &keys.Result{Factors: []*fmp.Fmpz{ln.FindPGivenD(d, t.Key.PublicKey.E, t.Key.N)}, D: d}, nil
 				}
 			}
 		}
@@ -53,5 +72,5 @@ func Attack(ts []*keys.RSA, ch chan error) {
 	}
 
 	// Try the variant approach.
-	wiener2.Attack(ts, ch)
+	return wiener2.Attack(ctx, ts)
 }
@@ -1,8 +1,10 @@
 package wiener2
 
 import (
+	"context"
 	"log"
 
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/attacks/wienervariant"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
@@ -68,27 +70,30 @@ func fullReverse(n, e *fmp.Fmpz, c [2]*fmp.Fmpz) *fmp.Fmpz {
 
 // Attack performs a variant of the wiener attack ported from the python version here:
 // https://github.com/MxRy/rsa-attacks/blob/master/wiener-attack.py
-func Attack(ks []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ks []*keys.RSA) (*keys.Result, error) {
 	k := ks[0]
 	if k.Key.D != nil {
-		ch <- nil
-		return
+		return nil, nil
 	}
 
 	ts := fmp.NewFmpz(42)
 	newc := squareAndMultiply(ts, k.Key.PublicKey.E, k.Key.N)
 	convergants := ln.ConvergantsFromContfract(ln.RationalToContfract(k.Key.PublicKey.E, k.Key.N))
 
+	progress := attacks.ProgressFrom(ctx)
+	progress.Start("convergents", int64(len(convergants)))
+
 	for _, c := range convergants {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		progress.Add(1)
+
 		if squareAndMultiply(newc, c[1], k.Key.N).Equals(ts) {
 			if pp := fullReverse(k.Key.N, k.Key.PublicKey.E, c); pp != nil {
-				k.PackGivenP(pp)
-				ch <- nil
-				return
+				return &keys.Result{Factors: []*fmp.Fmpz{pp}, D: c[1]}, nil
 			}
-			k.PackGivenP(ln.FindPGivenD(c[1], k.Key.PublicKey.E, k.Key.N))
-			ch <- nil
-			return
+			return &keys.Result{Factors: []*fmp.Fmpz{ln.FindPGivenD(c[1], k.Key.PublicKey.E, k.Key.N)}, D: c[1]}, nil
 		}
 	}
 
@@ -97,5 +102,5 @@ func Attack(ks []*keys.RSA, ch chan error) {
 	}
 
 	// Try the next variant approach.
-	wienervariant.Attack(ks, ch)
+	return wienervariant.Attack(ctx, ks)
 }
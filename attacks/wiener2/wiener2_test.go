@@ -1,6 +1,7 @@
 package wiener2
 
 import (
+	"context"
 	"testing"
 
 	"github.com/sourcekris/goRsaTool/keys"
@@ -35,9 +36,10 @@ func TestAttack(t *testing.T) {
 		}
 
 		k, _ := keys.NewRSA(keys.PrivateFromPublic(fmpPubKey), ln.NumberToBytes(tc.c), nil, "", false)
-		ch := make(chan error)
-		go Attack([]*keys.RSA{k}, ch)
-		err := <-ch
+		r, err := Attack(context.Background(), []*keys.RSA{k})
+		if err == nil {
+			err = keys.Merge([]*keys.RSA{k}, r)
+		}
 		if err != nil && !tc.wantErr {
 			t.Errorf("Attack() failed: %s expected no error got error: %v", tc.name, err)
 		}
@@ -46,8 +48,8 @@ func TestAttack(t *testing.T) {
 			t.Errorf("Attack() failed: %s d not found", tc.name)
 		}
 
-		if !utils.FoundP(tc.wantP, k.Key.Primes) {
-			t.Errorf("Attack() failed: %s expected primes not found - got %v wanted %v", tc.name, k.Key.Primes, tc.wantP)
+		if r == nil || !utils.FoundP(tc.wantP, r.Factors) {
+			t.Errorf("Attack() failed: %s expected factor not found - got %v wanted %v", tc.name, r, tc.wantP)
 		}
 	}
 }
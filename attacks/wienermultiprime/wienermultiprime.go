@@ -1,8 +1,10 @@
 package wienermultiprime
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 
@@ -11,14 +13,25 @@ import (
 
 const name = "wiener multiprime"
 
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "wienermultiprime",
+		Description: "Wiener's attack extended to moduli with more than two primes.",
+		Category:    attacks.CategoryExponent,
+		Reference:   "https://eprint.iacr.org/2015/1123",
+		Expects:     []attacks.Feature{attacks.FeatureLargeE},
+		Unnatended:  true,
+		F:           Attack,
+	})
+}
+
 // Attack implements the Wiener attack on an RSA public key where the modulus is composed of
 // more than 2 primes.
-func Attack(ts []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ts []*keys.RSA) (*keys.Result, error) {
 	t := ts[0]
 	if t.Key.D != nil {
 		// Key already factored.
-		ch <- nil
-		return
+		return nil, nil
 	}
 
 	// Encrypt something simple to validate our decryption later.
@@ -30,8 +43,16 @@ func Attack(ts []*keys.RSA, ch chan error) {
 	frac := ln.RationalToContfract(t.Key.PublicKey.E, t.Key.N)
 	convergants := ln.ConvergantsFromContfract(frac)
 
+	progress := attacks.ProgressFrom(ctx)
+	progress.Start("convergents", int64(len(convergants)))
+
 	var r, s int64
 	for _, g := range convergants {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		progress.Add(1)
+
 		q1 := g[1] // denominator
 
 		for r = 0; r < 20; r++ {
@@ -43,14 +64,12 @@ func Attack(ts []*keys.RSA, ch chan error) {
 				// Test decryption with our new d.
 				m1 := new(fmp.Fmpz).Pow(c, d, t.Key.N)
 				if m1.Cmp(m) == 0 {
-					t.PackGivenD(d)
-					ch <- nil
-					return
+					return &keys.Result{D: d}, nil
 				}
 			}
 			q0 = q0.Set(q1)
 		}
 	}
 
-	ch <- fmt.Errorf("%s attack failed", name)
+	return nil, fmt.Errorf("%s attack failed", name)
 }
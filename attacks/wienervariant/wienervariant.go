@@ -3,8 +3,10 @@
 package wienervariant
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 
@@ -15,11 +17,10 @@ import (
 const name = "wiener variant"
 
 // Attack performs a variant of the wiener attack by Andrej Dujella.
-func Attack(ks []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ks []*keys.RSA) (*keys.Result, error) {
 	k := ks[0]
 	if k.Key.D != nil {
-		ch <- nil
-		return
+		return nil, nil
 	}
 
 	var (
@@ -30,7 +31,15 @@ func Attack(ks []*keys.RSA, ch chan error) {
 
 	convergants := ln.ConvergantsFromContfract(ln.RationalToContfract(k.Key.PublicKey.E, k.Key.N))
 
+	progress := attacks.ProgressFrom(ctx)
+	progress.Start("convergents", int64(len(convergants)))
+
 	for _, c := range convergants {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		progress.Add(1)
+
 		q1 := c[1]
 
 		for r := 0; r <= 30; r++ {
@@ -38,9 +47,7 @@ func Attack(ks []*keys.RSA, ch chan error) {
 				d := new(fmp.Fmpz).Set(q1).MulI(r).AddZ(new(fmp.Fmpz).Set(q0).MulI(s))
 				mMaybe := new(fmp.Fmpz).Exp(fakeC, d, k.Key.N)
 				if mMaybe.Equals(fakeM) {
-					k.PackGivenP(ln.FindPGivenD(d, k.Key.PublicKey.E, k.Key.N))
-					ch <- nil
-					return
+					return &keys.Result{Factors: []*fmp.Fmpz{ln.FindPGivenD(d, k.Key.PublicKey.E, k.Key.N)}, D: d}, nil
 				}
 			}
 		}
@@ -48,5 +55,5 @@ func Attack(ks []*keys.RSA, ch chan error) {
 		q0.Set(q1)
 	}
 
-	ch <- fmt.Errorf("%s attack failed", name)
+	return nil, fmt.Errorf("%s attack failed", name)
 }
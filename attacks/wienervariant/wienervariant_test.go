@@ -1,6 +1,7 @@
 package wienervariant
 
 import (
+	"context"
 	"testing"
 
 	"github.com/sourcekris/goRsaTool/keys"
@@ -35,15 +36,16 @@ func TestAttack(t *testing.T) {
 		c := new(fmp.Fmpz).Exp(fmp.NewFmpz(31337), tc.e, tc.n)
 
 		k, _ := keys.NewRSA(keys.PrivateFromPublic(fmpPubKey), ln.NumberToBytes(c), nil, "", false)
-		ch := make(chan error)
-		go Attack([]*keys.RSA{k}, ch)
-		err := <-ch
+		r, err := Attack(context.Background(), []*keys.RSA{k})
+		if err == nil {
+			err = keys.Merge([]*keys.RSA{k}, r)
+		}
 		if err != nil {
 			t.Errorf("Attack() failed: %s expected no error got error: %v", tc.name, err)
 		}
 
-		if !utils.FoundP(tc.want, k.Key.Primes) {
-			t.Errorf("Attack() failed: %s expected primes not found - got %v wanted %v", tc.name, k.Key.Primes, tc.want)
+		if r == nil || !utils.FoundP(tc.want, r.Factors) {
+			t.Errorf("Attack() failed: %s expected factor not found - got %v wanted %v", tc.name, r, tc.want)
 		}
 	}
 }
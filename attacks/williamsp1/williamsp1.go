@@ -1,7 +1,9 @@
 package williamsp1
 
 import (
+	"context"
 	"github.com/jbarham/primegen"
+	"github.com/sourcekris/goRsaTool/attacks"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
 
@@ -11,14 +13,34 @@ import (
 // name is the name of this attack.
 const name = "william's p+1"
 
+func init() {
+	attacks.Register(&attacks.Attack{
+		Name:        "williamsp1",
+		Description: "Williams' p+1 factorization for primes where p+1 is smooth.",
+		Category:    attacks.CategoryFactorization,
+		Reference:   "H. C. Williams, \"A p+1 Method of Factoring\", Math. Comp. 1982",
+		Unnatended:  true,
+		F:           Attack,
+	})
+}
+
 // Attack performs williams P+1 factorization.
-func Attack(ks []*keys.RSA, ch chan error) {
+func Attack(ctx context.Context, ks []*keys.RSA) (*keys.Result, error) {
 	k := ks[0]
 	p := primegen.New()
 	v := fmp.NewFmpz(0)
+
+	progress := attacks.ProgressFrom(ctx)
+	progress.Start("primes", 0)
+
 	for {
 		v.Add(v, ln.BigOne)
 		for {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			progress.Add(1)
+
 			pcursor := fmp.NewFmpz(int64(p.Next()))
 			e := ln.ILog(new(fmp.Fmpz).Set(k.Key.N).Root(k.Key.N, 2), pcursor)
 			if e.Equals(ln.BigZero) {
@@ -33,8 +55,7 @@ func Attack(ks []*keys.RSA, ch chan error) {
 			g := ln.FindGcd(new(fmp.Fmpz).Set(v).Sub(v, ln.BigTwo), k.Key.N)
 			if g.Cmp(ln.BigOne) > 0 && g.Cmp(k.Key.N) < 0 {
 				// Found P.
-				k.PackGivenP(g)
-				ch <- nil
+				return &keys.Result{Factors: []*fmp.Fmpz{g}}, nil
 			}
 
 			if g.Equals(k.Key.N) {
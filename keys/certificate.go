@@ -0,0 +1,161 @@
+package keys
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+// PEM block types of certificates and certificate signing requests.
+var certPEMTypes = map[string]bool{
+	"CERTIFICATE":             true,
+	"TRUSTED CERTIFICATE":     true,
+	"CERTIFICATE REQUEST":     true,
+	"NEW CERTIFICATE REQUEST": true,
+}
+
+// signed mirrors the outer structure shared by X.509 certificates and PKCS#10 requests, the
+// signed data followed by the signature algorithm and signature.
+type signed struct {
+	Data      asn1.RawValue
+	Algorithm pkix.AlgorithmIdentifier
+	Signature asn1.BitString
+}
+
+// tbsCertificate mirrors the X.509 TBSCertificate ASN.1 structure. Only the fields needed to find
+// the key and names are decoded so certificates with unusual keys or extensions still parse.
+type tbsCertificate struct {
+	Version         int `asn1:"optional,explicit,default:0,tag:0"`
+	SerialNumber    asn1.RawValue
+	Algorithm       pkix.AlgorithmIdentifier
+	Issuer          asn1.RawValue
+	Validity        asn1.RawValue
+	Subject         asn1.RawValue
+	PublicKey       asn1.RawValue
+	IssuerUniqueID  asn1.BitString `asn1:"optional,tag:1"`
+	SubjectUniqueID asn1.BitString `asn1:"optional,tag:2"`
+	Extensions      asn1.RawValue  `asn1:"optional,explicit,tag:3"`
+}
+
+// certificationRequestInfo mirrors the PKCS#10 CertificationRequestInfo ASN.1 structure.
+type certificationRequestInfo struct {
+	Version    int
+	Subject    asn1.RawValue
+	PublicKey  asn1.RawValue
+	Attributes asn1.RawValue `asn1:"optional,tag:0"`
+}
+
+// parseName decodes the distinguished name encoded in raw.
+func parseName(raw asn1.RawValue) pkix.Name {
+	var (
+		rdns pkix.RDNSequence
+		n    pkix.Name
+	)
+
+	if _, err := asn1.Unmarshal(raw.FullBytes, &rdns); err == nil {
+		n.FillFromRDNSequence(&rdns)
+	}
+
+	return n
+}
+
+// certificate is what is taken from an X.509 certificate or PKCS#10 request. The issuer of a
+// request is empty and the key is nil when it is not an RSA key.
+type certificate struct {
+	key             *FMPPublicKey
+	subject, issuer pkix.Name
+}
+
+// parseCertificate decodes a DER encoded X.509 certificate or PKCS#10 request and returns the
+// remaining bytes after it.
+func parseCertificate(der []byte) (*certificate, []byte, error) {
+	var s signed
+	rest, err := asn1.Unmarshal(der, &s)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parseCertificate: %v", err)
+	}
+
+	var (
+		c    = &certificate{}
+		spki asn1.RawValue
+		tbs  tbsCertificate
+		cri  certificationRequestInfo
+	)
+
+	if _, err := asn1.Unmarshal(s.Data.FullBytes, &tbs); err == nil {
+		spki, c.subject, c.issuer = tbs.PublicKey, parseName(tbs.Subject), parseName(tbs.Issuer)
+	} else if _, err := asn1.Unmarshal(s.Data.FullBytes, &cri); err == nil {
+		spki, c.subject = cri.PublicKey, parseName(cri.Subject)
+	} else {
+		return nil, nil, errors.New("parseCertificate: not a certificate or certificate request")
+	}
+
+	var pki publicKeyInfo
+	if _, err := asn1.Unmarshal(spki.FullBytes, &pki); err != nil {
+		return nil, nil, fmt.Errorf("parseCertificate: invalid public key of %s: %v", c.subject, err)
+	}
+
+	if !pki.Algorithm.Algorithm.Equal(oidPublicKeyRSA) {
+		// Not an RSA key, there is nothing to attack.
+		return c, rest, nil
+	}
+
+	if c.key, err = parsePublicRsaKey(spki.FullBytes); err != nil {
+		return nil, nil, fmt.Errorf("parseCertificate: invalid RSA key of %s: %v", c.subject, err)
+	}
+
+	return c, rest, nil
+}
+
+// newRSA returns an RSA for the certificate's key, labelled with the subject's common name.
+func (c *certificate) newRSA() (*RSA, error) {
+	k, err := NewRSA(PrivateFromPublic(c.key), nil, nil, "", false)
+	if err != nil {
+		return nil, err
+	}
+
+	k.Label = c.subject.CommonName
+	if k.Label == "" {
+		k.Label = c.subject.String()
+	}
+	k.Subject, k.Issuer = c.subject.String(), c.issuer.String()
+
+	return k, nil
+}
+
+// importCertificates imports the RSA keys of every certificate or request in concatenated DER.
+// Certificates holding other types of key are skipped.
+func importCertificates(der []byte) ([]*RSA, error) {
+	var (
+		ks   []*RSA
+		errs []error
+	)
+
+	for len(der) > 0 {
+		c, rest, err := parseCertificate(der)
+		if err != nil {
+			errs = append(errs, err)
+			break
+		}
+		der = rest
+
+		if c.key == nil {
+			errs = append(errs, fmt.Errorf("%s does not hold an RSA key", c.subject))
+			continue
+		}
+
+		k, err := c.newRSA()
+		if err != nil {
+			return nil, err
+		}
+
+		ks = append(ks, k)
+	}
+
+	if len(ks) == 0 {
+		return nil, fmt.Errorf("importCertificates: no RSA keys found: %v", errs)
+	}
+
+	return ks, nil
+}
@@ -0,0 +1,135 @@
+package keys
+
+import (
+	"encoding/pem"
+	"testing"
+)
+
+const (
+	// fermatCert is a self-signed certificate for the key in examples/fermat.pub.
+	fermatCert = `-----BEGIN CERTIFICATE-----
+MIICRjCCAa+gAwIBAgIUc5gebQ+ZB9jDgGCZXZxoRS4uUMIwDQYJKoZIhvcNAQEL
+BQAwNTEbMBkGA1UEAwwSZmVybWF0LmV4YW1wbGUuY29tMRYwFAYDVQQKDA1XZWFr
+IEtleXMgTHRkMB4XDTI2MTAxNzA5NTIwNVoXDTM2MTAxNDA5NTIwNVowNTEbMBkG
+A1UEAwwSZmVybWF0LmV4YW1wbGUuY29tMRYwFAYDVQQKDA1XZWFrIEtleXMgTHRk
+MIGfMA0GCSqGSIb3DQEBAQUAA4GNADCBiQKBgQDolThJ8R6TLpEnrzXhAAAAAAAA
+AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAABR+Ot9
+BVbgn///////////////////////////////////////////////////////////
+//////////////utVQIDAQABo1MwUTAdBgNVHQ4EFgQU6fPBzntVMcl8SGa/PtKN
+WL6BbIYwHwYDVR0jBBgwFoAU6fPBzntVMcl8SGa/PtKNWL6BbIYwDwYDVR0TAQH/
+BAUwAwEB/zANBgkqhkiG9w0BAQsFAAOBgQCsoF7//ogxTHgILLWl6gBwsW2fhXmC
+SB5cnkaCXpB8SVywf02wioDs692lbPUXtPTiQZN+WVwsgBZIa9u4Ftf5INhQ0FWK
+Aq/JBUpMSDMCYh+azeMexYWfJ2Zl2hSMlDsHtCpYzw2Ni4CwazutnhRMnrSQHAnz
+P241JPILwrPCOg==
+-----END CERTIFICATE-----`
+	fermatCSR = `-----BEGIN CERTIFICATE REQUEST-----
+MIIBWTCBwwIBADAaMRgwFgYDVQQDDA9jc3IuZXhhbXBsZS5jb20wgZ8wDQYJKoZI
+hvcNAQEBBQADgY0AMIGJAoGBAOiVOEnxHpMukSevNeEAAAAAAAAAAAAAAAAAAAAA
+AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAFH4630FVuCf////////
+////////////////////////////////////////////////////////////////
++61VAgMBAAGgADANBgkqhkiG9w0BAQsFAAOBgQB/YBSGBSp5u4aJndb+cnNP1e0u
+NWRc35mdnBZdclw4SHjLlJfwBHeVR38uO8fuaSP+zlGYjSQGsnkhjZkuGFDiRRei
+DRTGnrjI7eYFG2m2hs+4Tv3s+Tc6N8/lqZVDfCRqQWDC9lZwHmFGghRb2KBYECUq
+tV4e33NK4+SN+9m/JQ==
+-----END CERTIFICATE REQUEST-----`
+	ecCert = `-----BEGIN CERTIFICATE-----
+MIIBhzCCAS2gAwIBAgIUSdoAdYJupNM4rGaCpM5fFmBvoPIwCgYIKoZIzj0EAwIw
+GTEXMBUGA1UEAwwOZWMuZXhhbXBsZS5jb20wHhcNMjYxMDE3MDk1MjA1WhcNMzYx
+MDE0MDk1MjA1WjAZMRcwFQYDVQQDDA5lYy5leGFtcGxlLmNvbTBZMBMGByqGSM49
+AgEGCCqGSM49AwEHA0IABJu8huZYnigZJK5bHp5wmn+Ixzk+D7VY/wUV1nGfRKo1
+WIOT9hvRRUHqwSJX5gpDL+uqpPWiFGhPvKF6v3p0loWjUzBRMB0GA1UdDgQWBBR8
+j2QrFt+WAAzABT/EE3/55HAmmzAfBgNVHSMEGDAWgBR8j2QrFt+WAAzABT/EE3/5
+5HAmmzAPBgNVHRMBAf8EBTADAQH/MAoGCCqGSM49BAMCA0gAMEUCIQCR7TtKFEvF
+BKrAaelr/6VzGzN+o8Mnu8wtn3pLx2I5AQIgEvK1jvxpMaErz8sjecK6+VneaI66
+zeltqMaP2/uLZpI=
+-----END CERTIFICATE-----`
+	fermatN = "163325259729739139586456854939342071588766536976661696628405612100543978684304953042431845499808366612030757037530278155957389217094639917994417350499882225626580260012564702898468467277918937337494297292631474713546289580689715170963879872522418640251986734692138838546500522994170062961577034037699354013013"
+)
+
+func TestImportCertificates(t *testing.T) {
+	block, _ := pem.Decode([]byte(fermatCert))
+
+	tt := []struct {
+		name    string
+		kb      []byte
+		want    []string
+		subject string
+		issuer  string
+		wantErr bool
+	}{
+		{
+			name:    "pem certificate",
+			kb:      []byte(fermatCert),
+			want:    []string{"fermat.example.com"},
+			subject: "CN=fermat.example.com,O=Weak Keys Ltd",
+			issuer:  "CN=fermat.example.com,O=Weak Keys Ltd",
+		},
+		{
+			name:    "der certificate",
+			kb:      block.Bytes,
+			want:    []string{"fermat.example.com"},
+			subject: "CN=fermat.example.com,O=Weak Keys Ltd",
+			issuer:  "CN=fermat.example.com,O=Weak Keys Ltd",
+		},
+		{
+			name:    "pem certificate request",
+			kb:      []byte(fermatCSR),
+			want:    []string{"csr.example.com"},
+			subject: "CN=csr.example.com",
+		},
+		{
+			name:    "chain with a non RSA certificate",
+			kb:      []byte(ecCert + "\n" + fermatCert + "\n" + fermatCSR),
+			want:    []string{"fermat.example.com", "csr.example.com"},
+			subject: "CN=fermat.example.com,O=Weak Keys Ltd",
+			issuer:  "CN=fermat.example.com,O=Weak Keys Ltd",
+		},
+		{
+			name:    "der chain",
+			kb:      append(append([]byte{}, block.Bytes...), block.Bytes...),
+			want:    []string{"fermat.example.com", "fermat.example.com"},
+			subject: "CN=fermat.example.com,O=Weak Keys Ltd",
+			issuer:  "CN=fermat.example.com,O=Weak Keys Ltd",
+		},
+		{
+			name:    "no RSA keys",
+			kb:      []byte(ecCert),
+			wantErr: true,
+		},
+		{
+			name:    "truncated der",
+			kb:      block.Bytes[:100],
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		ks, err := ImportKeys(tc.kb)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: ImportKeys() expected error got nil", tc.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: ImportKeys() failed: %v", tc.name, err)
+			continue
+		}
+
+		if len(ks) != len(tc.want) {
+			t.Errorf("%s: ImportKeys() got %d keys want %d", tc.name, len(ks), len(tc.want))
+			continue
+		}
+
+		for i, k := range ks {
+			if k.Label != tc.want[i] || k.Key.N.String() != fermatN {
+				t.Errorf("%s: key %d got label %q n=%v want label %q n=%s", tc.name, i, k.Label, k.Key.N, tc.want[i], fermatN)
+			}
+		}
+
+		if ks[0].Subject != tc.subject || ks[0].Issuer != tc.issuer {
+			t.Errorf("%s: got subject %q issuer %q want %q and %q", tc.name, ks[0].Subject, ks[0].Issuer, tc.subject, tc.issuer)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package keys
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sourcekris/x509big"
+)
+
+// Format is an encoding keys can be exported in.
+type Format string
+
+const (
+	// FormatPKCS1 is a PEM encoded PKCS#1 RSA PRIVATE KEY or RSA PUBLIC KEY.
+	FormatPKCS1 Format = "pkcs1"
+	// FormatPKCS8 is a PEM encoded PKCS#8 PRIVATE KEY or, for public keys, a PKIX PUBLIC KEY.
+	FormatPKCS8 Format = "pkcs8"
+	// FormatOpenSSH is an unencrypted openssh-key-v1 private key or an ssh-rsa public key line.
+	FormatOpenSSH Format = "openssh"
+	// FormatDER is binary DER, PKCS#1 for private keys and PKIX for public keys like openssl.
+	FormatDER Format = "der"
+	// FormatJWK is a JSON Web Key.
+	FormatJWK Format = "jwk"
+)
+
+// Formats lists the supported formats.
+var Formats = []Format{FormatPKCS1, FormatPKCS8, FormatOpenSSH, FormatDER, FormatJWK}
+
+// ParseFormat returns the format called s, an empty string is FormatPKCS1.
+func ParseFormat(s string) (Format, error) {
+	if s == "" {
+		return FormatPKCS1, nil
+	}
+
+	for _, f := range Formats {
+		if strings.EqualFold(s, string(f)) {
+			return f, nil
+		}
+	}
+
+	var fs []string
+	for _, f := range Formats {
+		fs = append(fs, string(f))
+	}
+
+	return "", fmt.Errorf("unsupported key format %q, expected one of %s", s, strings.Join(fs, ", "))
+}
+
+// Binary returns true if keys in the format are not printable text.
+func (f Format) Binary() bool {
+	return f == FormatDER
+}
+
+var (
+	oidPublicKeyRSA = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	rsaAlgorithm    = pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyRSA, Parameters: asn1.NullRawValue}
+)
+
+// pkcs8 mirrors the PKCS#8 PrivateKeyInfo ASN.1 structure.
+type pkcs8 struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+// publicKeyInfo mirrors the PKIX SubjectPublicKeyInfo ASN.1 structure.
+type publicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// marshalPKIXPublicKey returns pub as a DER encoded SubjectPublicKeyInfo.
+func marshalPKIXPublicKey(pub *FMPPublicKey) ([]byte, error) {
+	der := x509big.MarshalPKCS1BigPublicKey(FMPtoBigPublicKey(pub))
+	return asn1.Marshal(publicKeyInfo{
+		Algorithm: rsaAlgorithm,
+		PublicKey: asn1.BitString{Bytes: der, BitLength: 8 * len(der)},
+	})
+}
+
+// marshalPKCS8PrivateKey returns priv as a DER encoded PKCS#8 PrivateKeyInfo.
+func marshalPKCS8PrivateKey(priv *FMPPrivateKey) ([]byte, error) {
+	return asn1.Marshal(pkcs8{
+		Algo:       rsaAlgorithm,
+		PrivateKey: x509big.MarshalPKCS1BigPrivateKey(FMPtoBigPrivateKey(priv)),
+	})
+}
+
+// parsePKCS8PrivateKey parses a DER encoded PKCS#8 RSA private key.
+func parsePKCS8PrivateKey(der []byte) (*FMPPrivateKey, error) {
+	var k pkcs8
+	if rest, err := asn1.Unmarshal(der, &k); err != nil {
+		return nil, fmt.Errorf("parsePKCS8PrivateKey: %v", err)
+	} else if len(rest) != 0 {
+		return nil, errors.New("parsePKCS8PrivateKey: trailing data after PKCS#8 key")
+	}
+
+	if !k.Algo.Algorithm.Equal(oidPublicKeyRSA) {
+		return nil, fmt.Errorf("parsePKCS8PrivateKey: unsupported key algorithm %v", k.Algo.Algorithm)
+	}
+
+	return parseBigPrivateRsaKey(k.PrivateKey)
+}
+
+// exportable returns priv with its CRT values computed, or an error if priv lacks the values
+// needed to encode it or they don't form a valid key.
+func exportable(priv *FMPPrivateKey) (*FMPPrivateKey, error) {
+	if priv.D == nil || len(priv.Primes) < 2 {
+		return nil, errors.New("private key needs d and at least two primes to be exported")
+	}
+
+	if priv.precomputed() {
+		return priv, nil
+	}
+
+	c := priv.copy()
+	if err := c.Precompute(); err != nil {
+		return nil, fmt.Errorf("private key cannot be exported: %v", err)
+	}
+
+	return &c, nil
+}
+
+// EncodePrivateKey encodes priv in format f.
+func EncodePrivateKey(priv *FMPPrivateKey, f Format) ([]byte, error) {
+	priv, err := exportable(priv)
+	if err != nil {
+		return nil, err
+	}
+
+	switch f {
+	case FormatPKCS1:
+		return []byte(EncodeFMPPrivateKey(priv)), nil
+	case FormatPKCS8:
+		der, err := marshalPKCS8PrivateKey(priv)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(encodeDerToPem(der, "PRIVATE KEY")), nil
+	case FormatOpenSSH:
+		return encodeOpenSSHPrivateKey(priv, "")
+	case FormatDER:
+		return x509big.MarshalPKCS1BigPrivateKey(FMPtoBigPrivateKey(priv)), nil
+	case FormatJWK:
+		return encodePrivateJWK(priv)
+	}
+
+	return nil, fmt.Errorf("unsupported key format %q", f)
+}
+
+// EncodePublicKey encodes pub in format f.
+func EncodePublicKey(pub *FMPPublicKey, f Format) ([]byte, error) {
+	switch f {
+	case FormatPKCS1:
+		return []byte(EncodeFMPPublicKey(pub)), nil
+	case FormatPKCS8, FormatDER:
+		der, err := marshalPKIXPublicKey(pub)
+		if err != nil {
+			return nil, err
+		}
+		if f == FormatDER {
+			return der, nil
+		}
+		return []byte(encodeDerToPem(der, "PUBLIC KEY")), nil
+	case FormatOpenSSH:
+		return []byte(sshRSA + " " + base64.StdEncoding.EncodeToString(marshalSSHPublicKey(pub)) + "\n"), nil
+	case FormatJWK:
+		return encodePublicJWK(pub)
+	}
+
+	return nil, fmt.Errorf("unsupported key format %q", f)
+}
@@ -0,0 +1,82 @@
+package keys
+
+import (
+	"testing"
+
+	fmp "github.com/sourcekris/goflint"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	priv := PrivateFromPublic(&FMPPublicKey{N: fmp.NewFmpz(3233), E: fmp.NewFmpz(17)})
+	priv.D = fmp.NewFmpz(2753)
+	priv.Primes = []*fmp.Fmpz{fmp.NewFmpz(61), fmp.NewFmpz(53)}
+
+	for _, f := range Formats {
+		b, err := EncodePrivateKey(priv, f)
+		if err != nil {
+			t.Errorf("EncodePrivateKey(%s) failed: %v", f, err)
+			continue
+		}
+
+		var got *FMPPrivateKey
+		if f == FormatDER {
+			got, err = parseBigPrivateRsaKey(b)
+		} else {
+			var k *RSA
+			if k, err = ImportKey(b); err == nil {
+				got = &k.Key
+			}
+		}
+		if err != nil {
+			t.Errorf("importing %s private key failed: %v", f, err)
+			continue
+		}
+
+		if !got.PublicKey.N.Equals(priv.PublicKey.N) || !got.PublicKey.E.Equals(priv.PublicKey.E) || !got.D.Equals(priv.D) || len(got.Primes) != 2 {
+			t.Errorf("%s private key round trip got n=%v e=%v d=%v primes=%v", f, got.PublicKey.N, got.PublicKey.E, got.D, got.Primes)
+		}
+
+		if pc := got.Precomputed; pc == nil || pc.Dp == nil || pc.Dq == nil || pc.Qinv == nil {
+			t.Errorf("%s private key round trip got CRT values %+v want them precomputed", f, pc)
+		}
+
+		b, err = EncodePublicKey(priv.PublicKey, f)
+		if err != nil {
+			t.Errorf("EncodePublicKey(%s) failed: %v", f, err)
+			continue
+		}
+
+		var pub *FMPPublicKey
+		if f == FormatDER {
+			pub, err = parsePublicRsaKey(b)
+		} else {
+			var k *RSA
+			if k, err = ImportKey(b); err == nil {
+				pub = k.Key.PublicKey
+			}
+		}
+		if err != nil {
+			t.Errorf("importing %s public key failed: %v", f, err)
+			continue
+		}
+
+		if !pub.N.Equals(priv.PublicKey.N) || !pub.E.Equals(priv.PublicKey.E) {
+			t.Errorf("%s public key round trip got n=%v e=%v", f, pub.N, pub.E)
+		}
+	}
+
+	mp := PrivateFromPublic(&FMPPublicKey{N: fmp.NewFmpz(1001), E: fmp.NewFmpz(7)})
+	mp.D = fmp.NewFmpz(103)
+	mp.Primes = []*fmp.Fmpz{fmp.NewFmpz(7), fmp.NewFmpz(11), fmp.NewFmpz(13)}
+	if _, err := EncodePrivateKey(mp, FormatOpenSSH); err == nil {
+		t.Errorf("EncodePrivateKey(openssh) of a 3 prime key expected error got nil")
+	}
+
+	if _, err := EncodePrivateKey(PrivateFromPublic(priv.PublicKey), FormatPKCS1); err == nil {
+		t.Errorf("EncodePrivateKey() of a public key expected error got nil")
+	}
+
+	if _, err := ParseFormat("pem"); err == nil {
+		t.Errorf("ParseFormat(pem) expected error got nil")
+	}
+}
@@ -0,0 +1,97 @@
+package keys
+
+import (
+	"github.com/sourcekris/goRsaTool/ln"
+
+	fmp "github.com/sourcekris/goflint"
+)
+
+// Factorization tracks what is known about the factors of a modulus: the primes found so far and
+// the composite cofactors that are still to be split. The product of Primes and Composites is
+// always N.
+type Factorization struct {
+	N *fmp.Fmpz
+	// Primes holds every prime factor found so far, repeated primes appear once per power.
+	Primes     []*fmp.Fmpz
+	Composites []*fmp.Fmpz
+}
+
+// NewFactorization returns a Factorization of n where nothing is known yet.
+func NewFactorization(n *fmp.Fmpz) *Factorization {
+	f := &Factorization{N: new(fmp.Fmpz).Set(n)}
+	f.add(new(fmp.Fmpz).Set(n))
+
+	return f
+}
+
+// Complete returns true if every factor of N is known to be prime.
+func (f *Factorization) Complete() bool {
+	return len(f.Composites) == 0
+}
+
+// Refine splits any composite cofactor that shares a factor with d, then keeps dividing out the
+// primes found until no more progress can be made. It returns true if the factorization changed.
+func (f *Factorization) Refine(d *fmp.Fmpz) bool {
+	if !f.split(d) {
+		return false
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, p := range f.Primes {
+			if f.split(p) {
+				changed = true
+			}
+		}
+	}
+
+	return true
+}
+
+// split does a single pass over the composites splitting each one using its gcd with d.
+func (f *Factorization) split(d *fmp.Fmpz) bool {
+	var (
+		changed bool
+		cs      = f.Composites
+	)
+
+	f.Composites = nil
+	for _, c := range cs {
+		g := new(fmp.Fmpz).GCD(c, d)
+		if g.Cmp(ln.BigOne) <= 0 || g.Equals(c) {
+			f.Composites = append(f.Composites, c)
+			continue
+		}
+
+		changed = true
+		f.add(g)
+		f.add(new(fmp.Fmpz).Div(c, g))
+	}
+
+	return changed
+}
+
+// add records n as a prime or a composite factor.
+func (f *Factorization) add(n *fmp.Fmpz) {
+	switch {
+	case n.Cmp(ln.BigOne) <= 0:
+		return
+	case n.IsProbabPrime() > 0:
+		f.Primes = append(f.Primes, n)
+	default:
+		f.Composites = append(f.Composites, n)
+	}
+}
+
+// copy returns a deep copy of f.
+func (f *Factorization) copy() *Factorization {
+	if f == nil {
+		return nil
+	}
+
+	return &Factorization{
+		N:          copyFmpz(f.N),
+		Primes:     copyFmpzs(f.Primes),
+		Composites: copyFmpzs(f.Composites),
+	}
+}
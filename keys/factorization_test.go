@@ -0,0 +1,88 @@
+package keys
+
+import (
+	"testing"
+
+	fmp "github.com/sourcekris/goflint"
+)
+
+func TestFactorization(t *testing.T) {
+	tt := []struct {
+		name     string
+		n        int64
+		divisors []int64
+		// wantPrimes is the number of primes known after refining with divisors.
+		wantPrimes   int
+		wantComplete bool
+	}{
+		{
+			name:         "one factor of a two prime modulus completes it",
+			n:            11 * 13,
+			divisors:     []int64{11},
+			wantPrimes:   2,
+			wantComplete: true,
+		},
+		{
+			name:       "one factor of a three prime modulus leaves a composite",
+			n:          11 * 13 * 17,
+			divisors:   []int64{13},
+			wantPrimes: 1,
+		},
+		{
+			name:         "composite divisors are split further",
+			n:            11 * 13 * 17,
+			divisors:     []int64{11 * 13, 13 * 17},
+			wantPrimes:   3,
+			wantComplete: true,
+		},
+		{
+			name:         "repeated primes are divided out",
+			n:            11 * 11 * 11 * 13,
+			divisors:     []int64{11},
+			wantPrimes:   4,
+			wantComplete: true,
+		},
+		{
+			name:       "divisors sharing no factor change nothing",
+			n:          11 * 13 * 17,
+			divisors:   []int64{19, 1},
+			wantPrimes: 0,
+		},
+	}
+
+	for _, tc := range tt {
+		f := NewFactorization(fmp.NewFmpz(tc.n))
+		for _, d := range tc.divisors {
+			f.Refine(fmp.NewFmpz(d))
+		}
+
+		if got := len(f.Primes); got != tc.wantPrimes {
+			t.Errorf("%s: got %d primes %v want %d", tc.name, got, f.Primes, tc.wantPrimes)
+		}
+
+		if got := f.Complete(); got != tc.wantComplete {
+			t.Errorf("%s: Complete() = %t want %t", tc.name, got, tc.wantComplete)
+		}
+
+		n := fmp.NewFmpz(1)
+		for _, p := range append(f.Primes, f.Composites...) {
+			n.MulZ(p)
+		}
+		if !n.Equals(fmp.NewFmpz(tc.n)) {
+			t.Errorf("%s: product of the factors is %v want %d", tc.name, n, tc.n)
+		}
+	}
+}
+
+func TestPackMultiPrimeRepeatedPrimes(t *testing.T) {
+	// n = 11^2 * 13, phi = 11 * 10 * 12 = 1320.
+	k := newKey(11 * 11 * 13)
+	if err := k.PackMultiPrime([]*fmp.Fmpz{fmp.NewFmpz(11), fmp.NewFmpz(11), fmp.NewFmpz(13)}); err != nil {
+		t.Fatalf("PackMultiPrime() unexpected error: %v", err)
+	}
+
+	ed := new(fmp.Fmpz).Mul(k.Key.D, k.Key.PublicKey.E)
+	if !ed.Mod(ed, fmp.NewFmpz(1320)).Equals(fmp.NewFmpz(1)) {
+		t.Errorf("PackMultiPrime() d = %v is not the inverse of e mod phi(n)", k.Key.D)
+	}
+}
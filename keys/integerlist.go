@@ -0,0 +1,559 @@
+package keys
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sourcekris/goRsaTool/ln"
+	fmp "github.com/sourcekris/goflint"
+	mp "github.com/sourcekris/mathparse"
+)
+
+var (
+	// integerFields maps the names integers are given in challenge files, lower cased and without
+	// '_' or '-', to the field they are imported as.
+	integerFields = map[string]string{
+		"n":              "n",
+		"modulus":        "n",
+		"e":              "e",
+		"exponent":       "e",
+		"publicexponent": "e",
+		"c":              "c",
+		"ct":             "c",
+		"cipher":         "c",
+		"ciphertext":     "c",
+		"enc":            "c",
+		"encrypted":      "c",
+		"cs":             "c",
+		"cts":            "c",
+		"ciphertexts":    "c",
+		"p":              "p",
+		"prime1":         "p",
+		"q":              "q",
+		"prime2":         "q",
+		"dp":             "dp",
+		"dmp1":           "dp",
+		"exponent1":      "dp",
+		"dq":             "dq",
+		"dmq1":           "dq",
+		"exponent2":      "dq",
+		"d0":             "d0",
+		"kpt":            "kpt",
+		"e2":             "e2",
+		"e3":             "e3",
+		"e4":             "e4",
+		"e9":             "e9",
+	}
+
+	// listFields are the fields that may be given several times, as a list or with an index such
+	// as c1 and c2.
+	listFields = map[string]bool{"c": true, "kpt": true}
+	// indexRE splits an indexed name into its name and index.
+	indexRE = regexp.MustCompile(`^([a-z]+?)(\d+)$`)
+
+	// oracleFields are the fields holding the ciphertexts of 2, 3, 4 and 9 from an encryption oracle.
+	oracleFields = map[int]string{2: "e2", 3: "e3", 4: "e4", 9: "e9"}
+
+	// assignRE matches a name being given a value, e.g. n = 1, N: 1, "n": "1", 'n': 1 or self.n = 1.
+	assignRE = regexp.MustCompile(`(?m)(?:^|[\s{(,;])["']?([A-Za-z_][\w.]*)["']?[ \t]*(:=|[:=])`)
+	// tupleRE matches the names of a tuple assignment, e.g. n, e = 1, 2 or (n, e) = (1, 2).
+	tupleRE = regexp.MustCompile(`(?m)^[ \t]*\(?[ \t]*([A-Za-z_]\w*(?:[ \t]*,[ \t]*[A-Za-z_]\w*)+)[ \t]*\)?[ \t]*=[^=]`)
+	// wrapRE matches a line that continues the value on the line before it, e.g. a wrapped number.
+	wrapRE = regexp.MustCompile(`^[0-9a-fA-F+\-*/^%()\s]*[0-9a-fA-F][0-9a-fA-F+\-*/^%()\s]*$`)
+
+	// hexRE, longRE, castRE and nameRE match parts of values that are rewritten before evaluation,
+	// hex literals, Python 2 long literals, integer casts and names of other fields.
+	hexRE  = regexp.MustCompile(`\b0[xX][0-9a-fA-F]+\b`)
+	longRE = regexp.MustCompile(`\b(0[xX][0-9a-fA-F]+|\d+)[lL]\b`)
+	castRE = regexp.MustCompile(`\b(?:int|long|mpz|Integer)\(`)
+	nameRE = regexp.MustCompile(`[A-Za-z_][\w.]*`)
+	// exprRE matches what is left of an expression mathparse can evaluate.
+	exprRE = regexp.MustCompile(`^[0-9a-z+\-*/^%(),]+$`)
+)
+
+// mathFuncs are the functions mathparse evaluates.
+var mathFuncs = map[string]bool{"abs": true, "sqrt": true, "min": true, "max": true, "mod": true, "pow": true, "invmod": true}
+
+// IntegerList holds the integers of an integer list key file by field along with the names that
+// were found and ignored, which are reported to help fix files that fail to import.
+type IntegerList struct {
+	Fields map[string]*fmp.Fmpz
+	// Lists holds every value of the fields in listFields, Fields holds the first of them.
+	Lists   map[string][]*fmp.Fmpz
+	Found   []string
+	Ignored []string
+}
+
+// String returns the names found and ignored.
+func (l *IntegerList) String() string {
+	found := "nothing"
+	if len(l.Found) > 0 {
+		found = strings.Join(l.Found, ", ")
+	}
+
+	if len(l.Ignored) == 0 {
+		return fmt.Sprintf("found %s", found)
+	}
+
+	return fmt.Sprintf("found %s; ignored %s", found, strings.Join(l.Ignored, ", "))
+}
+
+// assignment is a value given to a name at an offset of an integer list.
+type assignment struct {
+	pos   int
+	name  string
+	value string
+}
+
+// normalizeName lower cases a name and removes any object prefix, '_' and '-'.
+func normalizeName(s string) string {
+	if i := strings.LastIndex(s, "."); i >= 0 {
+		s = s[i+1:]
+	}
+
+	return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(s))
+}
+
+// stripComments removes shell and Python style comments from every line of s.
+func stripComments(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		for j := 0; j < len(l); j++ {
+			if l[j] == '#' && (j == 0 || l[j-1] == ' ' || l[j-1] == '\t') {
+				lines[i] = l[:j]
+				break
+			}
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// continues returns true if the value v carries on past the line break at s[i].
+func continues(v, s string, i int) bool {
+	v = strings.TrimSpace(v)
+	if v != "" && strings.ContainsAny(v[len(v)-1:], `\+-*/^%`) {
+		return true
+	}
+
+	next := s[i+1:]
+	if j := strings.IndexByte(next, '\n'); j >= 0 {
+		next = next[:j]
+	}
+
+	return wrapRE.MatchString(next)
+}
+
+// scanValue returns the value starting at s[i] and the offset after it. A value ends at a comma,
+// semicolon, closing brace or line break outside of parentheses unless the next line continues it.
+// Nested objects are not values and are returned empty.
+func scanValue(s string, i int) (string, int) {
+	var (
+		b     strings.Builder
+		depth int
+	)
+
+	for ; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == '{' && depth == 0 && strings.TrimSpace(b.String()) == "":
+			return "", i
+		case ch == '(' || ch == '[':
+			depth++
+		case (ch == ')' || ch == ']') && depth == 0:
+			return b.String(), i
+		case ch == ')' || ch == ']':
+			depth--
+		case (ch == ',' || ch == ';' || ch == '}') && depth == 0:
+			return b.String(), i
+		case ch == '\n' && depth == 0 && !continues(b.String(), s, i):
+			return b.String(), i
+		}
+
+		b.WriteByte(s[i])
+	}
+
+	return b.String(), i
+}
+
+// findAssignments returns every name given a value in s, in the order they appear.
+func findAssignments(s string) []assignment {
+	var as []assignment
+
+	// Tuple assignments are taken first and blanked so their last name is not matched again.
+	b := []byte(s)
+	for _, m := range tupleRE.FindAllStringSubmatchIndex(s, -1) {
+		var (
+			names = strings.Split(s[m[2]:m[3]], ",")
+			i     = m[1] - 1
+			vs    []string
+		)
+
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '(') {
+			i++
+		}
+
+		for len(vs) < len(names) {
+			v, end := scanValue(s, i)
+			vs = append(vs, v)
+			if end >= len(s) || s[end] != ',' {
+				i = end
+				break
+			}
+			i = end + 1
+		}
+
+		if len(vs) != len(names) {
+			continue
+		}
+
+		for j, n := range names {
+			as = append(as, assignment{pos: m[0], name: strings.TrimSpace(n), value: vs[j]})
+		}
+
+		for j := m[0]; j < i && j < len(b); j++ {
+			if b[j] != '\n' {
+				b[j] = ' '
+			}
+		}
+	}
+	s = string(b)
+
+	end := 0
+	for _, m := range assignRE.FindAllStringSubmatchIndex(s, -1) {
+		if m[0] < end || (m[1] < len(s) && s[m[1]] == '=') {
+			continue
+		}
+
+		var v string
+		v, end = scanValue(s, m[1])
+		if strings.TrimSpace(v) == "" {
+			continue
+		}
+
+		as = append(as, assignment{pos: m[2], name: s[m[2]:m[3]], value: v})
+	}
+
+	sort.SliceStable(as, func(i, j int) bool { return as[i].pos < as[j].pos })
+
+	return as
+}
+
+// evalInteger evaluates the value v, a decimal or hex integer or an expression such as 2**16+1 or
+// p*q referring to the integers in vars.
+func evalInteger(v string, vars map[string]*fmp.Fmpz) (z *fmp.Fmpz, err error) {
+	// mathparse panics on some malformed expressions such as ().
+	defer func() {
+		if r := recover(); r != nil {
+			z, err = nil, fmt.Errorf("%q is not an integer expression", v)
+		}
+	}()
+
+	v = strings.TrimSpace(v)
+	if len(v) > 1 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+		v = v[1 : len(v)-1]
+	}
+
+	v = strings.NewReplacer("\\\n", "", "\\\r\n", "", "**", "^").Replace(v)
+	v = strings.Join(strings.Fields(v), "")
+	v = longRE.ReplaceAllString(v, "$1")
+	v = castRE.ReplaceAllString(v, "(")
+	v = hexRE.ReplaceAllStringFunc(v, func(h string) string {
+		z, _ := new(fmp.Fmpz).SetString(h[2:], 16)
+		return z.String()
+	})
+
+	v = nameRE.ReplaceAllStringFunc(v, func(name string) string {
+		if mathFuncs[name] {
+			return name
+		}
+
+		if z, ok := vars[normalizeName(name)]; ok {
+			return z.String()
+		}
+
+		if err == nil {
+			err = fmt.Errorf("unknown name %q", name)
+		}
+
+		return name
+	})
+
+	switch {
+	case err != nil:
+		return nil, err
+	case v == "":
+		return nil, fmt.Errorf("empty value")
+	case !exprRE.MatchString(v):
+		return nil, fmt.Errorf("%q is not an integer expression", v)
+	}
+
+	if z, ok := new(fmp.Fmpz).SetString(v, 10); ok {
+		return z, nil
+	}
+
+	return mp.Eval(v)
+}
+
+// listValue is a value of a field that may be given several times, with the index it was given.
+type listValue struct {
+	index int
+	z     *fmp.Fmpz
+}
+
+// isList returns true if v is a Python or JSON list or tuple of values.
+func isList(v string) bool {
+	v = strings.TrimSpace(v)
+	switch {
+	case strings.HasPrefix(v, "[") && strings.HasSuffix(v, "]"):
+		return true
+	case strings.HasPrefix(v, "(") && strings.HasSuffix(v, ")"):
+		return len(splitList(v)) > 1
+	}
+
+	return false
+}
+
+// splitList returns the values of the list or tuple v.
+func splitList(v string) []string {
+	v = strings.TrimSpace(v)
+	v = v[1 : len(v)-1]
+
+	var (
+		vs    []string
+		depth int
+		start int
+	)
+
+	for i := 0; i < len(v); i++ {
+		switch v[i] {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				vs = append(vs, v[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	if last := v[start:]; strings.TrimSpace(last) != "" {
+		vs = append(vs, last)
+	}
+
+	return vs
+}
+
+// ParseIntegerList finds the integers of a key given as a list of integers such as n = 0x1234 and
+// e = 65537. It reads Python and JSON style dumps, tuple assignments, numbers wrapped over several
+// lines and expressions like e = 2**16+1 or n = p*q. Common names like modulus, publicExponent and
+// ct are recognised, other names are ignored but may be used in expressions. Several ciphertexts
+// and known plaintexts may be given as a list, by repeating c or as indexed names like c1 and c2.
+func ParseIntegerList(kb []byte) (*IntegerList, error) {
+	var (
+		l     = &IntegerList{Fields: make(map[string]*fmp.Fmpz), Lists: make(map[string][]*fmp.Fmpz)}
+		vars  = make(map[string]*fmp.Fmpz)
+		seen  = make(map[string]bool)
+		lists = make(map[string][]listValue)
+	)
+
+	ignore := func(name string) {
+		if !seen[name] {
+			l.Ignored = append(l.Ignored, name)
+			seen[name] = true
+		}
+	}
+
+	for _, a := range findAssignments(stripComments(string(kb))) {
+		var (
+			name         = normalizeName(a.name)
+			field, known = integerFields[name]
+			index        = -1
+		)
+
+		if m := indexRE.FindStringSubmatch(name); !known && m != nil && listFields[integerFields[m[1]]] {
+			field, known = integerFields[m[1]], true
+			index, _ = strconv.Atoi(m[2])
+		}
+
+		if known && listFields[field] && isList(a.value) {
+			var n int
+			for _, v := range splitList(a.value) {
+				z, err := evalInteger(v, vars)
+				if err != nil {
+					l.Ignored = append(l.Ignored, fmt.Sprintf("%s value (%v)", a.name, err))
+					continue
+				}
+
+				lists[field] = append(lists[field], listValue{index: index, z: z})
+				n++
+			}
+
+			if n > 0 {
+				l.Found = append(l.Found, fmt.Sprintf("%s (%s, %d values)", field, a.name, n))
+			}
+			continue
+		}
+
+		z, err := evalInteger(a.value, vars)
+		if err != nil {
+			if known {
+				l.Ignored = append(l.Ignored, fmt.Sprintf("%s (%v)", a.name, err))
+			} else {
+				ignore(a.name)
+			}
+			continue
+		}
+
+		vars[name] = z
+		if !known {
+			ignore(a.name)
+			continue
+		}
+
+		if listFields[field] {
+			dup := false
+			for _, lv := range lists[field] {
+				dup = dup || lv.z.Equals(z)
+			}
+
+			if dup {
+				continue
+			}
+
+			lists[field] = append(lists[field], listValue{index: index, z: z})
+			if index < 0 {
+				vars[field] = z
+			}
+		} else {
+			_, dup := l.Fields[field]
+			vars[field], l.Fields[field] = z, z
+			if dup {
+				continue
+			}
+		}
+
+		if name == field {
+			l.Found = append(l.Found, field)
+		} else {
+			l.Found = append(l.Found, fmt.Sprintf("%s (%s)", field, a.name))
+		}
+	}
+
+	// Values without an index come first, then indexed values in index order.
+	for field, lvs := range lists {
+		sort.SliceStable(lvs, func(i, j int) bool { return lvs[i].index < lvs[j].index })
+		for _, lv := range lvs {
+			l.Lists[field] = append(l.Lists[field], lv.z)
+		}
+		l.Fields[field] = l.Lists[field][0]
+	}
+
+	if len(l.Fields) == 0 {
+		return nil, fmt.Errorf("ParseIntegerList: no integers found: %v", l)
+	}
+
+	return l, nil
+}
+
+// addLists adds the ciphertexts and known plaintexts of the integer list to k.
+func (l *IntegerList) addLists(k *RSA) {
+	for _, c := range l.Lists["c"] {
+		k.AddCipherText(ln.NumberToBytes(c))
+	}
+
+	for i, kpt := range l.Lists["kpt"] {
+		if i == 0 {
+			k.KnownPlainText = ln.NumberToBytes(kpt)
+		}
+		k.KnownPlainTexts = append(k.KnownPlainTexts, ln.NumberToBytes(kpt))
+	}
+}
+
+// Key returns the key described by the integer list. A modulus and exponent are needed, or the CRT
+// members, or the four oracle ciphertexts used to recover a modulus.
+func (l *IntegerList) Key() (*RSA, error) {
+	var (
+		f       = l.Fields
+		n, e    = f["n"], f["e"]
+		p, q    = f["p"], f["q"]
+		crt     bool
+		oracles = make(map[int]*fmp.Fmpz)
+	)
+
+	for i, name := range oracleFields {
+		if o, ok := f[name]; ok {
+			oracles[i] = o
+		}
+	}
+
+	// Do we have enough for CRT solution?
+	if f["dp"] != nil && f["dq"] != nil {
+		switch {
+		case n == nil && p != nil && q != nil:
+			n, crt = new(fmp.Fmpz).Mul(p, q), true
+		case n != nil && p != nil:
+			q, crt = new(fmp.Fmpz).Div(n, p), true
+		case n != nil && q != nil:
+			p, crt = new(fmp.Fmpz).Div(n, q), true
+		}
+	}
+
+	if (n == nil || e == nil) && !crt && len(oracles) < 4 {
+		return nil, fmt.Errorf("failed to decode key, missing a modulus or an exponent: %v", l)
+	}
+
+	if crt {
+		k, err := NewRSA(PrivateFromPublic(&FMPPublicKey{N: n}), nil, nil, "", false)
+		if err != nil {
+			return nil, err
+		}
+
+		k.Key.Primes = []*fmp.Fmpz{p, q}
+		k.Key.Precomputed = &PrecomputedValues{Dp: f["dp"], Dq: f["dq"]}
+		l.addLists(k)
+
+		return k, nil
+	}
+
+	k, err := NewRSA(PrivateFromPublic(&FMPPublicKey{N: n, E: e}), nil, nil, "", false)
+	if err != nil {
+		return nil, err
+	}
+	l.addLists(k)
+
+	if len(oracles) == 4 {
+		k.OracleCiphertexts = oracles
+	}
+
+	// Place the LSB of D into the k.DLSB field.
+	if d0, ok := f["d0"]; ok {
+		k.DLSB = ln.NumberToBytes(d0)
+	}
+
+	// Add the primes if we got any.
+	if p != nil {
+		k.Key.Primes = append(k.Key.Primes, p)
+	}
+
+	if q != nil {
+		k.Key.Primes = append(k.Key.Primes, q)
+	}
+
+	return k, nil
+}
+
+// ImportIntegerList attempts to parse the key (and optionally ciphertext) data as if it was a list
+// of integers N, and e and c. See ParseIntegerList for the formats read.
+func ImportIntegerList(kb []byte) (*RSA, error) {
+	l, err := ParseIntegerList(kb)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.Key()
+}
@@ -0,0 +1,191 @@
+package keys
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseIntegerList(t *testing.T) {
+	tt := []struct {
+		name        string
+		kb          string
+		want        map[string]int64
+		wantLists   map[string][]int64
+		wantFound   []string
+		wantIgnored []string
+		wantErr     bool
+	}{
+		{
+			name:      "plain list",
+			kb:        "n = 3233\ne = 17\nc =  855\n",
+			want:      map[string]int64{"n": 3233, "e": 17, "c": 855},
+			wantFound: []string{"n", "e", "c"},
+		},
+		{
+			name:        "colons, hex and comments",
+			kb:          "# a comment with e = 3\nN:0xca1\ne : 17 # the exponent\nflag = ???\n",
+			want:        map[string]int64{"n": 3233, "e": 17},
+			wantFound:   []string{"n", "e"},
+			wantIgnored: []string{"flag"},
+		},
+		{
+			name:      "number wrapped over lines",
+			kb:        "n = 32\n33\ne = 17\n",
+			want:      map[string]int64{"n": 3233, "e": 17},
+			wantFound: []string{"n", "e"},
+		},
+		{
+			name:        "expressions",
+			kb:          "p = 61\nq = 53\nn = p*q\nphi = (p-1)*(q-1)\ne = 2**4+1\nct = pow(2, 3) + phi\n",
+			want:        map[string]int64{"p": 61, "q": 53, "n": 3233, "e": 17, "c": 3128},
+			wantFound:   []string{"p", "q", "n", "e", "c (ct)"},
+			wantIgnored: []string{"phi"},
+		},
+		{
+			name:      "json",
+			kb:        `{"key": {"n": "3233", "e": 17}, "ciphertext": "0x357"}`,
+			want:      map[string]int64{"n": 3233, "e": 17, "c": 855},
+			wantFound: []string{"n", "e", "c (ciphertext)"},
+		},
+		{
+			name:        "python dict",
+			kb:          "key = {'modulus': 0xca1L, 'publicExponent': 17, 'c': mpz(855), 'hints': [1, 2]}\n",
+			want:        map[string]int64{"n": 3233, "e": 17, "c": 855},
+			wantFound:   []string{"n (modulus)", "e (publicExponent)", "c"},
+			wantIgnored: []string{"hints"},
+		},
+		{
+			name:      "python tuple",
+			kb:        "n, e = (3233,\n        17)\nself.c = 855\n",
+			want:      map[string]int64{"n": 3233, "e": 17, "c": 855},
+			wantFound: []string{"n", "e", "c"},
+		},
+		{
+			name:        "invalid expression",
+			kb:          "n = 3233\ne = x+1\n",
+			want:        map[string]int64{"n": 3233},
+			wantFound:   []string{"n"},
+			wantIgnored: []string{`e (unknown name "x")`},
+		},
+		{
+			name:      "indexed ciphertexts and known plaintexts",
+			kb:        "n = 3233\ne = 17\nc_2 = 2\nc1 = 1\nkpt1 = 7\nkpt2 = 8\n",
+			want:      map[string]int64{"n": 3233, "e": 17, "c": 1, "kpt": 7},
+			wantLists: map[string][]int64{"c": {1, 2}, "kpt": {7, 8}},
+			wantFound: []string{"n", "e", "c (c_2)", "c (c1)", "kpt (kpt1)", "kpt (kpt2)"},
+		},
+		{
+			name:      "ciphertext list and repeated ciphertexts",
+			kb:        "{\"n\": 3233, \"e\": 17, \"c\": 5, \"cts\": [6, 0x7], \"ct\": 5}\n",
+			want:      map[string]int64{"n": 3233, "e": 17, "c": 5},
+			wantLists: map[string][]int64{"c": {5, 6, 7}},
+			wantFound: []string{"n", "e", "c", "c (cts, 2 values)"},
+		},
+		{
+			name:    "no integers",
+			kb:      "just some text\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		l, err := ParseIntegerList([]byte(tc.kb))
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: ParseIntegerList() expected error got nil", tc.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: ParseIntegerList() failed: %v", tc.name, err)
+			continue
+		}
+
+		got := make(map[string]int64)
+		for f, z := range l.Fields {
+			got[f] = z.Int64()
+		}
+
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: ParseIntegerList() got fields %v want %v", tc.name, got, tc.want)
+		}
+
+		gotLists := make(map[string][]int64)
+		for f, zs := range l.Lists {
+			for _, z := range zs {
+				gotLists[f] = append(gotLists[f], z.Int64())
+			}
+		}
+
+		if tc.wantLists != nil && !reflect.DeepEqual(gotLists, tc.wantLists) {
+			t.Errorf("%s: ParseIntegerList() got lists %v want %v", tc.name, gotLists, tc.wantLists)
+		}
+
+		if !reflect.DeepEqual(l.Found, tc.wantFound) || !reflect.DeepEqual(l.Ignored, tc.wantIgnored) {
+			t.Errorf("%s: ParseIntegerList() got %q want found %q ignored %q", tc.name, l, tc.wantFound, tc.wantIgnored)
+		}
+	}
+}
+
+func TestImportIntegerList(t *testing.T) {
+	tt := []struct {
+		name       string
+		kb         string
+		wantN      int64
+		wantPrimes int
+		wantCRT    bool
+		// wantCTs is the number of ciphertexts.
+		wantCTs int
+		wantErr bool
+	}{
+		{
+			name:       "key with a known prime",
+			kb:         "n = 3233\ne = 17\np = 61\n",
+			wantN:      3233,
+			wantPrimes: 1,
+		},
+		{
+			name:       "crt members",
+			kb:         "p = 61\nq = 53\ndp = 53\ndq = 49\nc = 855\n",
+			wantN:      3233,
+			wantPrimes: 2,
+			wantCRT:    true,
+			wantCTs:    1,
+		},
+		{
+			name:    "several ciphertexts",
+			kb:      "n = 3233\ne = 17\nc = [855, 1]\nc3 = 2\n",
+			wantN:   3233,
+			wantCTs: 3,
+		},
+		{
+			name:    "missing exponent",
+			kb:      "n = 3233\nc = 855\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		k, err := ImportIntegerList([]byte(tc.kb))
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: ImportIntegerList() expected error got nil", tc.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: ImportIntegerList() failed: %v", tc.name, err)
+			continue
+		}
+
+		if got := len(k.AllCipherTexts()); got != tc.wantCTs {
+			t.Errorf("%s: ImportIntegerList() got %d ciphertexts want %d", tc.name, got, tc.wantCTs)
+		}
+
+		if k.Key.N.Int64() != tc.wantN || len(k.Key.Primes) != tc.wantPrimes || (k.Key.Precomputed != nil) != tc.wantCRT {
+			t.Errorf("%s: ImportIntegerList() got n=%v primes=%v crt=%t want n=%d %d primes crt=%t", tc.name, k.Key.N, k.Key.Primes, k.Key.Precomputed != nil, tc.wantN, tc.wantPrimes, tc.wantCRT)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package keys
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/sourcekris/goRsaTool/ln"
+	fmp "github.com/sourcekris/goflint"
+)
+
+// jwk is an RSA JSON Web Key as described in RFC 7517 and RFC 7518.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	D   string `json:"d,omitempty"`
+	P   string `json:"p,omitempty"`
+	Q   string `json:"q,omitempty"`
+	DP  string `json:"dp,omitempty"`
+	DQ  string `json:"dq,omitempty"`
+	QI  string `json:"qi,omitempty"`
+}
+
+// jwks is a JSON Web Key Set.
+type jwks struct {
+	Keys []*jwk `json:"keys"`
+}
+
+// isJSON returns true if kb looks like a JSON object.
+func isJSON(kb []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(kb), []byte("{"))
+}
+
+// isJWK returns true if kb is a JSON object with a kty or keys member, a JWK or a JWKS.
+func isJWK(kb []byte) bool {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(kb, &m); err != nil {
+		return false
+	}
+
+	_, kty := m["kty"]
+	_, set := m["keys"]
+	return kty || set
+}
+
+// b64uInt decodes a base64url encoded big-endian integer, padding is tolerated.
+func b64uInt(s string) (*fmp.Fmpz, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+
+	return new(fmp.Fmpz).SetBytes(b), nil
+}
+
+// b64u encodes a non-negative integer as base64url without padding.
+func b64u(z *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(z.Bytes())
+}
+
+// toRSA converts the JWK to an RSA key. Partial private keys are completed where possible, the
+// primes are recovered from d or from either prime, and any CRT members are kept for the CRT
+// attacks.
+func (j *jwk) toRSA() (*RSA, error) {
+	zs := make(map[string]*fmp.Fmpz)
+	for name, v := range map[string]string{"n": j.N, "e": j.E, "d": j.D, "p": j.P, "q": j.Q, "dp": j.DP, "dq": j.DQ, "qi": j.QI} {
+		if v == "" {
+			continue
+		}
+
+		z, err := b64uInt(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed decoding %s of key %q: %v", name, j.Kid, err)
+		}
+		zs[name] = z
+	}
+
+	if zs["n"] == nil || zs["e"] == nil {
+		return nil, fmt.Errorf("key %q is missing n or e", j.Kid)
+	}
+
+	k, err := NewRSA(PrivateFromPublic(&FMPPublicKey{N: zs["n"], E: zs["e"]}), nil, nil, "", false)
+	if err != nil {
+		return nil, err
+	}
+	k.Label = j.Kid
+
+	switch {
+	case zs["d"] != nil && zs["p"] != nil && zs["q"] != nil:
+		k.Key.D = zs["d"]
+		k.Key.Primes = []*fmp.Fmpz{zs["p"], zs["q"]}
+		if err := k.Key.Precompute(); err != nil {
+			return nil, fmt.Errorf("invalid private key: %v", err)
+		}
+	case zs["p"] != nil:
+		k.PackGivenP(zs["p"])
+	case zs["q"] != nil:
+		k.PackGivenP(zs["q"])
+	case zs["d"] != nil:
+		if p := ln.FindPGivenD(zs["d"], zs["e"], zs["n"]); p.Cmp(ln.BigOne) > 0 {
+			k.PackGivenP(new(fmp.Fmpz).Set(p))
+		}
+		if k.Key.D == nil {
+			k.PackGivenD(zs["d"])
+		}
+	}
+
+	// Keep the CRT members of keys without d for the crt attack.
+	if zs["dp"] != nil && zs["dq"] != nil && k.Key.Precomputed == nil {
+		k.Key.Precomputed = &PrecomputedValues{Dp: zs["dp"], Dq: zs["dq"], Qinv: zs["qi"]}
+	}
+
+	return k, nil
+}
+
+// ImportJWK imports a JSON Web Key or every RSA key of a JSON Web Key Set. Keys of other types are
+// ignored.
+func ImportJWK(kb []byte) ([]*RSA, error) {
+	var set jwks
+	if err := json.Unmarshal(kb, &set); err != nil {
+		return nil, fmt.Errorf("ImportJWK: %v", err)
+	}
+
+	if set.Keys == nil {
+		var k jwk
+		if err := json.Unmarshal(kb, &k); err != nil {
+			return nil, fmt.Errorf("ImportJWK: %v", err)
+		}
+		set.Keys = []*jwk{&k}
+	}
+
+	var ks []*RSA
+	for _, j := range set.Keys {
+		if j.Kty != "RSA" {
+			continue
+		}
+
+		k, err := j.toRSA()
+		if err != nil {
+			return nil, fmt.Errorf("ImportJWK: %v", err)
+		}
+
+		ks = append(ks, k)
+	}
+
+	if len(ks) == 0 {
+		return nil, errors.New("ImportJWK: no RSA keys found")
+	}
+
+	return ks, nil
+}
+
+// publicJWK returns the public members of pub with the RFC 7638 thumbprint as its key ID.
+func publicJWK(pub *FMPPublicKey) *jwk {
+	bp := FMPtoBigPublicKey(pub)
+	j := &jwk{Kty: "RSA", N: b64u(bp.N), E: b64u(bp.E)}
+
+	// The thumbprint hashes the required members in lexicographic order without whitespace.
+	h := sha256.Sum256([]byte(fmt.Sprintf(`{"e":%q,"kty":"RSA","n":%q}`, j.E, j.N)))
+	j.Kid = base64.RawURLEncoding.EncodeToString(h[:])
+
+	return j
+}
+
+// encodeJWK returns the key as indented JSON.
+func encodeJWK(j *jwk) ([]byte, error) {
+	b, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(b, '\n'), nil
+}
+
+// encodePublicJWK returns pub as a JSON Web Key.
+func encodePublicJWK(pub *FMPPublicKey) ([]byte, error) {
+	return encodeJWK(publicJWK(pub))
+}
+
+// encodePrivateJWK returns priv as a JSON Web Key including the CRT members.
+func encodePrivateJWK(priv *FMPPrivateKey) ([]byte, error) {
+	if len(priv.Primes) != 2 {
+		return nil, fmt.Errorf("JWK export supports keys of two primes, the key has %d", len(priv.Primes))
+	}
+
+	var (
+		j    = publicJWK(priv.PublicKey)
+		bk   = FMPtoBigPrivateKey(priv)
+		p, q = bk.Primes[0], bk.Primes[1]
+		one  = big.NewInt(1)
+	)
+
+	j.D = b64u(bk.D)
+	j.P = b64u(p)
+	j.Q = b64u(q)
+	j.DP = b64u(new(big.Int).Mod(bk.D, new(big.Int).Sub(p, one)))
+	j.DQ = b64u(new(big.Int).Mod(bk.D, new(big.Int).Sub(q, one)))
+	j.QI = b64u(new(big.Int).ModInverse(q, p))
+
+	return encodeJWK(j)
+}
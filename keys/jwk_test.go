@@ -0,0 +1,148 @@
+package keys
+
+import (
+	"testing"
+)
+
+func TestImportJWK(t *testing.T) {
+	tt := []struct {
+		name       string
+		kb         string
+		wantN      []int64
+		wantLabels []string
+		// wantPrimes is true when the key should be fully factored.
+		wantPrimes bool
+		wantCRT    bool
+		wantErr    bool
+	}{
+		{
+			name:       "public jwk",
+			kb:         `{"kty":"RSA","kid":"one","n":"DKE","e":"EQ"}`,
+			wantN:      []int64{3233},
+			wantLabels: []string{"one"},
+		},
+		{
+			name:       "jwks with several keys",
+			kb:         `{"keys":[{"kty":"RSA","kid":"one","n":"DKE","e":"EQ"},{"kty":"EC","crv":"P-256","x":"AA","y":"AA"},{"kty":"RSA","kid":"two","n":"DDc","e":"Aw"}]}`,
+			wantN:      []int64{3233, 3127},
+			wantLabels: []string{"one", "two"},
+		},
+		{
+			name:       "private jwk",
+			kb:         `{"kty":"RSA","n":"DKE","e":"EQ","d":"CsE","p":"PQ","q":"NQ","dp":"NQ","dq":"MQ"}`,
+			wantN:      []int64{3233},
+			wantLabels: []string{""},
+			wantPrimes: true,
+			wantCRT:    true,
+		},
+		{
+			name:       "primes recovered from d",
+			kb:         `{"kty":"RSA","n":"DKE","e":"EQ","d":"CsE"}`,
+			wantN:      []int64{3233},
+			wantLabels: []string{""},
+			wantPrimes: true,
+			wantCRT:    true,
+		},
+		{
+			name:       "key completed from one prime",
+			kb:         `{"kty":"RSA","n":"DKE","e":"EQ","q":"NQ"}`,
+			wantN:      []int64{3233},
+			wantLabels: []string{""},
+			wantPrimes: true,
+			wantCRT:    true,
+		},
+		{
+			name:       "padded base64",
+			kb:         `{"kty":"RSA","n":"DKE=","e":"EQ=="}`,
+			wantN:      []int64{3233},
+			wantLabels: []string{""},
+		},
+		{
+			name:    "missing e",
+			kb:      `{"kty":"RSA","n":"DKE"}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid base64",
+			kb:      `{"kty":"RSA","n":"D*E","e":"EQ"}`,
+			wantErr: true,
+		},
+		{
+			name:    "no RSA keys",
+			kb:      `{"keys":[{"kty":"oct","k":"AA"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			kb:      `{"kty":"RSA",`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		ks, err := ImportKeys([]byte(tc.kb))
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: ImportKeys() expected error got nil", tc.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: ImportKeys() failed: %v", tc.name, err)
+			continue
+		}
+
+		if len(ks) != len(tc.wantN) {
+			t.Errorf("%s: ImportKeys() got %d keys want %d", tc.name, len(ks), len(tc.wantN))
+			continue
+		}
+
+		for i, k := range ks {
+			if k.Key.N.Int64() != tc.wantN[i] || k.Label != tc.wantLabels[i] {
+				t.Errorf("%s: key %d got n=%v label=%q want n=%d label=%q", tc.name, i, k.Key.N, k.Label, tc.wantN[i], tc.wantLabels[i])
+			}
+
+			if got := k.Key.D != nil && len(k.Key.Primes) == 2; got != tc.wantPrimes {
+				t.Errorf("%s: key %d got d=%v primes=%v want factored %t", tc.name, i, k.Key.D, k.Key.Primes, tc.wantPrimes)
+			}
+
+			if got := k.Key.Precomputed != nil; got != tc.wantCRT {
+				t.Errorf("%s: key %d got CRT values %t want %t", tc.name, i, got, tc.wantCRT)
+			}
+		}
+	}
+}
+
+func TestIsJWK(t *testing.T) {
+	tt := []struct {
+		name string
+		kb   string
+		want bool
+	}{
+		{
+			name: "jwk",
+			kb:   `{"kty": "RSA", "n": "DKE", "e": "EQ"}`,
+			want: true,
+		},
+		{
+			name: "jwks",
+			kb:   `{"keys": []}`,
+			want: true,
+		},
+		{
+			name: "integer list",
+			kb:   `{"n": 3233, "e": 17}`,
+		},
+		{
+			name: "python dict",
+			kb:   `{'n': 3233, 'e': 17}`,
+		},
+	}
+
+	for _, tc := range tt {
+		if got := isJWK([]byte(tc.kb)); got != tc.want {
+			t.Errorf("%s: isJWK() = %t want %t", tc.name, got, tc.want)
+		}
+	}
+}
@@ -1,48 +1,15 @@
 package keys
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/pem"
 	"errors"
 	"fmt"
-	"regexp"
-	"strings"
 
-	"github.com/sourcekris/goRsaTool/ln"
 	fmp "github.com/sourcekris/goflint"
 	"github.com/sourcekris/x509big"
 )
 
-var (
-	// lineRE is a regexp that should match interesting integers on lines.
-	lineRE = regexp.MustCompile(`(?i)^([necpqdk][pq02349]?t?|)\s*[:=]\s*((?:0x)?[0-9a-f]+)`)
-	// numRE matches numbers in base 10 or hex.
-	numRE = regexp.MustCompile(`[0-9a-f]+`)
-	// modRE, expRE, ctRE matches 'n', 'e', 'c' case insensitively.
-	modRE = regexp.MustCompile(`(?i)^n`)
-	expRE = regexp.MustCompile(`(?i)^e`)
-	ctRE  = regexp.MustCompile(`(?i)^c`)
-
-	// kptRE is a known plaintext regexp.
-	kptRE = regexp.MustCompile(`(?i)^kpt`)
-
-	// d0RE is the LSB of d regexp.
-	d0RE = regexp.MustCompile(`(?i)^d0`)
-
-	// CRT components regexps.
-	pRE  = regexp.MustCompile(`(?i)^p`)
-	qRE  = regexp.MustCompile(`(?i)^q`)
-	dpRE = regexp.MustCompile(`(?i)^dp`)
-	dqRE = regexp.MustCompile(`(?i)^dq`)
-
-	// Oracle Ciphertext regexps.
-	e2RE = regexp.MustCompile(`(?i)^e2`)
-	e3RE = regexp.MustCompile(`(?i)^e3`)
-	e4RE = regexp.MustCompile(`(?i)^e4`)
-	e9RE = regexp.MustCompile(`(?i)^e9`)
-)
-
 type pkParser func([]byte) (*x509big.BigPublicKey, error)
 
 // parsePublicRsaKey attempts to try parsing the given public key yielding a FMPPublicKey or
@@ -87,229 +54,124 @@ func PrivateFromPublic(key *FMPPublicKey) *FMPPrivateKey {
 	}
 }
 
-func isOracleCiphertext(s string) bool {
-	return e2RE.MatchString(s) || e3RE.MatchString(s) || e4RE.MatchString(s) || e9RE.MatchString(s)
-}
-
-func whichOracleCiphertext(s string) int {
-	switch {
-	case e2RE.MatchString(s):
-		return 2
-	case e3RE.MatchString(s):
-		return 3
-	case e4RE.MatchString(s):
-		return 4
+// ImportKey imports a key file or a certificate and returns a RSA object or error. Only the first
+// key of a file holding several is returned, use ImportKeys to import them all.
+func ImportKey(kb []byte) (*RSA, error) {
+	ks, err := ImportKeys(kb)
+	if err != nil {
+		return nil, err
 	}
 
-	return 9
+	return ks[0], nil
 }
 
-// getBase returns the base of a string and, if its prefixed with 0x then the remainder of the string after the prefix.
-func getBase(s string) (string, int) {
-	if strings.HasPrefix(s, "0x") {
-		return s[2:], 16
+// ImportKeys imports every key in a PEM, DER, OpenSSH, JWK or OpenPGP key file, e.g. a PEM bundle,
+// an authorized_keys file or a JWKS holding several keys, or in an X.509 certificate chain or
+// certificate request.
+func ImportKeys(kb []byte) ([]*RSA, error) {
+	// OpenPGP armor resembles PEM but carries a checksum that PEM decoding rejects.
+	if bytes.Contains(kb, []byte(pgpArmorPrefix)) || isPGPKeyPacket(kb) {
+		return ImportOpenPGP(kb)
 	}
 
-	return s, 10
-}
-
-// ImportIntegerList attempts to parse the key (and optionally ciphertext) data as if it was a list of integers N, and e and c.
-func ImportIntegerList(kb []byte) (*RSA, error) {
-	var (
-		n, e, c, p, q, dp, dq, d0 string
-		ct, kpt                   []byte
-		crt                       bool
-		os                        map[int]*fmp.Fmpz
-	)
+	if block, _ := pem.Decode(kb); block != nil {
+		return importPEM(kb)
+	}
 
-	os = make(map[int]*fmp.Fmpz)
-
-	s := bufio.NewScanner(bytes.NewReader(kb))
-	for s.Scan() {
-		if lineRE.MatchString(s.Text()) {
-			for _, sm := range lineRE.FindAllStringSubmatch(s.Text(), -1) {
-				if len(sm) < 3 {
-					continue
-				}
-
-				switch {
-				case modRE.MatchString(sm[1]) && numRE.MatchString(sm[2]):
-					n = sm[2]
-				case expRE.MatchString(sm[1]) && numRE.MatchString(sm[2]) && !isOracleCiphertext(sm[1]):
-					e = sm[2]
-				case ctRE.MatchString(sm[1]) && numRE.MatchString(sm[2]):
-					c = sm[2]
-				case pRE.MatchString(sm[1]) && numRE.MatchString(sm[2]):
-					p = sm[2]
-				case qRE.MatchString(sm[1]) && numRE.MatchString(sm[2]):
-					q = sm[2]
-				case dpRE.MatchString(sm[1]) && numRE.MatchString(sm[2]):
-					dp = sm[2]
-				case dqRE.MatchString(sm[1]) && numRE.MatchString(sm[2]):
-					dq = sm[2]
-				case d0RE.MatchString(sm[1]) && numRE.MatchString(sm[2]):
-					d0 = sm[2]
-				case isOracleCiphertext(sm[1]) && numRE.MatchString(sm[2]):
-					if o, ok := new(fmp.Fmpz).SetString(getBase(sm[2])); ok {
-						os[whichOracleCiphertext(sm[1])] = new(fmp.Fmpz).Set(o)
-					}
-				case kptRE.MatchString(sm[1]) && numRE.MatchString(sm[2]):
-					if kn, ok := new(fmp.Fmpz).SetString(getBase(sm[2])); ok {
-						kpt = ln.NumberToBytes(kn)
-					}
-				}
-			}
-		}
+	if isJWK(kb) {
+		return ImportJWK(kb)
 	}
 
-	// Do we have enough for CRT solution?
-	if dp != "" && dq != "" {
-		switch {
-		case n == "" && (p == "" || q == ""):
-		case n == "" && p != "" && q != "":
-			fP, ok1 := new(fmp.Fmpz).SetString(getBase(p))
-			fQ, ok2 := new(fmp.Fmpz).SetString(getBase(q))
-			if !ok1 || !ok2 {
-				crt = false
-				break
-			}
-			n = new(fmp.Fmpz).Mul(fP, fQ).String()
-			crt = true
-		case n != "" && p != "":
-			fN, ok1 := new(fmp.Fmpz).SetString(getBase(n))
-			fP, ok2 := new(fmp.Fmpz).SetString(getBase(p))
-			if !ok1 || !ok2 {
-				crt = false
-				break
-			}
-			q = new(fmp.Fmpz).Div(fN, fP).String()
-			crt = true
-		case n != "" && q != "":
-			fN, ok1 := new(fmp.Fmpz).SetString(getBase(n))
-			fQ, ok2 := new(fmp.Fmpz).SetString(getBase(q))
-			if !ok1 || !ok2 {
-				crt = false
-				break
-			}
-			p = new(fmp.Fmpz).Div(fN, fQ).String()
-			crt = true
-		}
+	// Other JSON objects, like {"n": ..., "e": ...}, are integer lists for ParseIntegerList.
+	if isJSON(kb) {
+		return nil, errors.New("JSON without a kty or keys member is not a JWK or JWKS")
 	}
 
-	if (n == "" || e == "") && !crt && len(os) < 4 {
-		return nil, fmt.Errorf("failed to decode key, missing a modulus or an exponent")
+	if bytes.Contains(kb, []byte(sshRSA+" ")) {
+		return ImportOpenSSH(kb)
 	}
 
-	fN, ok := new(fmp.Fmpz).SetString(getBase(n))
-	if !ok && len(os) < 4 {
-		return nil, fmt.Errorf("failed decoding modulus from keyfile: %v", n)
+	ks, err := importDER(kb)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the key as PEM, DER, OpenSSH, JWK or OpenPGP: %v", err)
 	}
 
-	if crt {
-		k, err := NewRSA(PrivateFromPublic(&FMPPublicKey{N: fN}), ct, nil, "", false)
-		if err != nil {
-			fmt.Printf("trying crt n: %v\n", n)
-			return nil, err
-		}
-		fQ, ok1 := new(fmp.Fmpz).SetString(getBase(q))
-		fP, ok2 := new(fmp.Fmpz).SetString(getBase(p))
-		fdP, ok3 := new(fmp.Fmpz).SetString(getBase(dp))
-		fdQ, ok4 := new(fmp.Fmpz).SetString(getBase(dq))
-		if !ok1 || !ok2 || !ok3 || !ok4 {
-			return nil, errors.New("failed to decode crt components")
-		}
-		k.Key.Primes = []*fmp.Fmpz{fP, fQ}
-		k.Key.Precomputed = &PrecomputedValues{Dp: fdP, Dq: fdQ}
+	return ks, nil
+}
 
-		if c != "" {
-			fC, ok := new(fmp.Fmpz).SetString(getBase(c))
-			if !ok {
-				return nil, errors.New("failed converting ciphertext integer to bytes")
-			}
+// importPEM imports the keys of every PEM block in kb. Blocks that don't hold an RSA key, like EC
+// keys or parameters in a bundle, are skipped.
+func importPEM(kb []byte) ([]*RSA, error) {
+	var (
+		ks   []*RSA
+		errs []error
+	)
 
-			k.CipherText = ln.NumberToBytes(fC)
+	for block, rest := pem.Decode(kb); block != nil; block, rest = pem.Decode(rest) {
+		bks, err := importPEMBlock(block)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", block.Type, err))
+			continue
 		}
 
-		return k, nil
+		ks = append(ks, bks...)
 	}
 
-	fE, ok := new(fmp.Fmpz).SetString(getBase(e))
-	if !ok && len(os) < 4 {
-		return nil, errors.New("failed decoding exponent from keyfile")
+	if len(ks) == 0 {
+		return nil, fmt.Errorf("ImportKey: failed to parse any PEM block as an RSA key: %v", errs)
 	}
 
-	if c != "" {
-		fC, ok := new(fmp.Fmpz).SetString(getBase(c))
-		if !ok {
-			return nil, errors.New("failed converting ciphertext integer to bytes")
-		}
+	return ks, nil
+}
 
-		ct = ln.NumberToBytes(fC)
-	}
+// importPEMBlock imports the keys of an OpenSSH private key, certificate or DER key PEM block.
+func importPEMBlock(block *pem.Block) ([]*RSA, error) {
+	switch {
+	case block.Type == sshPrivatePEM:
+		priv, err := parseOpenSSHPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
 
-	k, err := NewRSA(PrivateFromPublic(&FMPPublicKey{N: fN, E: fE}), ct, nil, "", false)
-	if err != nil {
-		return nil, err
+		return newRSAs(priv)
+	case certPEMTypes[block.Type]:
+		return importCertificates(block.Bytes)
 	}
 
-	if kpt != nil {
-		k.KnownPlainText = kpt
-	}
+	return importDER(block.Bytes)
+}
 
-	if len(os) == 4 {
-		k.OracleCiphertexts = os
+// importDER imports a DER encoded PKCS#1 or PKCS#8 private key, a PKCS#1 or PKIX public key, or
+// the keys of X.509 certificates and requests.
+func importDER(der []byte) ([]*RSA, error) {
+	// Try as a private key first.
+	if priv, err := parseBigPrivateRsaKey(der); err == nil {
+		return newRSAs(priv)
 	}
 
-	// Place the LSB of D into the k.DLSB field.
-	if d0 != "" {
-		fd0, ok := new(fmp.Fmpz).SetString(getBase(d0))
-		if !ok {
-			return nil, errors.New("failed converting d0 integer to bytes")
-		}
-
-		k.DLSB = ln.NumberToBytes(fd0)
+	if priv, err := parsePKCS8PrivateKey(der); err == nil {
+		return newRSAs(priv)
 	}
 
-	// Add the primes if we got any.
-	if p != "" {
-		fP, ok := new(fmp.Fmpz).SetString(getBase(p))
-		if !ok {
-			return nil, errors.New("failed decoding prime p from keyfile")
-		}
-		k.Key.Primes = append(k.Key.Primes, fP)
+	// Extract a FMPPublicKey from the DER decoded data and pack a private key struct.
+	if key, err := parsePublicRsaKey(der); err == nil {
+		return newRSAs(PrivateFromPublic(key))
 	}
 
-	if q != "" {
-		fQ, ok := new(fmp.Fmpz).SetString(getBase(q))
-		if !ok {
-			return nil, errors.New("failed decoding prime q from keyfile")
-		}
-		k.Key.Primes = append(k.Key.Primes, fQ)
+	ks, err := importCertificates(der)
+	if err != nil {
+		return nil, errors.New("not a PKCS#1 or PKCS#8 private key, PKCS#1 or PKIX public key or certificate")
 	}
 
-	return k, nil
+	return ks, nil
 }
 
-// ImportKey imports a PEM key file and returns a RSA object or error.
-func ImportKey(kb []byte) (*RSA, error) {
-	// Decode the PEM data to extract the DER format key.
-	block, _ := pem.Decode(kb)
-	if block == nil {
-		return nil, errors.New("failed to decode PEM key")
-	}
-
-	// Try as a private key first.
-	priv, err := parseBigPrivateRsaKey(block.Bytes)
-	if err == nil {
-		// If there was an error, try to parse it an alternative way below.
-		return NewRSA(priv, nil, nil, "", false)
-	}
-
-	// Extract a FMPPublicKey from the DER decoded data and pack a private key struct.
-	key, err := parsePublicRsaKey(block.Bytes)
+// newRSAs returns key as the only RSA of a file.
+func newRSAs(key *FMPPrivateKey) ([]*RSA, error) {
+	k, err := NewRSA(key, nil, nil, "", false)
 	if err != nil {
-		return nil, fmt.Errorf("ImportKey: failed to parse the key as either a public or private key: %v", err)
+		return nil, err
 	}
 
-	return NewRSA(PrivateFromPublic(key), nil, nil, "", false)
+	return []*RSA{k}, nil
 }
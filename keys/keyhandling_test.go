@@ -0,0 +1,110 @@
+package keys
+
+import (
+	"testing"
+
+	fmp "github.com/sourcekris/goflint"
+)
+
+func TestImportKeysDERAndPEM(t *testing.T) {
+	priv := PrivateFromPublic(&FMPPublicKey{N: fmp.NewFmpz(3233), E: fmp.NewFmpz(17)})
+	priv.D = fmp.NewFmpz(2753)
+	priv.Primes = []*fmp.Fmpz{fmp.NewFmpz(61), fmp.NewFmpz(53)}
+	other := &FMPPublicKey{N: fmp.NewFmpz(3127), E: fmp.NewFmpz(3)}
+
+	pkcs1, _ := EncodePrivateKey(priv, FormatDER)
+	pkcs8, _ := marshalPKCS8PrivateKey(priv)
+	pkix, _ := EncodePublicKey(other, FormatDER)
+	pkcs1PEM, _ := EncodePrivateKey(priv, FormatPKCS1)
+	pkcs8PEM, _ := EncodePrivateKey(priv, FormatPKCS8)
+	pkixPEM, _ := EncodePublicKey(other, FormatPKCS8)
+
+	tt := []struct {
+		name string
+		kb   string
+		// wantN is the modulus of each key, wantPrivate whether it has d.
+		wantN       []int64
+		wantPrivate []bool
+		wantErr     bool
+	}{
+		{
+			name:        "pkcs1 private der",
+			kb:          string(pkcs1),
+			wantN:       []int64{3233},
+			wantPrivate: []bool{true},
+		},
+		{
+			name:        "pkcs8 private der",
+			kb:          string(pkcs8),
+			wantN:       []int64{3233},
+			wantPrivate: []bool{true},
+		},
+		{
+			name:        "pkix public der",
+			kb:          string(pkix),
+			wantN:       []int64{3127},
+			wantPrivate: []bool{false},
+		},
+		{
+			name:        "pkcs8 pem",
+			kb:          string(pkcs8PEM),
+			wantN:       []int64{3233},
+			wantPrivate: []bool{true},
+		},
+		{
+			name:        "bundle of pem keys, certificates and other blocks",
+			kb:          string(pkcs1PEM) + string(pkixPEM) + ecCert + "\n" + fermatCert + "\n" + string(pkcs8PEM),
+			wantN:       []int64{3233, 3127, 0, 3233},
+			wantPrivate: []bool{true, false, false, true},
+		},
+		{
+			name:    "truncated der",
+			kb:      string(pkcs1[:len(pkcs1)-2]),
+			wantErr: true,
+		},
+		{
+			name:    "pem without RSA keys",
+			kb:      ecCert,
+			wantErr: true,
+		},
+		{
+			name:    "json integer list",
+			kb:      `{"n": 3233, "e": 17}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		ks, err := ImportKeys([]byte(tc.kb))
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: ImportKeys() expected error got nil", tc.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: ImportKeys() failed: %v", tc.name, err)
+			continue
+		}
+
+		if len(ks) != len(tc.wantN) {
+			t.Errorf("%s: ImportKeys() got %d keys want %d", tc.name, len(ks), len(tc.wantN))
+			continue
+		}
+
+		for i, k := range ks {
+			if tc.wantN[i] == 0 {
+				// The certificate key is too large to compare as an int64.
+				if k.Key.N.String() != fermatN {
+					t.Errorf("%s: key %d got n=%v want the certificate modulus", tc.name, i, k.Key.N)
+				}
+				continue
+			}
+
+			if k.Key.N.Int64() != tc.wantN[i] || (k.Key.D != nil) != tc.wantPrivate[i] {
+				t.Errorf("%s: key %d got n=%v d=%v want n=%d private %t", tc.name, i, k.Key.N, k.Key.D, tc.wantN[i], tc.wantPrivate[i])
+			}
+		}
+	}
+}
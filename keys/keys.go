@@ -2,11 +2,14 @@ package keys
 
 import (
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
+	"regexp"
 
 	"github.com/sourcekris/goRsaTool/ln"
+	"github.com/sourcekris/goRsaTool/plaintext"
 	"github.com/sourcekris/x509big"
 
 	fmp "github.com/sourcekris/goflint"
@@ -15,19 +18,36 @@ import (
 // RSA wraps FMPPrivateKey and adds a field for cipher and plaintexts as well as other fields
 // needed for various attacks.
 type RSA struct {
-	Key               FMPPrivateKey
-	CipherText        []byte
-	PlainText         []byte
-	KnownPlainText    []byte
+	Key        FMPPrivateKey
+	CipherText []byte
+	// CipherTexts holds every ciphertext encrypted under the key. CipherText is the first of them
+	// and the one attacks needing a single ciphertext use. Use AddCipherText to add to both.
+	CipherTexts    [][]byte
+	PlainText      []byte
+	KnownPlainText []byte
+	// KnownPlainTexts are the known parts of the plaintexts of CipherTexts, by index.
+	KnownPlainTexts [][]byte
+	// FlagFormat matches the expected plaintext, e.g. flag\{.*\}, it helps rank plaintext candidates.
+	FlagFormat *regexp.Regexp
+	// Candidates are the plaintext candidates attacks found, the most likely first.
+	Candidates        []plaintext.Candidate
 	DLSB              []byte
 	OracleCiphertexts map[int]*fmp.Fmpz
 	Hints             []*fmp.Fmpz
-	BruteMax          int64
 	KeyFilename       string
 	PastPrimesFile    string
 	NumPrimes         int
 	Verbose           bool
 	Log               *log.Logger
+	// Results holds the result of every attack that recovered something about this key.
+	Results []*Result
+	// Factorization tracks the factors of N found so far, it is nil until a factor is found.
+	Factorization *Factorization
+	// Label tells apart keys imported from the same file, e.g. the comment of an authorized_keys
+	// entry or the host names of a known_hosts entry.
+	Label string
+	// Subject and Issuer are the distinguished names of the certificate the key was taken from.
+	Subject, Issuer string
 }
 
 // NewRSA constructs an RSA object or returns an error.
@@ -37,49 +57,131 @@ func NewRSA(key *FMPPrivateKey, c []byte, m []byte, pf string, v bool) (*RSA, er
 		pastPrimesFile = pf
 	}
 
-	var cipherText []byte
-	if c != nil {
-		cipherText = c
-	}
-
 	// pack the RSA struct
-	return &RSA{
+	k := &RSA{
 		Key:            *key,
 		PastPrimesFile: pastPrimesFile,
-		CipherText:     cipherText,
 		Verbose:        v,
-	}, nil
+	}
+
+	if c != nil {
+		k.AddCipherText(c)
+	}
+
+	return k, nil
+}
+
+// AddCipherText adds c to the ciphertexts of the key, it becomes CipherText if it is the first.
+func (t *RSA) AddCipherText(c []byte) {
+	if len(t.CipherText) == 0 {
+		t.CipherText = c
+	}
+
+	t.CipherTexts = append(t.CipherTexts, c)
+}
+
+// AllCipherTexts returns every ciphertext of the key, including a CipherText set directly.
+func (t *RSA) AllCipherTexts() [][]byte {
+	if len(t.CipherTexts) == 0 && len(t.CipherText) > 0 {
+		return [][]byte{t.CipherText}
+	}
+
+	return t.CipherTexts
+}
+
+// Decrypt returns the plaintext of c or nil if d is not known yet.
+func (t *RSA) Decrypt(c []byte) []byte {
+	if t.Key.D == nil || t.Key.PublicKey.N == nil {
+		return nil
+	}
+
+	return ln.NumberToBytes(new(fmp.Fmpz).Exp(ln.BytesToNumber(c), t.Key.D, t.Key.PublicKey.N))
+}
+
+// AddCandidates ranks the plaintext candidates pts found by the attack called source among the
+// Candidates of the key and returns the best of pts.
+func (t *RSA) AddCandidates(source string, pts [][]byte) []byte {
+	t.Candidates = plaintext.Add(t.Candidates, source, pts, t.FlagFormat)
+	if rs := plaintext.Rank(pts, t.FlagFormat); len(rs) > 0 {
+		return rs[0].PlainText
+	}
+
+	return nil
+}
+
+// Copy returns a copy of t that can be attacked independently of t. The key integers are deep
+// copied since some attacks modify them in place.
+func (t *RSA) Copy() *RSA {
+	c := *t
+	c.Key = t.Key.copy()
+
+	if t.OracleCiphertexts != nil {
+		c.OracleCiphertexts = make(map[int]*fmp.Fmpz, len(t.OracleCiphertexts))
+		for i, o := range t.OracleCiphertexts {
+			c.OracleCiphertexts[i] = copyFmpz(o)
+		}
+	}
+
+	c.Hints = copyFmpzs(t.Hints)
+	c.Results = append([]*Result(nil), t.Results...)
+	c.Candidates = append([]plaintext.Candidate(nil), t.Candidates...)
+	c.Factorization = t.Factorization.copy()
+
+	return &c
+}
+
+// Refine records d as a divisor of N in the key's Factorization, splitting any composite cofactors
+// it shares a factor with. It returns true if the factorization changed.
+func (t *RSA) Refine(d *fmp.Fmpz) bool {
+	if t.Factorization == nil {
+		t.Factorization = NewFactorization(t.Key.N)
+	}
+
+	return t.Factorization.Refine(d)
 }
 
-// PackGivenP takes one prime p and packs the Key member of the RSA struct with the private
-// key values, p, q & d as well as the Plaintext if a Ciphertext was given.
+// PackGivenP takes a factor p of N and refines the key's Factorization with it. Once N is fully
+// factored the Key member of the RSA struct is packed with the private key values, the primes & d
+// as well as the Plaintext if a Ciphertext was given.
 func (t *RSA) PackGivenP(p *fmp.Fmpz) {
-	q := new(fmp.Fmpz).Div(t.Key.N, p)
-	t.Key.Primes = []*fmp.Fmpz{p, q}
-	t.Key.D = ln.SolveforD(p, q, t.Key.PublicKey.E)
+	t.Refine(p)
 
-	if t.Verbose {
-		for i, prime := range t.Key.Primes {
-			fmt.Printf("Prime %d: %v\n", i, prime)
+	f := t.Factorization
+	if !f.Complete() {
+		if t.Verbose {
+			log.Printf("found %d primes, %d composite cofactors remain", len(f.Primes), len(f.Composites))
 		}
+		return
 	}
 
-	// Pack the Plaintext if a Ciphertext was provided.
-	if t.CipherText != nil && t.PlainText == nil {
-		t.PlainText = ln.NumberToBytes(new(fmp.Fmpz).Exp(ln.BytesToNumber(t.CipherText), t.Key.D, t.Key.PublicKey.N))
+	if t.Verbose {
+		for i, prime := range f.Primes {
+			log.Printf("Prime %d: %v", i, prime)
+		}
 	}
+
+	// The product of a complete factorization is always N.
+	_ = t.PackMultiPrime(f.Primes)
 }
 
 // PackMultiPrime takes many primes and packs the RSA struct with the private
-// key values, []*Primes & d.
+// key values, []*Primes & d. Repeated primes are treated as prime powers.
 func (t *RSA) PackMultiPrime(primes []*fmp.Fmpz) error {
 	var (
-		n  = fmp.NewFmpz(1)
-		cp = fmp.NewFmpz(1)
+		n    = fmp.NewFmpz(1)
+		cp   = fmp.NewFmpz(1)
+		seen []*fmp.Fmpz
 	)
 
 	for _, p := range primes {
 		n.MulZ(p)
+
+		// phi(p^k) = p^(k-1) * (p-1).
+		if containsFmpz(seen, p) {
+			cp.MulZ(p)
+			continue
+		}
+		seen = append(seen, p)
 		cp.MulZ(new(fmp.Fmpz).Sub(p, ln.BigOne))
 	}
 
@@ -87,8 +189,23 @@ func (t *RSA) PackMultiPrime(primes []*fmp.Fmpz) error {
 		return fmt.Errorf("product of primes does not equal N")
 	}
 
+	// The primes are kept even if e has no inverse and so there is no private key.
 	t.Key.Primes = primes
-	t.Key.D = new(fmp.Fmpz).ModInverse(t.Key.PublicKey.E, cp)
+
+	e := t.Key.PublicKey.E
+	d := new(fmp.Fmpz).ModInverse(e, cp)
+	if d == nil || !new(fmp.Fmpz).Mod(new(fmp.Fmpz).Mul(e, d), cp).Equals(new(fmp.Fmpz).Mod(ln.BigOne, cp)) {
+		return errors.New("e is not invertible mod phi(N), there is no private key")
+	}
+
+	key := t.Key
+	key.D = d
+
+	// Keys with repeated primes decrypt but have no PKCS#1 CRT values.
+	if err := key.Precompute(); err != nil && len(seen) == len(primes) {
+		return err
+	}
+	t.Key = key
 
 	// Pack the Plaintext if a Ciphertext was provided.
 	if t.CipherText != nil && t.PlainText == nil {
@@ -98,20 +215,43 @@ func (t *RSA) PackMultiPrime(primes []*fmp.Fmpz) error {
 	return nil
 }
 
-// PackGivenD takes d and packs it into the key, solving for any ciphertext on the way.
+// PackGivenD takes d and packs it into the key, solving for any ciphertext on the way. The CRT
+// values are computed if the key's primes are already known.
 func (t *RSA) PackGivenD(d *fmp.Fmpz) {
 	t.Key.D = new(fmp.Fmpz).Set(d)
 
+	if len(t.Key.Primes) > 1 {
+		if err := t.Key.Precompute(); err != nil && t.Verbose {
+			log.Printf("unable to compute the CRT values of the key: %v", err)
+		}
+	}
+
 	// Pack the Plaintext if a Ciphertext was provided.
 	if t.CipherText != nil && t.PlainText == nil {
 		t.PlainText = ln.NumberToBytes(new(fmp.Fmpz).Exp(ln.BytesToNumber(t.CipherText), t.Key.D, t.Key.PublicKey.N))
 	}
 }
 
+// Name returns the key's filename along with its label if it has one.
+func (t *RSA) Name() string {
+	if t.Label != "" {
+		return fmt.Sprintf("%s (%s)", t.KeyFilename, t.Label)
+	}
+
+	return t.KeyFilename
+}
+
 // String returns the key components in a string format.
 func (t *RSA) String() string {
-	var res string
-	res = fmt.Sprintf("%s:\nn = %s\n", t.KeyFilename, t.Key.PublicKey.N)
+	res := fmt.Sprintf("%s:\n", t.Name())
+	if t.Subject != "" {
+		res = fmt.Sprintf("%ssubject = %s\n", res, t.Subject)
+	}
+	if t.Issuer != "" {
+		res = fmt.Sprintf("%sissuer = %s\n", res, t.Issuer)
+	}
+
+	res = fmt.Sprintf("%sn = %s\n", res, t.Key.PublicKey.N)
 	res = fmt.Sprintf("%se = %s\n", res, t.Key.PublicKey.E)
 
 	if t.Key.D != nil {
@@ -126,8 +266,12 @@ func (t *RSA) String() string {
 		}
 	}
 
-	if len(t.CipherText) > 0 {
-		res = fmt.Sprintf("%sc = %s\n", res, ln.BytesToNumber(t.CipherText))
+	if cts := t.AllCipherTexts(); len(cts) == 1 {
+		res = fmt.Sprintf("%sc = %s\n", res, ln.BytesToNumber(cts[0]))
+	} else {
+		for i, c := range cts {
+			res = fmt.Sprintf("%sc[%d] = %s\n", res, i, ln.BytesToNumber(c))
+		}
 	}
 
 	return res
@@ -174,6 +318,128 @@ type FMPPrivateKey struct {
 	Precomputed *PrecomputedValues
 }
 
+// Precompute fills in the key's PKCS#1 CRT values, Dp, Dq and Qinv for the first two primes and the
+// CRTValues of the third and later primes. It returns an error, leaving Precomputed unchanged, if
+// the key lacks d or its primes are not distinct, do not multiply to N or do not agree with e and d.
+func (k *FMPPrivateKey) Precompute() error {
+	if k.D == nil || k.PublicKey == nil || k.PublicKey.E == nil || k.N == nil {
+		return errors.New("precompute: key needs n, e and d")
+	}
+
+	if len(k.Primes) < 2 {
+		return fmt.Errorf("precompute: key needs at least two primes, it has %d", len(k.Primes))
+	}
+
+	var (
+		n   = fmp.NewFmpz(1)
+		ed  = new(fmp.Fmpz).Mul(k.PublicKey.E, k.D)
+		exp = make([]*fmp.Fmpz, len(k.Primes))
+	)
+
+	for i, p := range k.Primes {
+		if p.Cmp(ln.BigOne) <= 0 || containsFmpz(k.Primes[:i], p) {
+			return fmt.Errorf("precompute: primes must be distinct and greater than 1, prime %d is %v", i, p)
+		}
+		n.MulZ(p)
+
+		// d is an inverse of e mod p-1 for every prime of a valid key.
+		pm1 := new(fmp.Fmpz).Sub(p, ln.BigOne)
+		if !new(fmp.Fmpz).Mod(ed, pm1).Equals(new(fmp.Fmpz).Mod(ln.BigOne, pm1)) {
+			return fmt.Errorf("precompute: d is not the inverse of e mod p-1 for prime %d", i)
+		}
+		exp[i] = new(fmp.Fmpz).Mod(k.D, pm1)
+	}
+
+	if !n.Equals(k.N) {
+		return errors.New("precompute: product of primes does not equal N")
+	}
+
+	p, q := k.Primes[0], k.Primes[1]
+	pc := &PrecomputedValues{
+		Dp:   exp[0],
+		Dq:   exp[1],
+		Qinv: new(fmp.Fmpz).ModInverse(q, p),
+	}
+
+	r := new(fmp.Fmpz).Mul(p, q)
+	for i, prime := range k.Primes[2:] {
+		pc.CRTValues = append(pc.CRTValues, CRTValue{
+			Exp:   exp[i+2],
+			Coeff: new(fmp.Fmpz).ModInverse(r, prime),
+			R:     new(fmp.Fmpz).Set(r),
+		})
+		r = new(fmp.Fmpz).Mul(r, prime)
+	}
+
+	k.Precomputed = pc
+	return nil
+}
+
+// precomputed returns true if the key holds every CRT value of its primes.
+func (k *FMPPrivateKey) precomputed() bool {
+	pc := k.Precomputed
+	return pc != nil && pc.Dp != nil && pc.Dq != nil && pc.Qinv != nil && len(pc.CRTValues) == len(k.Primes)-2
+}
+
+// copy returns a deep copy of the key.
+func (k FMPPrivateKey) copy() FMPPrivateKey {
+	c := FMPPrivateKey{
+		D:      copyFmpz(k.D),
+		Primes: copyFmpzs(k.Primes),
+		N:      copyFmpz(k.N),
+	}
+
+	if k.PublicKey != nil {
+		c.PublicKey = &FMPPublicKey{N: copyFmpz(k.PublicKey.N), E: copyFmpz(k.PublicKey.E)}
+
+		// Keep the modulus shared between the public and private halves as it usually is.
+		if k.PublicKey.N == k.N {
+			c.PublicKey.N = c.N
+		}
+	}
+
+	if k.Precomputed != nil {
+		pc := *k.Precomputed
+		c.Precomputed = &pc
+	}
+
+	return c
+}
+
+// copyFmpz returns a copy of z or nil if z is nil.
+func copyFmpz(z *fmp.Fmpz) *fmp.Fmpz {
+	if z == nil {
+		return nil
+	}
+
+	return new(fmp.Fmpz).Set(z)
+}
+
+// containsFmpz returns true if z is in zs.
+func containsFmpz(zs []*fmp.Fmpz, z *fmp.Fmpz) bool {
+	for _, x := range zs {
+		if x.Equals(z) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// copyFmpzs returns a copy of each integer in zs.
+func copyFmpzs(zs []*fmp.Fmpz) []*fmp.Fmpz {
+	if zs == nil {
+		return nil
+	}
+
+	c := make([]*fmp.Fmpz, len(zs))
+	for i, z := range zs {
+		c[i] = copyFmpz(z)
+	}
+
+	return c
+}
+
 // BigtoFMPPrivateKey takes a x509big.BigPrivateKey and returns a FMPPrivateKey that uses fmp.Fmpz types
 func BigtoFMPPrivateKey(key *x509big.BigPrivateKey) FMPPrivateKey {
 	fmpPubKey := &FMPPublicKey{
@@ -186,12 +452,15 @@ func BigtoFMPPrivateKey(key *x509big.BigPrivateKey) FMPPrivateKey {
 		fmpPrivateKey = &FMPPrivateKey{
 			PublicKey: fmpPubKey,
 			D:         new(fmp.Fmpz).SetBytes(key.D.Bytes()),
+			N:         fmpPubKey.N,
 		}
 
 		for _, p := range key.Primes {
 			fmpPrivateKey.Primes = append(fmpPrivateKey.Primes, new(fmp.Fmpz).SetBytes(p.Bytes()))
 		}
 
+		// Keys that don't validate are still imported, they just lack the CRT values.
+		_ = fmpPrivateKey.Precompute()
 	} else {
 		fmpPrivateKey = PrivateFromPublic(fmpPubKey)
 	}
@@ -218,6 +487,23 @@ func FMPtoBigPrivateKey(key *FMPPrivateKey) *x509big.BigPrivateKey {
 			privateKey.Primes = append(privateKey.Primes, new(big.Int).SetBytes(p.Bytes()))
 		}
 
+		// Encode our own CRT values rather than those x509big computes for missing ones.
+		if key.precomputed() {
+			pc := key.Precomputed
+			privateKey.Precomputed = x509big.PrecomputedValues{
+				Dp:   new(big.Int).SetBytes(pc.Dp.Bytes()),
+				Dq:   new(big.Int).SetBytes(pc.Dq.Bytes()),
+				Qinv: new(big.Int).SetBytes(pc.Qinv.Bytes()),
+			}
+
+			for _, v := range pc.CRTValues {
+				privateKey.Precomputed.CRTValues = append(privateKey.Precomputed.CRTValues, x509big.CRTValue{
+					Exp:   new(big.Int).SetBytes(v.Exp.Bytes()),
+					Coeff: new(big.Int).SetBytes(v.Coeff.Bytes()),
+					R:     new(big.Int).SetBytes(v.R.Bytes()),
+				})
+			}
+		}
 	} else {
 		privateKey = &x509big.BigPrivateKey{
 			PublicKey: *pubKey,
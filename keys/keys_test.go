@@ -0,0 +1,180 @@
+package keys
+
+import (
+	"testing"
+
+	fmp "github.com/sourcekris/goflint"
+	"github.com/sourcekris/x509big"
+)
+
+func TestPrecompute(t *testing.T) {
+	tt := []struct {
+		name    string
+		n, e, d int64
+		primes  []int64
+		wantErr bool
+	}{
+		{
+			name:   "two primes",
+			n:      3233,
+			e:      17,
+			d:      2753,
+			primes: []int64{61, 53},
+		},
+		{
+			name:   "three primes",
+			n:      229543,
+			e:      17,
+			d:      205553,
+			primes: []int64{61, 53, 71},
+		},
+		{
+			name:    "repeated primes",
+			n:       3721,
+			e:       17,
+			d:       2153,
+			primes:  []int64{61, 61},
+			wantErr: true,
+		},
+		{
+			name:    "primes not multiplying to n",
+			n:       3233,
+			e:       17,
+			d:       2753,
+			primes:  []int64{61, 59},
+			wantErr: true,
+		},
+		{
+			name:    "wrong d",
+			n:       3233,
+			e:       17,
+			d:       2754,
+			primes:  []int64{61, 53},
+			wantErr: true,
+		},
+	}
+
+	one := fmp.NewFmpz(1)
+	for _, tc := range tt {
+		k := PrivateFromPublic(&FMPPublicKey{N: fmp.NewFmpz(tc.n), E: fmp.NewFmpz(tc.e)})
+		k.D = fmp.NewFmpz(tc.d)
+		for _, p := range tc.primes {
+			k.Primes = append(k.Primes, fmp.NewFmpz(p))
+		}
+
+		err := k.Precompute()
+		if tc.wantErr {
+			if err == nil || k.Precomputed != nil {
+				t.Errorf("%s: Precompute() expected error got %v and %+v", tc.name, err, k.Precomputed)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: Precompute() failed: %v", tc.name, err)
+			continue
+		}
+
+		pc, p, q := k.Precomputed, k.Primes[0], k.Primes[1]
+		if pc.Dp.Int64() != tc.d%(tc.primes[0]-1) || pc.Dq.Int64() != tc.d%(tc.primes[1]-1) ||
+			!new(fmp.Fmpz).Mod(new(fmp.Fmpz).Mul(pc.Qinv, q), p).Equals(one) {
+			t.Errorf("%s: Precompute() got dp=%v dq=%v qinv=%v", tc.name, pc.Dp, pc.Dq, pc.Qinv)
+		}
+
+		if len(pc.CRTValues) != len(tc.primes)-2 {
+			t.Errorf("%s: Precompute() got %d CRT values want %d", tc.name, len(pc.CRTValues), len(tc.primes)-2)
+			continue
+		}
+
+		r := tc.primes[0] * tc.primes[1]
+		for i, v := range pc.CRTValues {
+			prime := k.Primes[i+2]
+			if v.R.Int64() != r || v.Exp.Int64() != tc.d%(tc.primes[i+2]-1) ||
+				!new(fmp.Fmpz).Mod(new(fmp.Fmpz).Mul(v.Coeff, v.R), prime).Equals(one) {
+				t.Errorf("%s: Precompute() got CRT value %d exp=%v coeff=%v r=%v", tc.name, i, v.Exp, v.Coeff, v.R)
+			}
+			r *= tc.primes[i+2]
+		}
+
+		// The exported key carries the same CRT values.
+		der, err := EncodePrivateKey(k, FormatDER)
+		if err != nil {
+			t.Errorf("%s: EncodePrivateKey() failed: %v", tc.name, err)
+			continue
+		}
+
+		bk, err := x509big.ParseBigPKCS1PrivateKey(der)
+		if err != nil {
+			t.Errorf("%s: exported key failed to parse: %v", tc.name, err)
+			continue
+		}
+
+		if bk.Precomputed.Qinv.Int64() != pc.Qinv.Int64() || len(bk.Primes) != len(tc.primes) {
+			t.Errorf("%s: exported key got qinv=%v primes=%v want qinv=%v", tc.name, bk.Precomputed.Qinv, bk.Primes, pc.Qinv)
+		}
+	}
+}
+
+func TestPackMultiPrime(t *testing.T) {
+	tt := []struct {
+		name    string
+		n, e    int64
+		primes  []int64
+		wantD   int64
+		wantCRT bool
+		wantErr bool
+	}{
+		{
+			name:    "three primes",
+			n:       229543,
+			e:       17,
+			primes:  []int64{61, 53, 71},
+			wantD:   205553,
+			wantCRT: true,
+		},
+		{
+			name:   "prime power",
+			n:      3721,
+			e:      17,
+			primes: []int64{61, 61},
+			wantD:  2153,
+		},
+		{
+			name:    "e not invertible",
+			n:       61158437,
+			e:       3,
+			primes:  []int64{7919, 7723},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		k, _ := NewRSA(PrivateFromPublic(&FMPPublicKey{N: fmp.NewFmpz(tc.n), E: fmp.NewFmpz(tc.e)}), nil, nil, "", false)
+
+		var primes []*fmp.Fmpz
+		for _, p := range tc.primes {
+			primes = append(primes, fmp.NewFmpz(p))
+		}
+
+		err := k.PackMultiPrime(primes)
+		if len(k.Key.Primes) != len(tc.primes) {
+			t.Errorf("%s: PackMultiPrime() got primes %v want %v", tc.name, k.Key.Primes, tc.primes)
+		}
+
+		if tc.wantErr {
+			if err == nil || k.Key.D != nil {
+				t.Errorf("%s: PackMultiPrime() expected error and no d got %v, d=%v", tc.name, err, k.Key.D)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: PackMultiPrime() failed: %v", tc.name, err)
+			continue
+		}
+
+		if k.Key.D.Int64() != tc.wantD || k.Key.precomputed() != tc.wantCRT {
+			t.Errorf("%s: PackMultiPrime() got d=%v CRT values %t want d=%d %t", tc.name, k.Key.D, k.Key.precomputed(), tc.wantD, tc.wantCRT)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package keys
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	fmp "github.com/sourcekris/goflint"
+)
+
+// OpenPGP packet tags holding keys, RFC 4880 section 4.3.
+const (
+	pgpTagSecretKey    = 5
+	pgpTagPublicKey    = 6
+	pgpTagSecretSubkey = 7
+	pgpTagPublicSubkey = 14
+)
+
+// pgpArmorPrefix starts every ASCII armored OpenPGP block.
+const pgpArmorPrefix = "-----BEGIN PGP "
+
+// isPGPKeyPacket returns true if kb starts with an OpenPGP key packet header.
+func isPGPKeyPacket(kb []byte) bool {
+	if len(kb) == 0 || kb[0]&0x80 == 0 {
+		return false
+	}
+
+	tag := int(kb[0]&0x3c) >> 2
+	if kb[0]&0x40 != 0 {
+		tag = int(kb[0] & 0x3f)
+	}
+
+	return tag == pgpTagPublicKey || tag == pgpTagSecretKey
+}
+
+// crc24 is the checksum of ASCII armor, RFC 4880 section 6.1.
+func crc24(b []byte) uint32 {
+	crc := uint32(0xb704ce)
+	for _, c := range b {
+		crc ^= uint32(c) << 16
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= 0x1864cfb
+			}
+		}
+	}
+
+	return crc & 0xffffff
+}
+
+// dearmor returns the binary packets of every ASCII armored block in kb, checking their checksums.
+func dearmor(kb []byte) ([]byte, error) {
+	var (
+		out     []byte
+		body    strings.Builder
+		sum     string
+		inBlock bool
+		headers bool
+		s       = bufio.NewScanner(bytes.NewReader(kb))
+	)
+
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		switch {
+		case strings.HasPrefix(line, pgpArmorPrefix):
+			inBlock, headers = true, true
+			body.Reset()
+			sum = ""
+		case !inBlock:
+		case strings.HasPrefix(line, "-----END PGP "):
+			b, err := base64.StdEncoding.DecodeString(body.String())
+			if err != nil {
+				return nil, fmt.Errorf("failed decoding armor: %v", err)
+			}
+
+			if sum != "" {
+				want, err := base64.StdEncoding.DecodeString(sum)
+				if err != nil || len(want) != 3 {
+					return nil, fmt.Errorf("invalid armor checksum %q", sum)
+				}
+
+				if got := crc24(b); got != uint32(want[0])<<16|uint32(want[1])<<8|uint32(want[2]) {
+					return nil, fmt.Errorf("armor checksum mismatch, got %06x", got)
+				}
+			}
+
+			out = append(out, b...)
+			inBlock = false
+		case headers && strings.Contains(line, ": "):
+			// Armor headers such as Version or Comment.
+		case headers && line == "":
+			headers = false
+		case strings.HasPrefix(line, "=") && len(line) == 5:
+			sum = line[1:]
+		default:
+			headers = false
+			body.WriteString(line)
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(out) == 0 {
+		return nil, errors.New("no complete armored block found")
+	}
+
+	return out, nil
+}
+
+// pgpPacket is a tag and the body of an OpenPGP packet.
+type pgpPacket struct {
+	tag  int
+	body []byte
+}
+
+// readPGPPackets splits b into packets, supporting old and new format headers, RFC 4880 section 4.2.
+func readPGPPackets(b []byte) ([]pgpPacket, error) {
+	var ps []pgpPacket
+	for len(b) > 0 {
+		h := b[0]
+		if h&0x80 == 0 {
+			return nil, fmt.Errorf("invalid packet header %#x", h)
+		}
+
+		var (
+			tag int
+			l   int
+			hl  int
+		)
+
+		if h&0x40 == 0 {
+			// Old format, the length type is in the low two bits.
+			tag = int(h&0x3c) >> 2
+			switch h & 3 {
+			case 0:
+				if len(b) < 2 {
+					return nil, errors.New("truncated packet header")
+				}
+				l, hl = int(b[1]), 2
+			case 1:
+				if len(b) < 3 {
+					return nil, errors.New("truncated packet header")
+				}
+				l, hl = int(binary.BigEndian.Uint16(b[1:])), 3
+			case 2:
+				if len(b) < 5 {
+					return nil, errors.New("truncated packet header")
+				}
+				l, hl = int(binary.BigEndian.Uint32(b[1:])), 5
+			case 3:
+				l, hl = len(b)-1, 1
+			}
+		} else {
+			tag = int(h & 0x3f)
+			if len(b) < 2 {
+				return nil, errors.New("truncated packet header")
+			}
+
+			switch o := int(b[1]); {
+			case o < 192:
+				l, hl = o, 2
+			case o < 224:
+				if len(b) < 3 {
+					return nil, errors.New("truncated packet header")
+				}
+				l, hl = (o-192)<<8+int(b[2])+192, 3
+			case o == 255:
+				if len(b) < 6 {
+					return nil, errors.New("truncated packet header")
+				}
+				l, hl = int(binary.BigEndian.Uint32(b[2:])), 6
+			default:
+				return nil, errors.New("partial body lengths are not supported")
+			}
+		}
+
+		if l < 0 || l > len(b)-hl {
+			return nil, fmt.Errorf("packet length %d exceeds the remaining %d bytes", l, len(b)-hl)
+		}
+
+		ps = append(ps, pgpPacket{tag: tag, body: b[hl : hl+l]})
+		b = b[hl+l:]
+	}
+
+	return ps, nil
+}
+
+// pgpMPI reads a multiprecision integer, a two byte bit count followed by the big-endian value.
+func pgpMPI(b []byte) (*fmp.Fmpz, []byte, error) {
+	if len(b) < 2 {
+		return nil, nil, errors.New("truncated MPI")
+	}
+
+	l := (int(binary.BigEndian.Uint16(b)) + 7) / 8
+	if l > len(b)-2 {
+		return nil, nil, errors.New("truncated MPI")
+	}
+
+	return new(fmp.Fmpz).SetBytes(b[2 : 2+l]), b[2+l:], nil
+}
+
+// parsePGPKey decodes the public part of a key packet, RFC 4880 section 5.5.2, and returns the key
+// along with its ID, the low 64 bits of the fingerprint for v4 keys or of the modulus for v3 keys.
+// The key is nil for keys that are not RSA or not version 2 to 4.
+func parsePGPKey(body []byte) (*FMPPublicKey, string, error) {
+	if len(body) < 1 {
+		return nil, "", errors.New("empty key packet")
+	}
+
+	var off int
+	switch v := body[0]; v {
+	case 4:
+		off = 6
+	case 2, 3:
+		off = 8
+	default:
+		// Newer key versions are not supported yet.
+		return nil, "", nil
+	}
+
+	if len(body) < off {
+		return nil, "", errors.New("truncated key packet")
+	}
+
+	// Public key algorithms 1, 2 and 3 are RSA encrypt or sign, encrypt only and sign only.
+	if a := body[off-1]; a < 1 || a > 3 {
+		return nil, "", nil
+	}
+
+	n, rest, err := pgpMPI(body[off:])
+	if err != nil {
+		return nil, "", fmt.Errorf("failed reading n: %v", err)
+	}
+
+	e, rest, err := pgpMPI(rest)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed reading e: %v", err)
+	}
+
+	var id []byte
+	if body[0] == 4 {
+		// The fingerprint covers the public part only, secret key packets carry more after it.
+		pub := body[:len(body)-len(rest)]
+		h := sha1.New()
+		h.Write([]byte{0x99, byte(len(pub) >> 8), byte(len(pub))})
+		h.Write(pub)
+		id = h.Sum(nil)[12:]
+	} else {
+		nb := n.Bytes()
+		if len(nb) < 8 {
+			return nil, "", errors.New("modulus too small for a v3 key ID")
+		}
+		id = nb[len(nb)-8:]
+	}
+
+	return &FMPPublicKey{N: n, E: e}, fmt.Sprintf("%X", id), nil
+}
+
+// ImportOpenPGP imports every RSA key and subkey of an ASCII armored or binary OpenPGP key block.
+// Keys are labelled with their key ID, keys using other algorithms are ignored.
+func ImportOpenPGP(kb []byte) ([]*RSA, error) {
+	if bytes.Contains(kb, []byte(pgpArmorPrefix)) {
+		var err error
+		if kb, err = dearmor(kb); err != nil {
+			return nil, fmt.Errorf("ImportOpenPGP: %v", err)
+		}
+	}
+
+	ps, err := readPGPPackets(kb)
+	if err != nil {
+		return nil, fmt.Errorf("ImportOpenPGP: %v", err)
+	}
+
+	var ks []*RSA
+	for _, p := range ps {
+		switch p.tag {
+		case pgpTagPublicKey, pgpTagPublicSubkey, pgpTagSecretKey, pgpTagSecretSubkey:
+		default:
+			continue
+		}
+
+		key, id, err := parsePGPKey(p.body)
+		if err != nil {
+			return nil, fmt.Errorf("ImportOpenPGP: %v", err)
+		}
+
+		if key == nil {
+			continue
+		}
+
+		k, err := NewRSA(PrivateFromPublic(key), nil, nil, "", false)
+		if err != nil {
+			return nil, err
+		}
+		k.Label = id
+
+		ks = append(ks, k)
+	}
+
+	if len(ks) == 0 {
+		return nil, errors.New("ImportOpenPGP: no RSA keys found")
+	}
+
+	return ks, nil
+}
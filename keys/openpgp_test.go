@@ -0,0 +1,86 @@
+package keys
+
+import (
+	"strings"
+	"testing"
+)
+
+// aliceKey has an RSA primary key, an RSA encryption subkey and an ed25519 signing subkey.
+const aliceKey = `-----BEGIN PGP PUBLIC KEY BLOCK-----
+
+mI0EatNGwQEEALvxxx9HJ/CJfbKSCg3pwU8iGclodQ8uAcg3Ub0UDwaX9jMquimn
+6qFwCeg4zqYpRdVR8ciH/8cae0KyqQKOqhGtEfHdIassZLgrXaAUlKYg2OMpFxQK
+m8Z0AT0jkSUxElXqFtBo5hVMKYsfSP9yPbxi+JmamAYVjnjlXF7XT7A1ABEBAAG0
+GUFsaWNlIDxhbGljZUBleGFtcGxlLmNvbT6IzgQTAQoAOBYhBPC55kMSAdsYZnap
+0RpTNX5JNtusBQJq00bBAhsDBQsJCAcCBhUKCQgLAgQWAgMBAh4BAheAAAoJEBpT
+NX5JNtuszWoEAKYr+aQ9AOat5UNLJcZX4m9AYht8Fk8xHeHKZJ8zbStxb7Kg4rZI
+SJBGsL5fcgGqXYFY4ok5is7Z1EFPlRPfVpHPPK93riUUArVb+r59uMvp5AGtVm15
+BGfj71bNnpoG2etu2TbM/gk7a2KPCYibRaTJMnwhPvt2Fde5hYXYFUOVuI0EatNG
+wQEEAOmPzfG4N5tX/GBIO8VRAq4Rt0Q4yIyk6nWccRguTCbNtBkSID1I//lv64af
+GOXYWtdSiK6ZCfsH9zif2iwlIpnaqHLFUwzJHtbaCJ0UH875M7Gz0UOX0zcbcpvE
+lw/6JLzJjBc70ttupqhg47O7LjAS1PRePz6OvZ3J8Wo8pxHHABEBAAGItgQYAQoA
+IBYhBPC55kMSAdsYZnap0RpTNX5JNtusBQJq00bBAhsMAAoJEBpTNX5JNtusEkUE
+AKH5iIekobgvBBIl7aH/D1JWt43r3L2QTmOQ02xredtNf1c0IjGXrqbmPheQjljt
+i9sp5jzNnKWxRp7PveoV7i9G0/FHJoQuBl/XeBzvUAkn8+3NReULhGwqicSP+/Ze
+Sk2UhR7oiQXAEdMQSMSvPZJxm31gqpj0Unq7/OLt4rHkuDMEatNGwRYJKwYBBAHa
+Rw8BAQdARY7TWIqW8zpQk2V0VJzAclSiVt3W9VMSNj6mgYYgi9iJAS0EGAEKACAW
+IQTwueZDEgHbGGZ2qdEaUzV+STbbrAUCatNGwQIbAgCBCRAaUzV+STbbrHYgBBkW
+CAAdFiEECD/ULw7PhgE8TBMEk+JV/B7ybY4FAmrTRsEACgkQk+JV/B7ybY7c/gEA
+1hBUBsxPqJv30sMVGWa1S9XmqauO0dT2UlO3Yy930P8BAPV6uX6eyOnTUNYfleSb
+ZXMTy7e+Kx/BNZB49jPTsd0NmO8EAJqE9UtfpPloKHnBiRct4ktSFJdkIw3jAQ0E
+dI7t+OtYDgrt4PkrrRsMylTxgK4YC3HOEcQrAlHe8iwHv3rgYEwSx8R9scJKHlWx
+JQiotC+ECWhZS65HjhTAvPTuSy+um0SU2d6OYzHCRLDaqOV2jQNOU8RGmF8eNbB7
+AjB6zvbL
+=5xbC
+-----END PGP PUBLIC KEY BLOCK-----`
+
+func TestImportOpenPGP(t *testing.T) {
+	bin, err := dearmor([]byte(aliceKey))
+	if err != nil {
+		t.Fatalf("dearmor() failed: %v", err)
+	}
+
+	// Corrupt the checksum, the last line before the footer.
+	lines := strings.Split(aliceKey, "\n")
+	sum := lines[len(lines)-2]
+	badSum := strings.Replace(aliceKey, sum, "=AAAA", 1)
+
+	tt := []struct {
+		name    string
+		kb      []byte
+		wantErr bool
+	}{
+		{name: "armored", kb: []byte(aliceKey)},
+		{name: "binary", kb: bin},
+		{name: "armor with headers", kb: []byte(strings.Replace(aliceKey, "BLOCK-----\n", "BLOCK-----\nComment: test\n", 1))},
+		{name: "bad checksum", kb: []byte(badSum), wantErr: true},
+		{name: "truncated binary", kb: bin[:100], wantErr: true},
+	}
+
+	want := []string{"1A53357E4936DBAC", "F98E56B719EA8EF0"}
+	for _, tc := range tt {
+		ks, err := ImportKeys(tc.kb)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: ImportKeys() expected error got nil", tc.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: ImportKeys() failed: %v", tc.name, err)
+			continue
+		}
+
+		if len(ks) != len(want) {
+			t.Errorf("%s: ImportKeys() got %d keys want %d", tc.name, len(ks), len(want))
+			continue
+		}
+
+		for i, k := range ks {
+			if k.Label != want[i] || k.Key.PublicKey.E.Int64() != 65537 || k.Key.N.BitLen() != 1024 {
+				t.Errorf("%s: key %d got label %q e=%v %d bit n want label %q", tc.name, i, k.Label, k.Key.PublicKey.E, k.Key.N.BitLen(), want[i])
+			}
+		}
+	}
+}
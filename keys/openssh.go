@@ -0,0 +1,330 @@
+package keys
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	fmp "github.com/sourcekris/goflint"
+)
+
+const (
+	// sshRSA is the OpenSSH key type of RSA public keys.
+	sshRSA = "ssh-rsa"
+	// sshPrivateMagic starts an openssh-key-v1 private key.
+	sshPrivateMagic = "openssh-key-v1\x00"
+	// sshPrivatePEM is the PEM block type of OpenSSH private keys.
+	sshPrivatePEM = "OPENSSH PRIVATE KEY"
+)
+
+// sshReader reads the string and mpint fields of the OpenSSH wire format described in RFC 4251.
+type sshReader struct {
+	b []byte
+}
+
+// next returns the next length prefixed field.
+func (r *sshReader) next() ([]byte, error) {
+	if len(r.b) < 4 {
+		return nil, errors.New("truncated field length")
+	}
+
+	l := binary.BigEndian.Uint32(r.b)
+	if uint64(l) > uint64(len(r.b)-4) {
+		return nil, fmt.Errorf("field length %d exceeds the remaining %d bytes", l, len(r.b)-4)
+	}
+
+	f := r.b[4 : 4+l]
+	r.b = r.b[4+l:]
+
+	return f, nil
+}
+
+// uint32 returns the next 32-bit integer.
+func (r *sshReader) uint32() (uint32, error) {
+	if len(r.b) < 4 {
+		return 0, errors.New("truncated integer")
+	}
+
+	i := binary.BigEndian.Uint32(r.b)
+	r.b = r.b[4:]
+
+	return i, nil
+}
+
+// mpint returns the next field as a non-negative integer.
+func (r *sshReader) mpint() (*fmp.Fmpz, error) {
+	f, err := r.next()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(f) > 0 && f[0]&0x80 != 0 {
+		return nil, errors.New("negative mpint")
+	}
+
+	return new(fmp.Fmpz).SetBytes(f), nil
+}
+
+// sshWriter builds the OpenSSH wire format.
+type sshWriter struct {
+	b []byte
+}
+
+func (w *sshWriter) uint32(i uint32) {
+	w.b = binary.BigEndian.AppendUint32(w.b, i)
+}
+
+func (w *sshWriter) bytes(f []byte) {
+	w.uint32(uint32(len(f)))
+	w.b = append(w.b, f...)
+}
+
+func (w *sshWriter) string(s string) {
+	w.bytes([]byte(s))
+}
+
+// mpint writes the non-negative integer z, with a leading zero byte when its top bit is set.
+func (w *sshWriter) mpint(z *fmp.Fmpz) {
+	b := z.Bytes()
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	w.bytes(b)
+}
+
+// marshalSSHPublicKey returns pub as an ssh-rsa public key blob.
+func marshalSSHPublicKey(pub *FMPPublicKey) []byte {
+	w := &sshWriter{}
+	w.string(sshRSA)
+	w.mpint(pub.E)
+	w.mpint(pub.N)
+
+	return w.b
+}
+
+// encodeOpenSSHPrivateKey returns priv as an unencrypted openssh-key-v1 private key in PEM form.
+func encodeOpenSSHPrivateKey(priv *FMPPrivateKey, comment string) ([]byte, error) {
+	if len(priv.Primes) != 2 {
+		return nil, fmt.Errorf("OpenSSH private keys hold exactly two primes, the key has %d", len(priv.Primes))
+	}
+
+	var check [4]byte
+	if _, err := rand.Read(check[:]); err != nil {
+		return nil, err
+	}
+
+	p, q := priv.Primes[0], priv.Primes[1]
+	iqmp := new(fmp.Fmpz).ModInverse(q, p)
+
+	pw := &sshWriter{}
+	pw.b = append(pw.b, check[:]...)
+	pw.b = append(pw.b, check[:]...)
+	pw.string(sshRSA)
+	pw.mpint(priv.PublicKey.N)
+	pw.mpint(priv.PublicKey.E)
+	pw.mpint(priv.D)
+	pw.mpint(iqmp)
+	pw.mpint(p)
+	pw.mpint(q)
+	pw.string(comment)
+	// Pad to the cipher block size, 8 for the none cipher.
+	for i := byte(1); len(pw.b)%8 != 0; i++ {
+		pw.b = append(pw.b, i)
+	}
+
+	w := &sshWriter{b: []byte(sshPrivateMagic)}
+	w.string("none")
+	w.string("none")
+	w.string("")
+	w.uint32(1)
+	w.bytes(marshalSSHPublicKey(priv.PublicKey))
+	w.bytes(pw.b)
+
+	return pem.EncodeToMemory(&pem.Block{Type: sshPrivatePEM, Bytes: w.b}), nil
+}
+
+// parseOpenSSHPrivateKey decodes the body of an unencrypted openssh-key-v1 RSA private key.
+func parseOpenSSHPrivateKey(b []byte) (*FMPPrivateKey, error) {
+	if !bytes.HasPrefix(b, []byte(sshPrivateMagic)) {
+		return nil, errors.New("parseOpenSSHPrivateKey: not an openssh-key-v1 key")
+	}
+
+	r := &sshReader{b: b[len(sshPrivateMagic):]}
+	cipher, err := r.next()
+	if err != nil {
+		return nil, fmt.Errorf("parseOpenSSHPrivateKey: %v", err)
+	}
+
+	if string(cipher) != "none" {
+		return nil, fmt.Errorf("parseOpenSSHPrivateKey: encrypted keys are not supported, the key uses %s", cipher)
+	}
+
+	// Skip the kdf name and options then the public keys.
+	for i := 0; i < 2; i++ {
+		if _, err := r.next(); err != nil {
+			return nil, fmt.Errorf("parseOpenSSHPrivateKey: %v", err)
+		}
+	}
+
+	nk, err := r.uint32()
+	if err != nil {
+		return nil, fmt.Errorf("parseOpenSSHPrivateKey: %v", err)
+	}
+
+	if nk != 1 {
+		return nil, fmt.Errorf("parseOpenSSHPrivateKey: expected 1 key got %d", nk)
+	}
+
+	if _, err := r.next(); err != nil {
+		return nil, fmt.Errorf("parseOpenSSHPrivateKey: %v", err)
+	}
+
+	ps, err := r.next()
+	if err != nil {
+		return nil, fmt.Errorf("parseOpenSSHPrivateKey: %v", err)
+	}
+
+	r = &sshReader{b: ps}
+	c1, err1 := r.uint32()
+	c2, err2 := r.uint32()
+	if err1 != nil || err2 != nil || c1 != c2 {
+		return nil, errors.New("parseOpenSSHPrivateKey: check bytes mismatch")
+	}
+
+	t, err := r.next()
+	if err != nil {
+		return nil, fmt.Errorf("parseOpenSSHPrivateKey: %v", err)
+	}
+
+	if string(t) != sshRSA {
+		return nil, fmt.Errorf("parseOpenSSHPrivateKey: unsupported key type %q", t)
+	}
+
+	// n, e, d, iqmp, p and q in that order.
+	var zs [6]*fmp.Fmpz
+	for i := range zs {
+		if zs[i], err = r.mpint(); err != nil {
+			return nil, fmt.Errorf("parseOpenSSHPrivateKey: %v", err)
+		}
+	}
+
+	k := PrivateFromPublic(&FMPPublicKey{N: zs[0], E: zs[1]})
+	k.D = zs[2]
+	k.Primes = []*fmp.Fmpz{zs[4], zs[5]}
+	if err := k.Precompute(); err != nil {
+		return nil, fmt.Errorf("parseOpenSSHPrivateKey: invalid private key: %v", err)
+	}
+
+	return k, nil
+}
+
+// parseSSHPublicKey decodes an ssh-rsa public key blob in the OpenSSH wire format.
+func parseSSHPublicKey(blob []byte) (*FMPPublicKey, error) {
+	r := &sshReader{b: blob}
+
+	t, err := r.next()
+	if err != nil {
+		return nil, fmt.Errorf("failed reading key type: %v", err)
+	}
+
+	if string(t) != sshRSA {
+		return nil, fmt.Errorf("unsupported key type %q", t)
+	}
+
+	e, err := r.mpint()
+	if err != nil {
+		return nil, fmt.Errorf("failed reading e: %v", err)
+	}
+
+	n, err := r.mpint()
+	if err != nil {
+		return nil, fmt.Errorf("failed reading n: %v", err)
+	}
+
+	return &FMPPublicKey{N: n, E: e}, nil
+}
+
+// parseSSHLine finds an ssh-rsa key on a line of an id_rsa.pub, authorized_keys or known_hosts file
+// and returns it along with a label taken from the key's comment or, failing that, the host
+// names. It returns a nil key for lines holding no RSA key.
+func parseSSHLine(line string) (*FMPPublicKey, string, error) {
+	fs := strings.Fields(line)
+	for i := 0; i+1 < len(fs); i++ {
+		if fs[i] != sshRSA {
+			continue
+		}
+
+		blob, err := base64.StdEncoding.DecodeString(fs[i+1])
+		if err != nil {
+			return nil, "", fmt.Errorf("failed decoding %s key: %v", sshRSA, err)
+		}
+
+		key, err := parseSSHPublicKey(blob)
+		if err != nil {
+			return nil, "", err
+		}
+
+		label := strings.Join(fs[i+2:], " ")
+		if label == "" && i > 0 && !strings.HasPrefix(fs[i-1], "@") {
+			// known_hosts lines start with the host names.
+			label = fs[i-1]
+		}
+
+		return key, label, nil
+	}
+
+	return nil, "", nil
+}
+
+// ImportOpenSSH imports every RSA public key in an OpenSSH public key, authorized_keys or
+// known_hosts file. Keys of other types, comments and blank lines are ignored.
+func ImportOpenSSH(kb []byte) ([]*RSA, error) {
+	var (
+		ks     []*RSA
+		s      = bufio.NewScanner(bytes.NewReader(kb))
+		lineNo int
+	)
+
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		lineNo++
+
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, label, err := parseSSHLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("ImportOpenSSH: line %d: %v", lineNo, err)
+		}
+
+		if key == nil {
+			continue
+		}
+
+		k, err := NewRSA(PrivateFromPublic(key), nil, nil, "", false)
+		if err != nil {
+			return nil, err
+		}
+		k.Label = label
+
+		ks = append(ks, k)
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("ImportOpenSSH: %v", err)
+	}
+
+	if len(ks) == 0 {
+		return nil, errors.New("ImportOpenSSH: no ssh-rsa keys found")
+	}
+
+	return ks, nil
+}
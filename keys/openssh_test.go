@@ -0,0 +1,91 @@
+package keys
+
+import (
+	"testing"
+)
+
+func TestImportKeys(t *testing.T) {
+	type key struct {
+		n, e  int64
+		label string
+	}
+
+	tt := []struct {
+		name    string
+		kb      string
+		want    []key
+		wantErr bool
+	}{
+		{
+			name: "id_rsa.pub",
+			kb:   "ssh-rsa AAAAB3NzaC1yc2EAAAABEQAAAAIMoQ== alice@laptop\n",
+			want: []key{{3233, 17, "alice@laptop"}},
+		},
+		{
+			name: "authorized_keys with options, comments and other key types",
+			kb: "# keys\n\n" +
+				"ssh-rsa AAAAB3NzaC1yc2EAAAABEQAAAAIMoQ== alice@laptop\n" +
+				"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIMTLmbFPU7jCO1byflBAbmykLbL4vbw0Jfba3xhGOf6u carol@ci\n" +
+				"from=\"10.0.0.0/8\",no-pty ssh-rsa AAAAB3NzaC1yc2EAAAABAwAAAAIMNw== bob at build\n",
+			want: []key{{3233, 17, "alice@laptop"}, {3127, 3, "bob at build"}},
+		},
+		{
+			name: "known_hosts",
+			kb: "example.com,10.1.1.1 ssh-rsa AAAAB3NzaC1yc2EAAAABEQAAAAIMoQ==\n" +
+				"@cert-authority *.example.com ssh-rsa AAAAB3NzaC1yc2EAAAABAwAAAAIMNw== ca\n",
+			want: []key{{3233, 17, "example.com,10.1.1.1"}, {3127, 3, "ca"}},
+		},
+		{
+			name:    "no rsa keys",
+			kb:      "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIMTLmbFPU7jCO1byflBAbmykLbL4vbw0Jfba3xhGOf6u carol@ci\n",
+			wantErr: true,
+		},
+		{
+			name:    "wrong key type in blob",
+			kb:      "ssh-rsa AAAAB3NzaC1kc3MAAAABAQ== mallory\n",
+			wantErr: true,
+		},
+		{
+			name:    "truncated blob",
+			kb:      "ssh-rsa AAAAB3NzaC1yc2EAAAABAwAAADIB mallory\n",
+			wantErr: true,
+		},
+		{
+			name:    "bad base64",
+			kb:      "ssh-rsa AAAA!!!! mallory\n",
+			wantErr: true,
+		},
+		{
+			name:    "not a key",
+			kb:      "hello world\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		ks, err := ImportKeys([]byte(tc.kb))
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: ImportKeys() expected error got nil", tc.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: ImportKeys() failed: %v", tc.name, err)
+			continue
+		}
+
+		if len(ks) != len(tc.want) {
+			t.Errorf("%s: ImportKeys() got %d keys want %d", tc.name, len(ks), len(tc.want))
+			continue
+		}
+
+		for i, k := range ks {
+			w := tc.want[i]
+			if k.Key.N.Int64() != w.n || k.Key.PublicKey.E.Int64() != w.e || k.Label != w.label {
+				t.Errorf("%s: key %d got n=%v e=%v label=%q want n=%d e=%d label=%q", tc.name, i, k.Key.N, k.Key.PublicKey.E, k.Label, w.n, w.e, w.label)
+			}
+		}
+	}
+}
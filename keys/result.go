@@ -0,0 +1,92 @@
+package keys
+
+import (
+	"fmt"
+	"time"
+
+	fmp "github.com/sourcekris/goflint"
+)
+
+// Result is what a single attack recovered. Attacks return a Result instead of modifying the keys
+// they were given and the result is merged into the keys with Merge.
+type Result struct {
+	// Attack is the name of the attack that produced the result.
+	Attack string
+	// Key is the index of the key that D, N and PlainTexts belong to.
+	Key int
+	// Factors are factors of the modulus of one or more of the keys.
+	Factors []*fmp.Fmpz
+	D       *fmp.Fmpz
+	// N is a recovered modulus for a key where it was unknown.
+	N *fmp.Fmpz
+	// PlainTexts are plaintext candidates, the most likely first.
+	PlainTexts [][]byte
+	// Candidates are possible plaintexts the attack could not confirm, they are ranked among the
+	// Candidates of the key but never become its PlainText.
+	Candidates [][]byte
+	Duration   time.Duration
+	// Iterations is the number of iterations, curves or candidates the attack tried.
+	Iterations int64
+}
+
+// Merge applies r to ks. Factors refine the Factorization of every key whose modulus they share a
+// factor with while D, N, PlainTexts and Candidates are applied to ks[r.Key]. Values already recovered by an earlier result are
+// never overwritten. r is recorded in the Results of every key it applied to.
+func Merge(ks []*RSA, r *Result) error {
+	if r == nil {
+		return nil
+	}
+
+	if r.Key < 0 || r.Key >= len(ks) {
+		return fmt.Errorf("%s result is for key %d but only %d keys were given", r.Attack, r.Key, len(ks))
+	}
+
+	for i, k := range ks {
+		var refined bool
+		if k.Key.N != nil {
+			for _, f := range r.Factors {
+				if k.Refine(f) {
+					refined = true
+				}
+			}
+		}
+
+		if i != r.Key && !refined {
+			continue
+		}
+
+		if i == r.Key {
+			if r.N != nil && k.Key.PublicKey.N == nil {
+				k.Key.PublicKey.N = new(fmp.Fmpz).Set(r.N)
+				k.Key.N = k.Key.PublicKey.N
+			}
+
+			// Plaintexts go first so that a d which cannot decrypt the ciphertext on its own, as
+			// with defectivee, does not replace the plaintext the attack found.
+			if best := k.AddCandidates(r.Attack, r.PlainTexts); best != nil && len(k.PlainText) == 0 {
+				k.PlainText = best
+			}
+			k.AddCandidates(r.Attack, r.Candidates)
+		}
+
+		// The private key is only built once N is fully factored, or when the attack found d.
+		if k.Key.D == nil {
+			complete := k.Factorization != nil && k.Factorization.Complete()
+			switch {
+			case i == r.Key && r.D != nil:
+				if complete {
+					k.Key.Primes = k.Factorization.Primes
+				}
+				k.PackGivenD(r.D)
+			case refined && complete:
+				if err := k.PackMultiPrime(k.Factorization.Primes); err != nil {
+					return err
+				}
+			}
+		}
+
+		k.Results = append(k.Results, r)
+	}
+
+	return nil
+}
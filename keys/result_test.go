@@ -0,0 +1,96 @@
+package keys
+
+import (
+	"testing"
+
+	fmp "github.com/sourcekris/goflint"
+)
+
+func newKey(n int64) *RSA {
+	k, _ := NewRSA(PrivateFromPublic(&FMPPublicKey{N: fmp.NewFmpz(n), E: fmp.NewFmpz(7)}), nil, nil, "", false)
+	return k
+}
+
+func TestMerge(t *testing.T) {
+	tt := []struct {
+		name string
+		ks   []*RSA
+		// pre is merged before r to simulate an earlier attack.
+		pre    *Result
+		r      *Result
+		wantD  []bool
+		wantPT string
+		// wantResults is the number of results recorded on each key.
+		wantResults []int
+	}{
+		{
+			name:        "factor applies to every key it divides",
+			ks:          []*RSA{newKey(77), newKey(91), newKey(143)},
+			r:           &Result{Attack: "commonfactors", Factors: []*fmp.Fmpz{fmp.NewFmpz(7)}},
+			wantD:       []bool{true, true, false},
+			wantResults: []int{1, 1, 0},
+		},
+		{
+			name:        "first plaintext candidate is used",
+			ks:          []*RSA{newKey(77)},
+			r:           &Result{Attack: "defectivee", PlainTexts: [][]byte{[]byte("a"), []byte("b")}},
+			wantD:       []bool{false},
+			wantPT:      "a",
+			wantResults: []int{1},
+		},
+		{
+			name:        "unconfirmed candidates are not the plaintext",
+			ks:          []*RSA{newKey(77)},
+			r:           &Result{Attack: "defectivee", Candidates: [][]byte{[]byte("a"), []byte("b")}},
+			wantD:       []bool{false},
+			wantResults: []int{1},
+		},
+		{
+			name:        "earlier plaintext is not overwritten",
+			ks:          []*RSA{newKey(77)},
+			pre:         &Result{Attack: "hastads", PlainTexts: [][]byte{[]byte("first")}},
+			r:           &Result{Attack: "brokenrsa", PlainTexts: [][]byte{[]byte("second")}},
+			wantD:       []bool{false},
+			wantPT:      "first",
+			wantResults: []int{2},
+		},
+		{
+			name:        "d and primes from a result apply to its key",
+			ks:          []*RSA{newKey(77), newKey(91)},
+			r:           &Result{Attack: "squaren", Key: 1, Factors: []*fmp.Fmpz{fmp.NewFmpz(7), fmp.NewFmpz(13)}, D: fmp.NewFmpz(31)},
+			wantD:       []bool{true, true},
+			wantResults: []int{1, 1},
+		},
+	}
+
+	for _, tc := range tt {
+		if tc.pre != nil {
+			if err := Merge(tc.ks, tc.pre); err != nil {
+				t.Fatalf("%s: Merge() unexpected error: %v", tc.name, err)
+			}
+		}
+
+		if err := Merge(tc.ks, tc.r); err != nil {
+			t.Errorf("%s: Merge() unexpected error: %v", tc.name, err)
+			continue
+		}
+
+		for i, k := range tc.ks {
+			if got := k.Key.D != nil; got != tc.wantD[i] {
+				t.Errorf("%s: key %d has d = %v want d set %t", tc.name, i, k.Key.D, tc.wantD[i])
+			}
+
+			if got := len(k.Results); got != tc.wantResults[i] {
+				t.Errorf("%s: key %d has %d results want %d", tc.name, i, got, tc.wantResults[i])
+			}
+		}
+
+		if got := string(tc.ks[0].PlainText); got != tc.wantPT {
+			t.Errorf("%s: plaintext = %q want %q", tc.name, got, tc.wantPT)
+		}
+	}
+
+	if err := Merge([]*RSA{newKey(77)}, &Result{Attack: "bad", Key: 1}); err == nil {
+		t.Errorf("Merge() expected an error for a result with an out of range key")
+	}
+}
@@ -0,0 +1,303 @@
+package keys
+
+import (
+	"bytes"
+	"encoding/pem"
+	"fmt"
+
+	fmp "github.com/sourcekris/goflint"
+)
+
+const (
+	// minScanBits is the smallest modulus Scan reports, smaller ones are likely chance matches.
+	minScanBits = 64
+	// pemBegin and pemEnd start the first and last lines of PEM and OpenPGP armored blocks.
+	pemBegin = "-----BEGIN "
+	pemEnd   = "-----END "
+)
+
+// Carved is an RSA key found inside a larger file, like a memory dump or firmware image, by Scan.
+type Carved struct {
+	// Offset is where the key's encoding starts in the file.
+	Offset int
+	// Kind describes the key's encoding, e.g. "PEM RSA PRIVATE KEY".
+	Kind string
+	// Key is labelled with the offset followed by any label of its own, like an OpenSSH comment.
+	Key *RSA
+	// label is the key's own label.
+	label string
+}
+
+// String describes where the key was found and its type.
+func (c *Carved) String() string {
+	kind := "public"
+	if c.Key.Key.D != nil {
+		kind = "private"
+	}
+
+	s := fmt.Sprintf("offset %#08x: %s, %d bit %s key", c.Offset, c.Kind, c.Key.Key.N.BitLen(), kind)
+	if c.label != "" {
+		s = fmt.Sprintf("%s (%s)", s, c.label)
+	}
+
+	return s
+}
+
+// carver tries to decode keys of one encoding starting at the beginning of b. It returns the keys,
+// a description of the encoding and its length in bytes or no keys if b doesn't start with one.
+type carver func(b []byte) ([]*RSA, string, int)
+
+// carvers are tried in order at every offset of a scanned file.
+var carvers = []carver{carvePEM, carveSSHLine, carveSSHPrivate, carveSSHBlob, carveDER, carveIntegers}
+
+// Scan finds the RSA keys in PEM and OpenPGP armored blocks, DER encoded PKCS#1, PKCS#8, PKIX keys
+// and certificates, OpenSSH public keys and blobs, unencrypted openssh-key-v1 private keys and runs
+// of DER integers that fit the PKCS#1 private key structure anywhere inside b. The bytes of each key
+// found are skipped so keys nested in others are reported once.
+func Scan(b []byte) []*Carved {
+	var cs []*Carved
+
+	for i := 0; i < len(b); i++ {
+		for _, carve := range carvers {
+			ks, kind, l := carve(b[i:])
+
+			var found bool
+			for _, k := range ks {
+				if k.Key.N == nil || k.Key.N.BitLen() < minScanBits {
+					continue
+				}
+
+				c := &Carved{Offset: i, Kind: kind, Key: k, label: k.Label}
+				k.Label = fmt.Sprintf("offset %#x", i)
+				if c.label != "" {
+					k.Label = fmt.Sprintf("offset %#x: %s", i, c.label)
+				}
+
+				cs = append(cs, c)
+				found = true
+			}
+
+			if found {
+				i += l - 1
+				break
+			}
+		}
+	}
+
+	return cs
+}
+
+// armoredBlock returns the PEM or OpenPGP armored block that b starts with up to the end of its
+// last line, or nil if there isn't one.
+func armoredBlock(b []byte) []byte {
+	if !bytes.HasPrefix(b, []byte(pemBegin)) {
+		return nil
+	}
+
+	e := bytes.Index(b, []byte(pemEnd))
+	if e < 0 {
+		return nil
+	}
+
+	l := bytes.Index(b[e+len(pemEnd):], []byte("-----"))
+	if l < 0 {
+		return nil
+	}
+
+	return b[:e+len(pemEnd)+l+5]
+}
+
+// carvePEM decodes a PEM or OpenPGP armored block.
+func carvePEM(b []byte) ([]*RSA, string, int) {
+	ab := armoredBlock(b)
+	if ab == nil {
+		return nil, "", 0
+	}
+
+	if bytes.HasPrefix(ab, []byte(pgpArmorPrefix)) {
+		ks, _ := ImportOpenPGP(ab)
+		return ks, "OpenPGP armored key", len(ab)
+	}
+
+	block, _ := pem.Decode(ab)
+	if block == nil {
+		return nil, "", 0
+	}
+
+	ks, _ := importPEMBlock(block)
+	return ks, "PEM " + block.Type, len(ab)
+}
+
+// carveSSHLine decodes an OpenSSH public key line like those of id_rsa.pub and authorized_keys.
+func carveSSHLine(b []byte) ([]*RSA, string, int) {
+	if !bytes.HasPrefix(b, []byte(sshRSA+" AAAA")) {
+		return nil, "", 0
+	}
+
+	l := bytes.IndexAny(b, "\r\n\x00")
+	if l < 0 {
+		l = len(b)
+	}
+
+	key, label, err := parseSSHLine(string(b[:l]))
+	if err != nil || key == nil {
+		return nil, "", 0
+	}
+
+	k, err := NewRSA(PrivateFromPublic(key), nil, nil, "", false)
+	if err != nil {
+		return nil, "", 0
+	}
+	k.Label = label
+
+	return []*RSA{k}, "OpenSSH public key", l
+}
+
+// carveSSHPrivate decodes an unencrypted openssh-key-v1 private key, the body of an OPENSSH PRIVATE
+// KEY PEM block.
+func carveSSHPrivate(b []byte) ([]*RSA, string, int) {
+	if !bytes.HasPrefix(b, []byte(sshPrivateMagic)) {
+		return nil, "", 0
+	}
+
+	priv, err := parseOpenSSHPrivateKey(b)
+	if err != nil {
+		return nil, "", 0
+	}
+
+	// The key ends after the cipher, kdf name and options, key count, public keys and private keys.
+	r := &sshReader{b: b[len(sshPrivateMagic):]}
+	for i := 0; i < 3; i++ {
+		r.next()
+	}
+	r.uint32()
+	r.next()
+	r.next()
+
+	ks, err := newRSAs(priv)
+	if err != nil {
+		return nil, "", 0
+	}
+
+	return ks, "openssh-key-v1 private key", len(b) - len(r.b)
+}
+
+// carveSSHBlob decodes an ssh-rsa public key in the OpenSSH wire format.
+func carveSSHBlob(b []byte) ([]*RSA, string, int) {
+	if !bytes.HasPrefix(b, []byte("\x00\x00\x00\x07"+sshRSA)) {
+		return nil, "", 0
+	}
+
+	// The blob holds the key type, e and n.
+	r := &sshReader{b: b}
+	for i := 0; i < 3; i++ {
+		if _, err := r.next(); err != nil {
+			return nil, "", 0
+		}
+	}
+
+	key, err := parseSSHPublicKey(b[:len(b)-len(r.b)])
+	if err != nil {
+		return nil, "", 0
+	}
+
+	ks, err := newRSAs(PrivateFromPublic(key))
+	if err != nil {
+		return nil, "", 0
+	}
+
+	return ks, "OpenSSH public key blob", len(b) - len(r.b)
+}
+
+// derElement returns the length of the DER element with tag t that b starts with, including its
+// header, and the length of the header.
+func derElement(b []byte, t byte) (int, int, bool) {
+	if len(b) < 2 || b[0] != t {
+		return 0, 0, false
+	}
+
+	if b[1] < 0x80 {
+		return 2 + int(b[1]), 2, 2+int(b[1]) <= len(b)
+	}
+
+	// Long form lengths, keys don't need more than 4 length bytes.
+	nl := int(b[1] & 0x7f)
+	if nl == 0 || nl > 4 || len(b) < 2+nl {
+		return 0, 0, false
+	}
+
+	var l int
+	for _, c := range b[2 : 2+nl] {
+		l = l<<8 | int(c)
+	}
+
+	if l < 0x80 || 2+nl+l > len(b) {
+		return 0, 0, false
+	}
+
+	return 2 + nl + l, 2 + nl, true
+}
+
+// carveDER decodes a DER encoded key or certificate.
+func carveDER(b []byte) ([]*RSA, string, int) {
+	l, _, ok := derElement(b, 0x30)
+	if !ok {
+		return nil, "", 0
+	}
+
+	ks, err := importDER(b[:l])
+	if err != nil {
+		return nil, "", 0
+	}
+
+	return ks, "DER", l
+}
+
+// carveIntegers decodes a run of DER integers holding the n, e, d, p and q members of a PKCS#1
+// private key, optionally preceded by its version, whose sequence header is missing or damaged.
+func carveIntegers(b []byte) ([]*RSA, string, int) {
+	var (
+		zs  []*fmp.Fmpz
+		end int
+	)
+
+	// Read up to the version, n, e, d, p, q, dp, dq and qinv.
+	for len(zs) < 9 {
+		l, h, ok := derElement(b[end:], 0x02)
+		if !ok || l == h || b[end+h]&0x80 != 0 {
+			break
+		}
+
+		zs = append(zs, new(fmp.Fmpz).SetBytes(b[end+h:end+l]))
+		end += l
+	}
+
+	if len(zs) > 0 && zs[0].Cmp(fmp.NewFmpz(0)) == 0 {
+		zs = zs[1:]
+	}
+
+	if len(zs) < 5 {
+		return nil, "", 0
+	}
+
+	n, e, d, p, q := zs[0], zs[1], zs[2], zs[3], zs[4]
+	if n.BitLen() < minScanBits || new(fmp.Fmpz).Mul(p, q).Cmp(n) != 0 {
+		return nil, "", 0
+	}
+
+	// Precompute also checks that d is the inverse of e, integers that only happen to multiply
+	// out are not a key.
+	priv := PrivateFromPublic(&FMPPublicKey{N: n, E: e})
+	priv.D = d
+	priv.Primes = []*fmp.Fmpz{p, q}
+	if err := priv.Precompute(); err != nil {
+		return nil, "", 0
+	}
+
+	ks, err := newRSAs(priv)
+	if err != nil {
+		return nil, "", 0
+	}
+
+	return ks, "PKCS#1 integers", end
+}
@@ -0,0 +1,99 @@
+package keys
+
+import (
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/sourcekris/goRsaTool/ln"
+	fmp "github.com/sourcekris/goflint"
+)
+
+func TestScan(t *testing.T) {
+	priv := PrivateFromPublic(&FMPPublicKey{N: ln.FmpString("2417851639291930512195989"), E: fmp.NewFmpz(65537)})
+	priv.D = ln.FmpString("812824928159774474329793")
+	priv.Primes = []*fmp.Fmpz{ln.FmpString("1099511627791"), ln.FmpString("2199023255579")}
+
+	tiny := PrivateFromPublic(&FMPPublicKey{N: fmp.NewFmpz(3233), E: fmp.NewFmpz(17)})
+	tiny.D = fmp.NewFmpz(2753)
+	tiny.Primes = []*fmp.Fmpz{fmp.NewFmpz(61), fmp.NewFmpz(53)}
+
+	pkcs1, _ := EncodePrivateKey(priv, FormatDER)
+	tinyDER, _ := EncodePrivateKey(tiny, FormatDER)
+	pkixPEM, _ := EncodePublicKey(priv.PublicKey, FormatPKCS8)
+	sshLine, _ := EncodePublicKey(priv.PublicKey, FormatOpenSSH)
+	sshPEM, _ := EncodePrivateKey(priv, FormatOpenSSH)
+	sshBlock, _ := pem.Decode(sshPEM)
+
+	// The integers of the PKCS#1 key without the sequence header.
+	_, h, _ := derElement(pkcs1, 0x30)
+
+	// The same integers with a d that is not the inverse of e.
+	var badD []byte
+	for _, z := range []*fmp.Fmpz{fmp.NewFmpz(0), priv.N, priv.PublicKey.E, new(fmp.Fmpz).Add(priv.D, ln.BigTwo), priv.Primes[0], priv.Primes[1]} {
+		b, _ := asn1.Marshal(new(big.Int).SetBytes(z.Bytes()))
+		badD = append(badD, b...)
+	}
+
+	parts := []struct {
+		b           []byte
+		wantKind    string
+		wantPrivate bool
+	}{
+		{b: pkcs1, wantKind: "DER", wantPrivate: true},
+		{b: pkixPEM, wantKind: "PEM PUBLIC KEY"},
+		{b: append(sshLine[:len(sshLine)-1:len(sshLine)-1], " user@host\n"...), wantKind: "OpenSSH public key"},
+		{b: sshBlock.Bytes, wantKind: "openssh-key-v1 private key", wantPrivate: true},
+		{b: marshalSSHPublicKey(priv.PublicKey), wantKind: "OpenSSH public key blob"},
+		{b: pkcs1[h:], wantKind: "PKCS#1 integers", wantPrivate: true},
+		{b: badD},
+		{b: tinyDER},
+		{b: sshPEM, wantKind: "PEM OPENSSH PRIVATE KEY", wantPrivate: true},
+	}
+
+	var (
+		dump    []byte
+		want    []string
+		junk    = []byte("\xde\xad\xbe\xef junk\x00\xff")
+		private []bool
+	)
+
+	for _, p := range parts {
+		dump = append(dump, junk...)
+		if p.wantKind != "" {
+			want = append(want, fmt.Sprintf("%d %s", len(dump), p.wantKind))
+			private = append(private, p.wantPrivate)
+		}
+		dump = append(dump, p.b...)
+	}
+	dump = append(dump, junk...)
+
+	var (
+		got        []string
+		gotPrivate []bool
+	)
+
+	for _, c := range Scan(dump) {
+		got = append(got, fmt.Sprintf("%d %s", c.Offset, c.Kind))
+		gotPrivate = append(gotPrivate, c.Key.Key.D != nil)
+
+		if !c.Key.Key.N.Equals(priv.N) {
+			t.Errorf("Scan() %v got n=%v want %v", c, c.Key.Key.N, priv.N)
+		}
+
+		if c.Key.Key.D != nil && c.Key.Key.Precomputed == nil {
+			t.Errorf("Scan() %v got a private key without CRT values", c)
+		}
+	}
+
+	if !reflect.DeepEqual(got, want) || !reflect.DeepEqual(gotPrivate, private) {
+		t.Errorf("Scan() got %q private %v want %q private %v", got, gotPrivate, want, private)
+	}
+
+	if cs := Scan(junk); len(cs) != 0 {
+		t.Errorf("Scan() got %v from junk want nothing", cs)
+	}
+}
@@ -0,0 +1,190 @@
+package plaintext
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxDepth is the most transforms chained when decoding a plaintext.
+	maxDepth = 4
+	// maxInflated is the most bytes a zlib stream is inflated to.
+	maxInflated = 1 << 20
+)
+
+// commonBigrams are frequent pairs of letters in English text and flags, they tell text from
+// reversed text.
+var commonBigrams = []string{"th", "he", "in", "er", "an", "re", "on", "at", "en", "nd", "ti", "es", "or", "te", "of", "ed", "is", "it", "al", "ar", "st", "to", "nt", "ng", "ha", "ou", "fl", "la", "ag"}
+
+// transform decodes one layer of a plaintext, it returns false if b is not encoded that way.
+type transform struct {
+	name string
+	f    func(b []byte) ([]byte, bool)
+}
+
+var transforms = []transform{
+	{"hex", decodeHex},
+	{"base64", decodeBase64},
+	{"zlib", inflate},
+	{"little-endian", reverse},
+	{"decimal", decodeDecimal},
+}
+
+// Decoded is a plaintext along with the transforms that decoded it.
+type Decoded struct {
+	// Chain names each transform applied in order, e.g. base64 then zlib.
+	Chain     []string
+	PlainText []byte
+}
+
+// String returns the chain of transforms, e.g. "base64 -> zlib -> text".
+func (d *Decoded) String() string {
+	last := "bytes"
+	if Printable(d.PlainText) {
+		last = "text"
+	}
+
+	return strings.Join(append(append([]string(nil), d.Chain...), last), " -> ")
+}
+
+// Decode tries hex, base64, zlib, little-endian and decimal decoding of pt recursively and returns
+// the decodings that are more printable or more like text than pt, best first. Branches may pass
+// through binary layers, like a zlib stream, as long as each decoding reported is better than pt
+// and the layer it was decoded from. The flag format regexp, which may be nil, counts towards how
+// text-like a decoding is.
+func Decode(pt []byte, flag *regexp.Regexp) []*Decoded {
+	if len(pt) == 0 {
+		return nil
+	}
+
+	seen := map[string]bool{string(pt): true}
+
+	var ds []*Decoded
+	var walk func(b []byte, chain []string)
+	walk = func(b []byte, chain []string) {
+		if len(chain) == maxDepth {
+			return
+		}
+
+		for _, t := range transforms {
+			// Reversing twice gets back where we were.
+			if t.name == "little-endian" && len(chain) > 0 && chain[len(chain)-1] == t.name {
+				continue
+			}
+
+			out, ok := t.f(b)
+			if !ok || len(out) == 0 || seen[string(out)] {
+				continue
+			}
+			seen[string(out)] = true
+
+			c := append(append([]string(nil), chain...), t.name)
+			if better(out, b, flag) && better(out, pt, flag) {
+				ds = append(ds, &Decoded{Chain: c, PlainText: out})
+			}
+
+			walk(out, c)
+		}
+	}
+	walk(pt, nil)
+
+	sort.SliceStable(ds, func(i, j int) bool { return better(ds[i].PlainText, ds[j].PlainText, flag) })
+
+	return ds
+}
+
+// printableRatio returns the fraction of b that is printable.
+func printableRatio(b []byte) float64 {
+	var n int
+	for _, c := range b {
+		if isPrintable(c) {
+			n++
+		}
+	}
+
+	return float64(n) / float64(len(b))
+}
+
+// textScore is Score plus how often common bigrams occur in b, which unlike Score depends on the
+// order of the bytes.
+func textScore(b []byte, flag *regexp.Regexp) float64 {
+	var n int
+	l := bytes.ToLower(b)
+	for _, bg := range commonBigrams {
+		n += bytes.Count(l, []byte(bg))
+	}
+
+	return Score(b, flag) + 0.2*float64(n)/float64(len(b))
+}
+
+// better returns true if a is more printable than b or as printable and more like text.
+func better(a, b []byte, flag *regexp.Regexp) bool {
+	if pa, pb := printableRatio(a), printableRatio(b); pa != pb {
+		return pa > pb
+	}
+
+	return textScore(a, flag) > textScore(b, flag)
+}
+
+func decodeHex(b []byte) ([]byte, bool) {
+	s := strings.Join(strings.Fields(string(b)), "")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+
+	out, err := hex.DecodeString(s)
+	return out, err == nil
+}
+
+func decodeBase64(b []byte) ([]byte, bool) {
+	s := strings.Join(strings.Fields(string(b)), "")
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding} {
+		if out, err := enc.DecodeString(s); err == nil {
+			return out, true
+		}
+
+		if out, err := enc.WithPadding(base64.NoPadding).DecodeString(s); err == nil {
+			return out, true
+		}
+	}
+
+	return nil, false
+}
+
+func inflate(b []byte) ([]byte, bool) {
+	r, err := zlib.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, false
+	}
+	defer r.Close()
+
+	out, err := ioutil.ReadAll(io.LimitReader(r, maxInflated))
+	return out, err == nil
+}
+
+// reverse undoes a message converted to an integer little-endian, as with
+// int.from_bytes(m, "little"), since plaintexts are recovered big-endian.
+func reverse(b []byte) ([]byte, bool) {
+	out := make([]byte, len(b))
+	for i, c := range b {
+		out[len(b)-1-i] = c
+	}
+
+	return out, true
+}
+
+// decodeDecimal returns the bytes of an integer printed as decimal ASCII.
+func decodeDecimal(b []byte) ([]byte, bool) {
+	n, ok := new(big.Int).SetString(strings.TrimSpace(string(b)), 10)
+	if !ok || n.Sign() <= 0 {
+		return nil, false
+	}
+
+	return n.Bytes(), true
+}
@@ -0,0 +1,88 @@
+package plaintext
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	deflate := func(b []byte) []byte {
+		var buf bytes.Buffer
+		w := zlib.NewWriter(&buf)
+		w.Write(b)
+		w.Close()
+		return buf.Bytes()
+	}
+
+	b64 := func(b []byte) []byte { return []byte(base64.StdEncoding.EncodeToString(b)) }
+	hx := func(b []byte) []byte { return []byte(hex.EncodeToString(b)) }
+	rev := func(b []byte) []byte { r, _ := reverse(b); return r }
+	dec := func(b []byte) []byte { return []byte(new(big.Int).SetBytes(b).String()) }
+
+	msg := []byte("flag{many_layers_of_encoding}")
+
+	tt := []struct {
+		name      string
+		pt        []byte
+		want      []byte
+		wantChain string
+	}{
+		{
+			name:      "hex",
+			pt:        hx(msg),
+			want:      msg,
+			wantChain: "hex -> text",
+		},
+		{
+			name:      "base64 of zlib",
+			pt:        b64(deflate(msg)),
+			want:      msg,
+			wantChain: "base64 -> zlib -> text",
+		},
+		{
+			name:      "little-endian",
+			pt:        rev(msg),
+			want:      msg,
+			wantChain: "little-endian -> text",
+		},
+		{
+			name:      "decimal",
+			pt:        dec(msg),
+			want:      msg,
+			wantChain: "decimal -> text",
+		},
+		{
+			name:      "hex of base64 of a reversed message",
+			pt:        hx(b64(rev(msg))),
+			want:      msg,
+			wantChain: "hex -> base64 -> little-endian -> text",
+		},
+		{
+			name: "text needs no decoding",
+			pt:   []byte("the cat sat on the mat"),
+		},
+	}
+
+	for _, tc := range tt {
+		ds := Decode(tc.pt, nil)
+		if tc.want == nil {
+			if len(ds) > 0 {
+				t.Errorf("%s: Decode() got %q via %s want no decodings", tc.name, ds[0].PlainText, ds[0])
+			}
+			continue
+		}
+
+		if len(ds) == 0 {
+			t.Errorf("%s: Decode() got no decodings want %q", tc.name, tc.want)
+			continue
+		}
+
+		if !bytes.Equal(ds[0].PlainText, tc.want) || ds[0].String() != tc.wantChain {
+			t.Errorf("%s: Decode() got %q via %s want %q via %s", tc.name, ds[0].PlainText, ds[0], tc.want, tc.wantChain)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package plaintext
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+)
+
+// commonText holds the most frequent characters of English text.
+const commonText = " etaoinshrdluETAOINSHRDLU"
+
+// Candidate is a possible plaintext along with how likely it is to be the right one.
+type Candidate struct {
+	PlainText []byte
+	// Score rates the candidate, see Score.
+	Score float64
+	// Source names the attack that found the candidate.
+	Source string
+}
+
+// isPrintable returns true if c is printable ASCII or whitespace.
+func isPrintable(c byte) bool {
+	return (c >= 0x20 && c <= 0x7e) || c == '\t' || c == '\n' || c == '\r'
+}
+
+// Printable returns true if pt is not empty and only holds printable ASCII or whitespace.
+func Printable(pt []byte) bool {
+	for _, c := range pt {
+		if !isPrintable(c) {
+			return false
+		}
+	}
+
+	return len(pt) > 0
+}
+
+// Score rates how likely pt is to be a meaningful plaintext. The ratio of printable bytes counts
+// for up to 0.6 and how much pt looks like words, letters and common English characters, for up to
+// 0.4. A match of the flag format regexp, which may be nil, adds 1 so it outranks any other text.
+func Score(pt []byte, flag *regexp.Regexp) float64 {
+	if len(pt) == 0 {
+		return 0
+	}
+
+	var printable, letters, common int
+	for _, c := range pt {
+		if !isPrintable(c) {
+			continue
+		}
+		printable++
+
+		if c == ' ' || (c|0x20 >= 'a' && c|0x20 <= 'z') {
+			letters++
+		}
+
+		if bytes.IndexByte([]byte(commonText), c) >= 0 {
+			common++
+		}
+	}
+
+	l := float64(len(pt))
+	s := 0.6*float64(printable)/l + 0.2*float64(letters)/l + 0.2*float64(common)/l
+	if flag != nil && flag.Match(pt) {
+		s++
+	}
+
+	return s
+}
+
+// Rank scores each distinct plaintext in pts and returns them best first. Equally scored
+// plaintexts keep their order.
+func Rank(pts [][]byte, flag *regexp.Regexp) []Candidate {
+	var cs []Candidate
+	for _, pt := range pts {
+		cs = addCandidate(cs, Candidate{PlainText: pt, Score: Score(pt, flag)})
+	}
+
+	return cs
+}
+
+// Add scores pts found by the attack called source and adds those not already in cs, keeping cs
+// ranked best first.
+func Add(cs []Candidate, source string, pts [][]byte, flag *regexp.Regexp) []Candidate {
+	for _, pt := range pts {
+		cs = addCandidate(cs, Candidate{PlainText: pt, Score: Score(pt, flag), Source: source})
+	}
+
+	return cs
+}
+
+// addCandidate inserts c into the ranked cs unless its plaintext is already there.
+func addCandidate(cs []Candidate, c Candidate) []Candidate {
+	for _, x := range cs {
+		if bytes.Equal(x.PlainText, c.PlainText) {
+			return cs
+		}
+	}
+
+	i := sort.Search(len(cs), func(i int) bool { return cs[i].Score < c.Score })
+	cs = append(cs, Candidate{})
+	copy(cs[i+1:], cs[i:])
+	cs[i] = c
+
+	return cs
+}
@@ -0,0 +1,87 @@
+package plaintext
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestScore(t *testing.T) {
+	flag := regexp.MustCompile(`flag\{.*\}`)
+
+	// Each plaintext should score higher than the next.
+	pts := []string{
+		"flag{x9_q}",
+		"the rain in spain stays mainly",
+		"x9_q7#@%!0&",
+		"\x00\x8f\x13\xfe",
+	}
+
+	for i := 1; i < len(pts); i++ {
+		hi, lo := Score([]byte(pts[i-1]), flag), Score([]byte(pts[i]), flag)
+		if hi <= lo {
+			t.Errorf("Score(%q) = %.2f want more than Score(%q) = %.2f", pts[i-1], hi, pts[i], lo)
+		}
+	}
+
+	if s := Score(nil, flag); s != 0 {
+		t.Errorf("Score(nil) = %.2f want 0", s)
+	}
+}
+
+func TestRank(t *testing.T) {
+	tt := []struct {
+		name string
+		pts  []string
+		flag *regexp.Regexp
+		want []string
+	}{
+		{
+			name: "printable text first",
+			pts:  []string{"\x01\x02\xff", "hello world", "\x01\x02\xff"},
+			want: []string{"hello world", "\x01\x02\xff"},
+		},
+		{
+			name: "flag format outranks text",
+			pts:  []string{"hello world", "\x01CTF{y}\xff"},
+			flag: regexp.MustCompile(`CTF\{.*\}`),
+			want: []string{"\x01CTF{y}\xff", "hello world"},
+		},
+		{
+			name: "equal scores keep their order",
+			pts:  []string{"abc", "cba"},
+			want: []string{"abc", "cba"},
+		},
+	}
+
+	for _, tc := range tt {
+		var pts [][]byte
+		for _, pt := range tc.pts {
+			pts = append(pts, []byte(pt))
+		}
+
+		got := Rank(pts, tc.flag)
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: Rank() got %d candidates want %d", tc.name, len(got), len(tc.want))
+			continue
+		}
+
+		for i, c := range got {
+			if string(c.PlainText) != tc.want[i] {
+				t.Errorf("%s: Rank() candidate %d got %q want %q", tc.name, i, c.PlainText, tc.want[i])
+			}
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	cs := Add(nil, "first", [][]byte{[]byte("\x01\x02"), []byte("some text")}, nil)
+	cs = Add(cs, "second", [][]byte{[]byte("some text"), []byte("more text!")}, nil)
+
+	if len(cs) != 3 {
+		t.Fatalf("Add() got %d candidates want 3", len(cs))
+	}
+
+	if string(cs[0].PlainText) != "some text" || cs[0].Source != "first" || cs[2].Source != "first" {
+		t.Errorf("Add() got %+v", cs)
+	}
+}
@@ -0,0 +1,170 @@
+// Package plaintext implements making sense of recovered RSA plaintexts, like removing their padding.
+package plaintext
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	// Register the hashes OAEP padding can be removed for.
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+)
+
+// Hashes maps the names accepted by ParseHashes to the hashes OAEP padding can be removed for.
+var Hashes = map[string]crypto.Hash{
+	"sha1":   crypto.SHA1,
+	"sha256": crypto.SHA256,
+	"sha512": crypto.SHA512,
+}
+
+// Options control how recovered plaintexts are interpreted.
+type Options struct {
+	// OAEPHashes are tried in turn when removing OAEP padding, every supported hash if empty.
+	OAEPHashes []crypto.Hash
+	// OAEPLabel is the label messages were OAEP padded with, usually empty.
+	OAEPLabel []byte
+}
+
+// ParseHashes returns the hashes named in the comma separated list s, e.g. "sha1,sha256".
+func ParseHashes(s string) ([]crypto.Hash, error) {
+	var hs []crypto.Hash
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		h, ok := Hashes[strings.ReplaceAll(name, "-", "")]
+		if !ok {
+			return nil, fmt.Errorf("unsupported hash %q, expected sha1, sha256 or sha512", name)
+		}
+		hs = append(hs, h)
+	}
+
+	return hs, nil
+}
+
+// Unpadded is the message found by removing the padding of a plaintext.
+type Unpadded struct {
+	// Padding names the padding scheme, e.g. "PKCS#1 v1.5" or "OAEP SHA-256".
+	Padding string
+	// Message is the message within the padding, it is nil if the padding is too malformed to find it.
+	Message []byte
+	// Warning describes what is wrong with malformed padding.
+	Warning string
+}
+
+// Unpad detects PKCS#1 v1.5 type 2 and OAEP padding of the plaintext pt of a k byte modulus and
+// returns the message within it. It returns nil if pt is not padded with either scheme.
+func Unpad(pt []byte, k int, o Options) *Unpadded {
+	if len(pt) > k {
+		return nil
+	}
+
+	// Plaintexts converted from integers have lost the leading zero bytes of the encoding.
+	em := make([]byte, k)
+	copy(em[k-len(pt):], pt)
+
+	hs := o.OAEPHashes
+	if len(hs) == 0 {
+		hs = []crypto.Hash{crypto.SHA1, crypto.SHA256, crypto.SHA512}
+	}
+
+	// OAEP is tried first since its label hash makes it unambiguous.
+	for _, h := range hs {
+		if u := unpadOAEP(em, h, o.OAEPLabel); u != nil {
+			return u
+		}
+	}
+
+	return unpadPKCS1v15(em)
+}
+
+// unpadPKCS1v15 removes PKCS#1 v1.5 encryption padding, 0x00 0x02 followed by at least 8 non-zero
+// random bytes and a 0x00 separator.
+func unpadPKCS1v15(em []byte) *Unpadded {
+	if len(em) < 11 || em[0] != 0 || em[1] != 2 {
+		return nil
+	}
+
+	u := &Unpadded{Padding: "PKCS#1 v1.5"}
+	sep := bytes.IndexByte(em[2:], 0)
+	switch {
+	case sep < 0:
+		u.Warning = "PKCS#1 v1.5 padding has no zero byte separating the message"
+	case sep < 8:
+		u.Warning = fmt.Sprintf("PKCS#1 v1.5 padding has %d random bytes, at least 8 are required", sep)
+		u.Message = em[2+sep+1:]
+	default:
+		u.Message = em[2+sep+1:]
+	}
+
+	return u
+}
+
+// unpadOAEP removes OAEP padding made with hash h and label. It returns nil unless the hash of the
+// label is found in the unmasked data block.
+func unpadOAEP(em []byte, h crypto.Hash, label []byte) *Unpadded {
+	if !h.Available() {
+		return nil
+	}
+
+	hl := h.Size()
+	if len(em) < 2*hl+2 || em[0] != 0 {
+		return nil
+	}
+
+	seed := append([]byte(nil), em[1:1+hl]...)
+	db := append([]byte(nil), em[1+hl:]...)
+	mgf1XOR(seed, h, db)
+	mgf1XOR(db, h, seed)
+
+	lh := h.New()
+	lh.Write(label)
+	if subtle.ConstantTimeCompare(db[:hl], lh.Sum(nil)) != 1 {
+		return nil
+	}
+
+	u := &Unpadded{Padding: "OAEP " + h.String()}
+	rest := db[hl:]
+	i := 0
+	for i < len(rest) && rest[i] == 0 {
+		i++
+	}
+
+	if i == len(rest) || rest[i] != 1 {
+		u.Warning = fmt.Sprintf("%s padding has no 0x01 byte separating the message", u.Padding)
+		return u
+	}
+	u.Message = rest[i+1:]
+
+	return u
+}
+
+// mgf1XOR XORs out with the MGF1 mask generated from seed using hash h.
+func mgf1XOR(out []byte, h crypto.Hash, seed []byte) {
+	var (
+		counter [4]byte
+		done    int
+	)
+
+	for i := uint32(0); done < len(out); i++ {
+		binary.BigEndian.PutUint32(counter[:], i)
+
+		d := h.New()
+		d.Write(seed)
+		d.Write(counter[:])
+		for _, b := range d.Sum(nil) {
+			if done == len(out) {
+				break
+			}
+			out[done] ^= b
+			done++
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package plaintext
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestUnpad(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// decrypt returns the raw plaintext of c as an attack recovering d would.
+	decrypt := func(c []byte) []byte {
+		return new(big.Int).Exp(new(big.Int).SetBytes(c), key.D, key.N).Bytes()
+	}
+
+	msg := []byte("flag{padding_oracles_are_fun}")
+	pkcs1, _ := rsa.EncryptPKCS1v15(rand.Reader, &key.PublicKey, msg)
+	oaepLabel, _ := rsa.EncryptOAEP(sha512.New(), rand.Reader, &key.PublicKey, msg, []byte("label"))
+
+	// Avoid the random masked seed starting like PKCS#1 v1.5 padding when OAEP isn't detected.
+	var oaep256 []byte
+	for len(oaep256) == 0 || decrypt(oaep256)[0] == 2 {
+		oaep256, _ = rsa.EncryptOAEP(sha256.New(), rand.Reader, &key.PublicKey, msg, nil)
+	}
+
+	// shortPS has only 4 bytes of random padding and noSep no zero byte, the leading zero byte of
+	// the encodings is lost as with a decrypted integer.
+	long := bytes.Repeat([]byte("A"), key.Size()-7)
+	shortPS := append([]byte{2, 1, 1, 1, 1, 0}, long...)
+	noSep := append([]byte{2}, bytes.Repeat([]byte{1}, key.Size()-2)...)
+
+	tt := []struct {
+		name string
+		pt   []byte
+		o    Options
+		want *Unpadded
+	}{
+		{
+			name: "pkcs1 v1.5",
+			pt:   decrypt(pkcs1),
+			want: &Unpadded{Padding: "PKCS#1 v1.5", Message: msg},
+		},
+		{
+			name: "oaep sha256",
+			pt:   decrypt(oaep256),
+			want: &Unpadded{Padding: "OAEP SHA-256", Message: msg},
+		},
+		{
+			name: "oaep sha512 with a label",
+			pt:   decrypt(oaepLabel),
+			o:    Options{OAEPLabel: []byte("label")},
+			want: &Unpadded{Padding: "OAEP SHA-512", Message: msg},
+		},
+		{
+			name: "oaep restricted to another hash",
+			pt:   decrypt(oaep256),
+			o:    Options{OAEPHashes: []crypto.Hash{crypto.SHA1}},
+		},
+		{
+			name: "pkcs1 v1.5 with short padding",
+			pt:   shortPS,
+			want: &Unpadded{Padding: "PKCS#1 v1.5", Message: long, Warning: "PKCS#1 v1.5 padding has 4 random bytes, at least 8 are required"},
+		},
+		{
+			name: "pkcs1 v1.5 without a separator",
+			pt:   noSep,
+			want: &Unpadded{Padding: "PKCS#1 v1.5", Warning: "PKCS#1 v1.5 padding has no zero byte separating the message"},
+		},
+		{
+			name: "unpadded",
+			pt:   msg,
+		},
+	}
+
+	for _, tc := range tt {
+		got := Unpad(tc.pt, key.Size(), tc.o)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: Unpad() got %+v want %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParseHashes(t *testing.T) {
+	got, err := ParseHashes("SHA-256, sha1")
+	if want := []crypto.Hash{crypto.SHA256, crypto.SHA1}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseHashes() got %v, %v want %v", got, err, want)
+	}
+
+	if _, err := ParseHashes("md5"); err == nil {
+		t.Error("ParseHashes(md5) expected error got nil")
+	}
+}
@@ -1,18 +1,25 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"strconv"
+	"os/signal"
+	"regexp"
+	"runtime"
 	"strings"
+	"time"
 
 	"github.com/sourcekris/goRsaTool/attacks"
+	_ "github.com/sourcekris/goRsaTool/attacks/all"
 	"github.com/sourcekris/goRsaTool/attacks/jwtmodulus"
 	"github.com/sourcekris/goRsaTool/attacks/signatures"
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
+	"github.com/sourcekris/goRsaTool/plaintext"
 	"github.com/sourcekris/goRsaTool/utils"
 
 	fmp "github.com/sourcekris/goflint"
@@ -21,60 +28,156 @@ import (
 var (
 	fset           = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
 	keyFile        = fset.String("key", "", "The filename of the RSA key to attack or dump")
+	scanFile       = fset.String("scan", "", "Carve RSA keys out of any file, e.g. a memory dump or firmware image, and attack the public keys found without a private key.")
 	pastPrimesFile = fset.String("pastprimes", "pastctfprimes.txt", "The filename of a file containing past CTF prime numbers.")
 	verboseMode    = fset.Bool("verbose", false, "Enable verbose output.")
 	dumpKeyMode    = fset.Bool("dumpkey", false, "Just dump the RSA integers from a key - n,e,d,p,q.")
 	createKeyMode  = fset.Bool("createkey", false, "Create a public key given an E and N.")
+	outFormat      = fset.String("outformat", "", "Format of the keys printed: pkcs1, pkcs8, openssh, der or jwk. Defaults to pkcs1.")
 	exponentArg    = fset.String("e", "", "The exponent value.")
 	modulusArg     = fset.String("n", "", "The modulus value.")
 	cArg           = fset.String("c", "", "An integer ciphertext.")
 	primeArg       = fset.String("p", "", "One of the primes. If provided will shortcut the attack phase and produce a private key.")
 	dArg           = fset.String("d", "", "Give d in createkey mode to create a private key.")
 	d0Arg          = fset.String("d0", "", "Give LSBs of d, used in partiald attacks.")
-	cipherText     = fset.String("ciphertext", "", "An RSA encrypted binary file to decrypt, necessary for certain attacks.")
 	numP           = fset.Int("numprimes", 2, "Number of primes expected to be factored.")
 	keyList        = fset.String("keylist", "", "Comma seperated list of keys for multi-key attacks.")
 	ctList         = fset.String("ctlist", "", "Comma seperated list of ciphertext binaries for multi-key attacks.")
 	ptList         = fset.String("ptlist", "", "Comma sepereated list of plaintext files for use in signature mode.")
 	sigList        = fset.String("siglist", "", "Comma seperated list of signatures files.")
+	oaepHash       = fset.String("oaephash", "", "Comma seperated hashes tried when removing OAEP padding from plaintexts: sha1, sha256 or sha512. Defaults to all of them.")
+	oaepLabel      = fset.String("oaeplabel", "", "The label plaintexts were OAEP padded with.")
+	flagFormat     = fset.String("flagformat", "", "Regexp matching the expected plaintext, e.g. 'flag\\{.*\\}', used to rank plaintext candidates.")
+	ctFormat       = fset.String("ctformat", "auto", "Encoding of -ciphertext, -ctlist, -siglist and -ptlist files: auto, raw, hex, base64 or decimal. -ptlist files are read raw unless it is given.")
 	jwtList        = fset.String("jwtlist", "", "Comma seperated list of files containing JWTs.")
 	hintList       = fset.String("hintlist", "", "Comma seperated list of hints.")
-	bruteMax       = fset.String("brutemax", "4096", "Maximum value for brute force related attacks (e.g. apbq attack).")
+	bruteMax       = fset.String("brutemax", "", "Maximum value for brute force related attacks, the same as -opt apbq.brutemax=N.")
 	attack         = fset.String("attack", "all", "Specific attack to try. Specify \"all\" for everything that works unnatended.")
 	list           = fset.Bool("list", false, "List the attacks supported by the attack flag.")
+	describe       = fset.String("describe", "", "Describe the named attack in full.")
+	checkpointFile = fset.String("checkpoint", "", "Periodically save the progress of long running attacks to this file.")
+	resumeFile     = fset.String("resume", "", "Resume long running attacks from a file written by -checkpoint, progress continues to be saved to it.")
+	progress       = fset.Duration("progress", 0, "Print the progress of running attacks at this interval, e.g. 10s. Factoring searches and Wiener attacks count their work, others report their running time. Defaults to 10s with -verbose.")
+	plan           = fset.Bool("plan", false, "Print the attack plan for -attack all before running it.")
+	jsonOut        = fset.Bool("json", false, "Print the results as one JSON document on stdout, logs go to stderr.")
+	workers        = fset.Int("workers", runtime.NumCPU(), "Number of attacks to run concurrently when using -attack all.")
 	logger         *log.Logger
+	opts           optionList
+	cipherTexts    optionList
 )
 
+func init() {
+	fset.Var(&opts, "opt", "Set an attack parameter, e.g. -opt pollardsp1.b1=1000000. May be repeated. Use -describe to list an attack's parameters.")
+	fset.Var(&cipherTexts, "ciphertext", "An RSA encrypted binary file to decrypt, necessary for certain attacks. May be repeated for several ciphertexts under the key.")
+}
+
+// optionList collects the values of a repeated flag.
+type optionList []string
+
+func (o *optionList) String() string {
+	return strings.Join(*o, ",")
+}
+
+func (o *optionList) Set(v string) error {
+	*o = append(*o, v)
+	return nil
+}
+
 // unnatended will run all supported attacks against t that are listed as working in unnatended mode.
-func unnatended(t []*keys.RSA) []error {
-	var errs []error
-	for _, a := range attacks.SupportedAttacks.Supported {
-		if a.Unnatended {
-			if err := attacks.SupportedAttacks.Execute(a.Name, t); err != nil {
-				errs = append(errs, err)
-			}
-			for _, k := range t {
-				if k.Key.D != nil {
-					if k.Verbose {
-						logger.Printf("key factored with attack: %v\n", a.Name)
-					}
-					return nil
-				}
-			}
+// The attacks are planned from the features of t and run concurrently, the first one to recover the
+// key cancels the rest.
+func unnatended(ctx context.Context, t []*keys.RSA) (*attacks.Report, []error) {
+	p := attacks.SupportedAttacks.Plan(t)
+	if *plan {
+		fmt.Fprint(output(), p)
+	}
+
+	r := attacks.SupportedAttacks.ExecutePlan(ctx, p, t, *workers)
+	logger.Print(r)
+
+	if r.Winner != "" {
+		return r, nil
+	}
+
+	return r, r.Errors()
+}
+
+// execute runs the named attack against t and reports how it fared.
+func execute(ctx context.Context, name string, t []*keys.RSA) (*attacks.Report, error) {
+	start := time.Now()
+	err := attacks.SupportedAttacks.Execute(ctx, name, t)
+
+	r := &attacks.Report{Outcomes: []*attacks.Outcome{{Name: name, Err: err, Duration: time.Since(start), Started: true}}}
+	if err == nil && attacks.Solved(t) {
+		r.Winner = name
+	}
+
+	return r, err
+}
+
+// output returns where results meant for humans are printed, stderr when stdout is reserved for
+// the -json document.
+func output() io.Writer {
+	if *jsonOut {
+		return os.Stderr
+	}
+
+	return os.Stdout
+}
+
+// printDocument prints the -json document of ks and the attack report r, which may be nil, along
+// with errs.
+func printDocument(ks []*keys.RSA, r *attacks.Report, f keys.Format, o plaintext.Options, errs ...error) {
+	doc := utils.NewDocument(ks, r, f, o)
+	for _, err := range errs {
+		if err != nil {
+			doc.Errors = append(doc.Errors, err.Error())
 		}
 	}
 
-	return errs
+	if err := utils.PrintDocument(os.Stdout, doc); err != nil {
+		logger.Fatal(err)
+	}
+}
+
+// openCheckpoint returns the checkpoint requested with -resume or -checkpoint, or nil if neither
+// was given.
+func openCheckpoint() (*attacks.Checkpoint, error) {
+	switch {
+	case *resumeFile != "":
+		return attacks.LoadCheckpoint(*resumeFile)
+	case *checkpointFile != "":
+		return attacks.NewCheckpoint(*checkpointFile), nil
+	}
+
+	return nil, nil
 }
 
-// listAttacks returns a string containing the list of registered attacks.
-func listAttacks() string {
-	var res string
-	for _, a := range attacks.SupportedAttacks.Supported {
-		res = fmt.Sprintf("%s%s\n", res, a.Name)
+// showProgress makes attacks run with the returned context report their progress and logs a status
+// line for each running attack every interval until the returned function is called.
+func showProgress(ctx context.Context, interval time.Duration) (context.Context, func()) {
+	if interval <= 0 {
+		return ctx, func() {}
 	}
 
-	return res
+	m := attacks.NewMonitor()
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				for _, s := range m.Status() {
+					logger.Print(s)
+				}
+			}
+		}
+	}()
+
+	return attacks.WithMonitor(ctx, m), func() { close(done) }
 }
 
 // fileList returns a list of filenames or nil.
@@ -89,6 +192,47 @@ func fileList(fl string) []string {
 	return nil
 }
 
+// scanKeys prints where each RSA key carved out of file f was found. It returns one public key per
+// modulus found without its private key and the private keys found.
+func scanKeys(f string) ([]*keys.RSA, []*keys.RSA, error) {
+	b, err := os.ReadFile(f)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cs := keys.Scan(b)
+	if len(cs) == 0 {
+		return nil, nil, fmt.Errorf("no RSA keys found in %s", f)
+	}
+
+	private := make(map[string]bool)
+	for _, c := range cs {
+		fmt.Fprintln(output(), c)
+		if c.Key.Key.D != nil {
+			private[c.Key.Key.N.String()] = true
+		}
+	}
+
+	var pub, priv []*keys.RSA
+	for _, c := range cs {
+		k := c.Key
+		k.KeyFilename = f
+
+		n := k.Key.N.String()
+		if k.Key.D == nil {
+			if !private[n] {
+				pub = append(pub, k)
+				private[n] = true
+			}
+			continue
+		}
+
+		priv = append(priv, k)
+	}
+
+	return pub, priv, nil
+}
+
 func createKeyFromArgs() (*keys.RSA, error) {
 	var cliCt []byte
 	if *cArg != "" {
@@ -109,6 +253,10 @@ func createKeyFromArgs() (*keys.RSA, error) {
 func main() {
 	fset.Parse(os.Args[1:])
 
+	// Cancel any running attacks on Ctrl-C.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
 	var useFlagsForKey bool
 
 	logger = log.New(os.Stderr, "rsatool: ", log.Lshortfile)
@@ -117,8 +265,58 @@ func main() {
 		logger.Println("starting up...")
 	}
 
+	if *bruteMax != "" {
+		opts = append(optionList{"apbq.brutemax=" + *bruteMax}, opts...)
+	}
+
+	for _, o := range opts {
+		if err := attacks.SupportedAttacks.SetOption(o); err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	keyFormat, err := keys.ParseFormat(*outFormat)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	ctEncoding, err := utils.ParseEncoding(*ctFormat)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	// Plaintexts may well be all digits or valid hex so they are only decoded when asked to.
+	ptEncoding := utils.EncodingRaw
+	fset.Visit(func(f *flag.Flag) {
+		if f.Name == "ctformat" {
+			ptEncoding = ctEncoding
+		}
+	})
+
+	oaepHashes, err := plaintext.ParseHashes(*oaepHash)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	ptOptions := plaintext.Options{OAEPHashes: oaepHashes, OAEPLabel: []byte(*oaepLabel)}
+
+	var flagRE *regexp.Regexp
+	if *flagFormat != "" {
+		if flagRE, err = regexp.Compile(*flagFormat); err != nil {
+			logger.Fatalf("invalid -flagformat: %v", err)
+		}
+	}
+
 	if *list {
-		fmt.Print(listAttacks())
+		fmt.Print(attacks.SupportedAttacks.Table())
+		return
+	}
+
+	if *describe != "" {
+		at := attacks.SupportedAttacks.Lookup(*describe)
+		if at == nil {
+			logger.Fatalf("unsupported attack: %v. Use -list to see a list of supported attacks", *describe)
+		}
+		fmt.Print(at.Describe())
 		return
 	}
 
@@ -134,17 +332,47 @@ func main() {
 		klist = append(klist, *keyFile)
 	}
 
+	// Attack the public keys carved out of the -scan file, there is nothing left to do if only
+	// private keys were found.
+	var scanned, scannedPriv []*keys.RSA
+	if *scanFile != "" {
+		scanned, scannedPriv, err = scanKeys(*scanFile)
+		if err != nil {
+			logger.Fatal(err)
+		}
+
+		if !*jsonOut {
+			for _, k := range scannedPriv {
+				fmt.Printf("%s:\n", k.Name())
+				if err := utils.PrintPrivateKey(&k.Key, keyFormat); err != nil {
+					fmt.Printf("failed encoding the private key: %v\n", err)
+				}
+			}
+		}
+
+		if len(scanned) == 0 && klist == nil {
+			if *jsonOut {
+				printDocument(scannedPriv, nil, keyFormat, ptOptions)
+			}
+			return
+		}
+
+		if len(scanned) > 0 {
+			klist = append(klist, *scanFile)
+		}
+	}
+
 	// If no key file or key file list are provided, do we have n and e to make a key up on the fly with?
-	if klist == nil {
+	if klist == nil && !*createKeyMode {
 		if *modulusArg != "" && *exponentArg != "" {
 			klist = []string{"ignored"}
 			useFlagsForKey = true
 		}
 	}
 
-	// We need klist and clist to be the same length for now.
+	// We need klist and clist to be the same length for now, one ciphertext per key argument.
 	if len(klist) > 0 && len(clist) > 0 && len(klist) != len(clist) {
-		log.Fatalf("when using -keylist and -ctlist there should be the same number of files in each list")
+		log.Fatalf("when using -keylist and -ctlist there should be one ciphertext file for each key file, got %d key files and %d ciphertext files", len(klist), len(clist))
 	}
 
 	// We got one or more keys to work on, so lets do that.
@@ -152,140 +380,202 @@ func main() {
 		var rsaKeys []*keys.RSA
 		for i, kf := range klist {
 			var (
-				targetRSA *keys.RSA
+				imported  []*keys.RSA
 				nonPemKey bool
 				err       error
 			)
 
-			if !useFlagsForKey {
+			if len(scanned) > 0 && kf == *scanFile {
+				imported = scanned
+			} else if !useFlagsForKey {
 				kb, err := os.ReadFile(kf)
 				if err != nil {
 					log.Fatal(err)
 				}
-				targetRSA, err = keys.ImportKey(kb)
+				// A PEM key or an OpenSSH file, which may hold several keys.
+				imported, err = keys.ImportKeys(kb)
 				if err != nil {
-					// Failed to read a valid PEM key. Maybe it is an integer list type key?
-					targetRSA, err = keys.ImportIntegerList(kb)
-					if err != nil {
-						logger.Fatalf("failed reading key file: %v", err)
+					// Failed to read a valid PEM or OpenSSH key. Maybe it is an integer list type key?
+					il, ilErr := keys.ParseIntegerList(kb)
+					if ilErr != nil {
+						logger.Fatalf("failed reading key file: %v, or as an integer list: %v", err, ilErr)
 					}
 
+					if *verboseMode {
+						logger.Printf("%s: integer list %v", kf, il)
+					}
+
+					targetRSA, ilErr := il.Key()
+					if ilErr != nil {
+						logger.Fatalf("failed reading key file: %v, or as an integer list: %v", err, ilErr)
+					}
+
+					imported = []*keys.RSA{targetRSA}
 					nonPemKey = true
 				}
 			} else {
 				// Also include c if it is provided on command line.
-				var cliCt []byte
-				if *cArg != "" {
-					cliCt = ln.NumberToBytes(ln.FmpString(*cArg))
-				}
-				targetRSA, err = keys.NewRSA(
-					keys.PrivateFromPublic(
-						&keys.FMPPublicKey{
-							N: ln.FmpString(*modulusArg),
-							E: ln.FmpString(*exponentArg),
-						}), cliCt, nil, "", false)
+				targetRSA, err := createKeyFromArgs()
 				if err != nil {
-					logger.Fatalf("failed converting modulus and exponent into an RSA key: %v", err)
+					logger.Fatal(err)
 				}
-			}
-
-			targetRSA.PastPrimesFile = *pastPrimesFile
-			targetRSA.Verbose = *verboseMode
 
-			var (
-				c  []byte
-				cf string
-			)
+				imported = []*keys.RSA{targetRSA}
+			}
 
+			// -ctlist pairs a ciphertext with each key argument, not with each key imported from it,
+			// so every key in a file holding several gets the same ciphertexts.
+			var cfs []string
 			switch {
-			case *cipherText != "":
-				cf = *cipherText
+			case cipherTexts != nil:
+				cfs = cipherTexts
 			case clist != nil:
-				cf = clist[i]
+				cfs = clist[i : i+1]
 			}
 
-			if cf != "" {
-				c, err = utils.ReadCipherText(cf)
+			var cts [][]byte
+			for _, cf := range cfs {
+				c, err := utils.ReadCipherText(cf, ctEncoding)
 				if err != nil {
 					logger.Fatalf("failed reading ciphertext file: %v", err)
 				}
 
-				targetRSA.CipherText = c
+				cts = append(cts, c)
 			}
 
-			if *d0Arg != "" {
-				d0, ok := new(fmp.Fmpz).SetString(*d0Arg, 0)
-				if !ok {
-					logger.Fatal("failed parsing -d0 flag as an integer")
-				}
+			for _, targetRSA := range imported {
+				targetRSA.PastPrimesFile = *pastPrimesFile
+				targetRSA.Verbose = *verboseMode
+				targetRSA.FlagFormat = flagRE
 
-				targetRSA.DLSB = d0.Bytes()
-			}
+				// Ciphertext files replace any ciphertexts given in the key file.
+				if cts != nil {
+					targetRSA.CipherText, targetRSA.CipherTexts = nil, nil
+				}
 
-			if *primeArg != "" {
-				p, ok := new(fmp.Fmpz).SetString(*primeArg, 0)
-				if !ok {
-					logger.Fatal("failed parsing -p flag as an integer")
+				for _, c := range cts {
+					targetRSA.AddCipherText(append([]byte(nil), c...))
 				}
 
-				targetRSA.Key.Primes = append(targetRSA.Key.Primes, p)
-			}
+				if *d0Arg != "" {
+					d0, ok := new(fmp.Fmpz).SetString(*d0Arg, 0)
+					if !ok {
+						logger.Fatal("failed parsing -d0 flag as an integer")
+					}
 
-			if *bruteMax != "" {
-				bm, err := strconv.Atoi(*bruteMax)
-				if err != nil {
-					logger.Fatal("failed parsing -brutemax as an integer")
+					targetRSA.DLSB = d0.Bytes()
 				}
-				targetRSA.BruteMax = int64(bm)
-			}
 
-			if *hintList != "" {
-				hints := strings.Split(*hintList, ",")
-				if len(hints) == 0 {
-					logger.Fatal("expected at least 1 hint when -hintlist specified")
+				if *primeArg != "" {
+					p, ok := new(fmp.Fmpz).SetString(*primeArg, 0)
+					if !ok {
+						logger.Fatal("failed parsing -p flag as an integer")
+					}
+
+					targetRSA.Key.Primes = append(targetRSA.Key.Primes, p)
 				}
 
-				for _, hint := range hints {
-					targetRSA.Hints = append(targetRSA.Hints, ln.FmpString(hint))
+				if *hintList != "" {
+					hints := strings.Split(*hintList, ",")
+					if len(hints) == 0 {
+						logger.Fatal("expected at least 1 hint when -hintlist specified")
+					}
+
+					for _, hint := range hints {
+						targetRSA.Hints = append(targetRSA.Hints, ln.FmpString(hint))
+					}
 				}
-			}
 
-			// Add the key filename, logger and expected number of primes to the key.
-			targetRSA.KeyFilename = kf
-			targetRSA.Log = logger
-			targetRSA.NumPrimes = *numP
+				// Add the key filename, logger and expected number of primes to the key.
+				targetRSA.KeyFilename = kf
+				targetRSA.Log = logger
+				targetRSA.NumPrimes = *numP
 
-			if *dumpKeyMode {
-				targetRSA.DumpKey()
+				if *dumpKeyMode && !*jsonOut {
+					// Keep stdout clean for binary keys so they can be redirected to a file.
+					if !keyFormat.Binary() {
+						targetRSA.DumpKey()
+					}
 
-				if nonPemKey && targetRSA.Key.PublicKey.E != nil {
-					// The input was an integer list key so the user might actually want a PEM dump.
-					fmt.Println(keys.EncodeFMPPublicKey(targetRSA.Key.PublicKey))
+					switch {
+					case *outFormat != "" && targetRSA.Key.D != nil && len(targetRSA.Key.Primes) > 1:
+						// Convert the private key to the requested format.
+						err = utils.PrintPrivateKey(&targetRSA.Key, keyFormat)
+					case (nonPemKey || *outFormat != "") && targetRSA.Key.PublicKey.E != nil:
+						// The input was an integer list key so the user might actually want a PEM dump.
+						err = utils.PrintPublicKey(targetRSA.Key.PublicKey, keyFormat)
+					}
+					if err != nil {
+						logger.Fatalf("failed encoding key: %v", err)
+					}
 				}
-			}
 
-			rsaKeys = append(rsaKeys, targetRSA)
+				rsaKeys = append(rsaKeys, targetRSA)
+			}
 		}
 
 		if *dumpKeyMode {
+			if *jsonOut {
+				printDocument(rsaKeys, nil, keyFormat, ptOptions)
+			}
+
 			// Job done.
 			return
 		}
 
-		var errs []error
+		cp, err := openCheckpoint()
+		if err != nil {
+			logger.Fatal(err)
+		}
+		if cp != nil {
+			ctx = attacks.WithCheckpoint(ctx, cp)
+		}
+
+		interval := *progress
+		if interval == 0 && *verboseMode {
+			interval = 10 * time.Second
+		}
+		ctx, stopProgress := showProgress(ctx, interval)
+
+		var (
+			errs   []error
+			report *attacks.Report
+		)
 		switch {
 		case *attack == "all" && *primeArg != "":
-			errs = append(errs, attacks.SupportedAttacks.Execute("knownprime", rsaKeys))
+			r, err := execute(ctx, "knownprime", rsaKeys)
+			report, errs = r, append(errs, err)
 		case *attack == "all":
-			errs = unnatended(rsaKeys)
+			report, errs = unnatended(ctx, rsaKeys)
 		case attacks.SupportedAttacks.IsSupported(*attack):
 			if *keyList != "" && !attacks.SupportedAttacks.SupportsMulti(*attack) {
 				logger.Println("-keylist flag used for attack that does not support multikeys - only the first key will be attacked.")
 			}
-			errs = append(errs, attacks.SupportedAttacks.Execute(*attack, rsaKeys))
+			r, err := execute(ctx, *attack, rsaKeys)
+			report, errs = r, append(errs, err)
 		default:
 			errs = []error{fmt.Errorf("unsupported attack: %v. Use -list to see a list of supported attacks", *attack)}
 		}
+		stopProgress()
+
+		if cp != nil {
+			if err := cp.Err(); err != nil {
+				logger.Println(err)
+			} else if cp.Saved() {
+				logger.Printf("progress saved, continue with -resume %s", cp.Path())
+			}
+		}
+
+		// The document reports failed attacks too, the errors are logged below.
+		if *jsonOut {
+			printDocument(append(scannedPriv, rsaKeys...), report, keyFormat, ptOptions, errs...)
+		}
+
+		// Were we able to solve for any of the private keys or ciphertexts? This goes before the
+		// errors so that plaintext candidates are still printed when no attack won.
+		if !*jsonOut {
+			utils.ReportResults(rsaKeys, keyFormat, ptOptions)
+		}
 
 		for _, e := range errs {
 			if e != nil {
@@ -293,15 +583,25 @@ func main() {
 			}
 		}
 
-		// Were we able to solve for any of the private keys or ciphertexts?
-		utils.ReportResults(rsaKeys)
-
 		return
 	}
 
 	// Recover a modulus from signatures and plaintexts.
 	if siglist != nil && ptlist != nil {
-		if err := signatures.Attack(ptlist, siglist, *exponentArg); err != nil {
+		if *jsonOut {
+			start := time.Now()
+			k, err := signatures.Recover(ptlist, siglist, *exponentArg, ctEncoding, ptEncoding)
+			if err != nil {
+				logger.Fatalf("failed recovering modulus: %v", err)
+			}
+
+			k.KeyFilename = strings.Join(siglist, ",")
+			k.Results = []*keys.Result{{Attack: "signatures", N: k.Key.N, Duration: time.Since(start)}}
+			printDocument([]*keys.RSA{k}, nil, keyFormat, ptOptions)
+			return
+		}
+
+		if err := signatures.Attack(ptlist, siglist, *exponentArg, keyFormat, ctEncoding, ptEncoding); err != nil {
 			logger.Fatalf("failed recovering modulus: %v", err)
 		}
 
@@ -311,7 +611,20 @@ func main() {
 
 	// Recover a modulus from two JWTs.
 	if jwtlist != nil {
-		if err := jwtmodulus.Attack(jwtlist, *exponentArg); err != nil {
+		if *jsonOut {
+			start := time.Now()
+			k, err := jwtmodulus.Recover(jwtlist, *exponentArg)
+			if err != nil {
+				logger.Fatalf("failed recovering modulus: %v", err)
+			}
+
+			k.KeyFilename = strings.Join(jwtlist, ",")
+			k.Results = []*keys.Result{{Attack: "jwtmodulus", N: k.Key.N, Duration: time.Since(start)}}
+			printDocument([]*keys.RSA{k}, nil, keyFormat, ptOptions)
+			return
+		}
+
+		if err := jwtmodulus.Attack(jwtlist, *exponentArg, keyFormat); err != nil {
 			logger.Fatalf("failed recovering modulus: %v", err)
 		}
 
@@ -320,7 +633,7 @@ func main() {
 	}
 
 	if *createKeyMode {
-		if err := utils.EncodeAndPrintKey(*modulusArg, *exponentArg, *dArg); err != nil {
+		if err := utils.EncodeAndPrintKey(*modulusArg, *exponentArg, *dArg, keyFormat); err != nil {
 			logger.Fatal(err)
 		}
 
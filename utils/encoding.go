@@ -0,0 +1,171 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sourcekris/goRsaTool/ln"
+	fmp "github.com/sourcekris/goflint"
+)
+
+// Encoding is how a ciphertext, signature or plaintext is stored in a file.
+type Encoding string
+
+const (
+	// EncodingAuto detects hex, base64 and decimal text and reads anything else as raw bytes.
+	EncodingAuto Encoding = "auto"
+	// EncodingRaw is the exact bytes of the file.
+	EncodingRaw Encoding = "raw"
+	// EncodingHex is hex text, optionally prefixed with 0x.
+	EncodingHex Encoding = "hex"
+	// EncodingBase64 is standard or URL safe base64 text, with or without padding.
+	EncodingBase64 Encoding = "base64"
+	// EncodingDecimal is a base 10 integer.
+	EncodingDecimal Encoding = "decimal"
+)
+
+// Encodings lists the supported encodings.
+var Encodings = []Encoding{EncodingAuto, EncodingRaw, EncodingHex, EncodingBase64, EncodingDecimal}
+
+var (
+	decimalRE = regexp.MustCompile(`^[0-9]+$`)
+	hexRE     = regexp.MustCompile(`^(?:0[xX])?[0-9a-fA-F]+$`)
+	base64RE  = regexp.MustCompile(`^[A-Za-z0-9+/_-]+={0,2}$`)
+)
+
+// minBase64Len is the shortest text detected as base64, shorter text is more likely a word.
+const minBase64Len = 16
+
+// ParseEncoding returns the encoding called s, an empty string is EncodingAuto.
+func ParseEncoding(s string) (Encoding, error) {
+	if s == "" {
+		return EncodingAuto, nil
+	}
+
+	for _, e := range Encodings {
+		if strings.EqualFold(s, string(e)) {
+			return e, nil
+		}
+	}
+
+	var es []string
+	for _, e := range Encodings {
+		es = append(es, string(e))
+	}
+
+	return "", fmt.Errorf("unsupported encoding %q, expected one of %s", s, strings.Join(es, ", "))
+}
+
+// isText returns true if b only holds printable ASCII and whitespace.
+func isText(b []byte) bool {
+	for _, c := range b {
+		if (c < 0x20 || c > 0x7e) && c != '\t' && c != '\n' && c != '\r' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// decodeBase64 decodes standard or URL safe base64 with or without padding.
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(s, "=")
+	if strings.ContainsAny(s, "-_") {
+		return base64.RawURLEncoding.DecodeString(s)
+	}
+
+	return base64.RawStdEncoding.DecodeString(s)
+}
+
+// Detect returns the encoding of b, EncodingRaw unless b is text that is entirely hex, base64 or a
+// decimal integer. Digits alone are taken as decimal.
+func Detect(b []byte) Encoding {
+	if !isText(b) {
+		return EncodingRaw
+	}
+
+	s := strings.Join(strings.Fields(string(b)), "")
+	switch {
+	case s == "":
+		return EncodingRaw
+	case decimalRE.MatchString(s):
+		return EncodingDecimal
+	case hexRE.MatchString(s):
+		return EncodingHex
+	case len(s) >= minBase64Len && base64RE.MatchString(s):
+		if _, err := decodeBase64(s); err == nil {
+			return EncodingBase64
+		}
+	}
+
+	return EncodingRaw
+}
+
+// Decode returns the bytes encoded in b. Whitespace around and within textual encodings is ignored
+// while raw bytes are returned exactly.
+func Decode(b []byte, enc Encoding) ([]byte, error) {
+	if enc == EncodingAuto {
+		enc = Detect(b)
+	}
+
+	s := strings.Join(strings.Fields(string(b)), "")
+	switch enc {
+	case EncodingRaw:
+		return b, nil
+	case EncodingHex:
+		s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+		if len(s)%2 == 1 {
+			s = "0" + s
+		}
+
+		d, err := hex.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("failed decoding hex: %v", err)
+		}
+
+		return d, nil
+	case EncodingBase64:
+		d, err := decodeBase64(s)
+		if err != nil {
+			return nil, fmt.Errorf("failed decoding base64: %v", err)
+		}
+
+		return d, nil
+	case EncodingDecimal:
+		z, ok := new(fmp.Fmpz).SetString(s, 10)
+		if !ok || !decimalRE.MatchString(s) {
+			return nil, fmt.Errorf("failed decoding %q as a decimal integer", truncate(s))
+		}
+
+		return ln.NumberToBytes(z), nil
+	}
+
+	return nil, fmt.Errorf("unsupported encoding %q", enc)
+}
+
+// truncate shortens s for error messages.
+func truncate(s string) string {
+	if len(s) > 20 {
+		return s[:20] + "..."
+	}
+
+	return s
+}
+
+// ReadEncoded reads the file called name and decodes it from enc.
+func ReadEncoded(name string, enc Encoding) ([]byte, error) {
+	b, err := ReadBinary(name)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := Decode(b, enc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	return d, nil
+}
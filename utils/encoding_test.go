@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	// want is the 3 byte ciphertext 0x01ff0a used by every textual encoding below.
+	want := []byte{0x01, 0xff, 0x0a}
+
+	tt := []struct {
+		name    string
+		in      string
+		enc     Encoding
+		want    []byte
+		wantEnc Encoding
+		wantErr bool
+	}{
+		{
+			name:    "raw bytes ending in a newline are kept",
+			in:      "\x01\xff\x0a",
+			enc:     EncodingAuto,
+			want:    want,
+			wantEnc: EncodingRaw,
+		},
+		{
+			name:    "hex",
+			in:      "01ff0a\n",
+			enc:     EncodingAuto,
+			want:    want,
+			wantEnc: EncodingHex,
+		},
+		{
+			name:    "prefixed odd length hex",
+			in:      "0x1ff0a",
+			enc:     EncodingAuto,
+			want:    want,
+			wantEnc: EncodingHex,
+		},
+		{
+			name:    "decimal",
+			in:      " 130826\r\n",
+			enc:     EncodingAuto,
+			want:    want,
+			wantEnc: EncodingDecimal,
+		},
+		{
+			name:    "base64 wrapped over lines",
+			in:      "AAAAAAAAAAAAAAAA\nAAAAAf8K\n",
+			enc:     EncodingAuto,
+			want:    append(make([]byte, 15), want...),
+			wantEnc: EncodingBase64,
+		},
+		{
+			name:    "short base64 is a word",
+			in:      "Af8K",
+			enc:     EncodingAuto,
+			want:    []byte("Af8K"),
+			wantEnc: EncodingRaw,
+		},
+		{
+			name:    "text is raw",
+			in:      "The secret password is moo.",
+			enc:     EncodingAuto,
+			want:    []byte("The secret password is moo."),
+			wantEnc: EncodingRaw,
+		},
+		{
+			name: "explicit base64",
+			in:   "Af8K",
+			enc:  EncodingBase64,
+			want: want,
+		},
+		{
+			name: "explicit raw hex",
+			in:   "01ff0a",
+			enc:  EncodingRaw,
+			want: []byte("01ff0a"),
+		},
+		{
+			name:    "invalid hex",
+			in:      "01fg",
+			enc:     EncodingHex,
+			wantErr: true,
+		},
+		{
+			name:    "invalid decimal",
+			in:      "0x10",
+			enc:     EncodingDecimal,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		if tc.wantEnc != "" {
+			if got := Detect([]byte(tc.in)); got != tc.wantEnc {
+				t.Errorf("%s: Detect() got %q want %q", tc.name, got, tc.wantEnc)
+			}
+		}
+
+		got, err := Decode([]byte(tc.in), tc.enc)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%s: Decode() expected error got nil", tc.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: Decode() failed: %v", tc.name, err)
+			continue
+		}
+
+		if !bytes.Equal(got, tc.want) {
+			t.Errorf("%s: Decode() got %x want %x", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParseEncoding(t *testing.T) {
+	for in, want := range map[string]Encoding{"": EncodingAuto, "HEX": EncodingHex, "raw": EncodingRaw} {
+		if got, err := ParseEncoding(in); err != nil || got != want {
+			t.Errorf("ParseEncoding(%q) got %q, %v want %q", in, got, err, want)
+		}
+	}
+
+	if _, err := ParseEncoding("base32"); err == nil {
+		t.Errorf("ParseEncoding(%q) expected error got nil", "base32")
+	}
+}
+
+func TestReadBinary(t *testing.T) {
+	want := []byte("\x00binary\r\n")
+	f := filepath.Join(t.TempDir(), "ct.bin")
+	if err := os.WriteFile(f, want, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadBinary(f)
+	if err != nil || !bytes.Equal(got, want) {
+		t.Errorf("ReadBinary() got %q, %v want %q", got, err, want)
+	}
+}
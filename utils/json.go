@@ -0,0 +1,261 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/sourcekris/goRsaTool/attacks"
+	"github.com/sourcekris/goRsaTool/keys"
+	"github.com/sourcekris/goRsaTool/plaintext"
+
+	fmp "github.com/sourcekris/goflint"
+)
+
+// Document is the machine readable report of a run printed with -json.
+type Document struct {
+	Keys []*KeyDocument `json:"keys"`
+	// Winner is the attack that recovered a private key or plaintext.
+	Winner string `json:"winner,omitempty"`
+	// Attacks are the attacks tried, in the order they were planned.
+	Attacks []*AttackDocument `json:"attacks,omitempty"`
+	Errors  []string          `json:"errors,omitempty"`
+}
+
+// KeyDocument describes an input key and what was recovered about it. Integers are in decimal.
+type KeyDocument struct {
+	// ID identifies the input key, its file name and label.
+	ID      string `json:"id"`
+	Subject string `json:"subject,omitempty"`
+	Issuer  string `json:"issuer,omitempty"`
+	N       string `json:"n,omitempty"`
+	E       string `json:"e,omitempty"`
+	// RecoveredN is set when an attack recovered the modulus.
+	RecoveredN string   `json:"recovered_n,omitempty"`
+	Primes     []string `json:"primes,omitempty"`
+	D          string   `json:"d,omitempty"`
+	CRT        *CRT     `json:"crt,omitempty"`
+	// PublicKey and PrivateKey are encoded in the -outformat format, base64 for binary formats.
+	PublicKey  string               `json:"public_key,omitempty"`
+	PrivateKey string               `json:"private_key,omitempty"`
+	PlainTexts []*PlainTextDocument `json:"plaintexts,omitempty"`
+	// Candidates are the other plaintext candidates attacks found, the most likely first.
+	Candidates []*CandidateDocument `json:"candidates,omitempty"`
+	Results    []*ResultDocument    `json:"results,omitempty"`
+	// Error describes why a recovered key could not be encoded.
+	Error string `json:"error,omitempty"`
+}
+
+// CRT holds the PKCS#1 CRT values of a private key, imported keys may lack some of them.
+type CRT struct {
+	Dp   string `json:"dp,omitempty"`
+	Dq   string `json:"dq,omitempty"`
+	Qinv string `json:"qinv,omitempty"`
+	// Values are the CRT values of the third and later primes.
+	Values []*CRTValue `json:"values,omitempty"`
+}
+
+// CRTValue holds the CRT values of one of the third and later primes.
+type CRTValue struct {
+	Exp   string `json:"exp,omitempty"`
+	Coeff string `json:"coeff,omitempty"`
+	R     string `json:"r,omitempty"`
+}
+
+// Data holds bytes both in hex, which is exact, and as text.
+type Data struct {
+	Hex  string `json:"hex"`
+	Text string `json:"text"`
+}
+
+// PlainTextDocument is a recovered plaintext along with its padding and decodings.
+type PlainTextDocument struct {
+	// CipherText is the index of the ciphertext the plaintext belongs to.
+	CipherText int `json:"ciphertext"`
+	Data
+	Padding string `json:"padding,omitempty"`
+	// Message is the message within the padding.
+	Message *Data              `json:"message,omitempty"`
+	Warning string             `json:"warning,omitempty"`
+	Decoded []*DecodedDocument `json:"decoded,omitempty"`
+}
+
+// DecodedDocument is a decoding of a plaintext and the chain of transforms that produced it.
+type DecodedDocument struct {
+	Chain string `json:"chain"`
+	Data
+}
+
+// CandidateDocument is a plaintext candidate found by an attack.
+type CandidateDocument struct {
+	Attack string  `json:"attack"`
+	Score  float64 `json:"score"`
+	Data
+}
+
+// ResultDocument is what an attack recovered about a key.
+type ResultDocument struct {
+	Attack string `json:"attack"`
+	// Found lists what was recovered, e.g. "2 factors" or "d".
+	Found      []string `json:"found"`
+	DurationMS float64  `json:"duration_ms"`
+	Iterations int64    `json:"iterations,omitempty"`
+}
+
+// AttackDocument describes how an attack fared.
+type AttackDocument struct {
+	Name string `json:"name"`
+	// Status is one of won, failed, cancelled, skipped, not started or finished without a result.
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMS float64 `json:"duration_ms"`
+}
+
+func newData(b []byte) Data {
+	return Data{Hex: hex.EncodeToString(b), Text: string(b)}
+}
+
+func fmpString(z *fmp.Fmpz) string {
+	if z == nil {
+		return ""
+	}
+
+	return z.String()
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// encodeKey returns b as text, base64 encoded for binary formats.
+func encodeKey(b []byte, f keys.Format) string {
+	if f.Binary() {
+		return base64.StdEncoding.EncodeToString(b)
+	}
+
+	return string(b)
+}
+
+// NewDocument describes the keys ks and the attack report r, which may be nil, with keys encoded
+// in format f and plaintexts interpreted according to o.
+func NewDocument(ks []*keys.RSA, r *attacks.Report, f keys.Format, o plaintext.Options) *Document {
+	doc := &Document{Keys: []*KeyDocument{}}
+	for _, k := range ks {
+		doc.Keys = append(doc.Keys, NewKeyDocument(k, f, o))
+	}
+
+	if r == nil {
+		return doc
+	}
+
+	doc.Winner = r.Winner
+	for _, out := range r.Outcomes {
+		ad := &AttackDocument{Name: out.Name, Status: r.Status(out), DurationMS: milliseconds(out.Duration)}
+		if out.Err != nil {
+			ad.Error = out.Err.Error()
+		}
+		doc.Attacks = append(doc.Attacks, ad)
+	}
+
+	return doc
+}
+
+// NewKeyDocument describes k and everything recovered about it.
+func NewKeyDocument(k *keys.RSA, f keys.Format, o plaintext.Options) *KeyDocument {
+	kd := &KeyDocument{ID: k.Name(), Subject: k.Subject, Issuer: k.Issuer}
+
+	if pub := k.Key.PublicKey; pub != nil {
+		kd.N, kd.E = fmpString(pub.N), fmpString(pub.E)
+		if pub.N != nil && pub.E != nil {
+			b, err := keys.EncodePublicKey(pub, f)
+			if err != nil {
+				kd.Error = fmt.Sprintf("failed encoding the public key: %v", err)
+			} else {
+				kd.PublicKey = encodeKey(b, f)
+			}
+		}
+	}
+
+	for _, r := range k.Results {
+		if r.N != nil {
+			kd.RecoveredN = r.N.String()
+		}
+
+		kd.Results = append(kd.Results, &ResultDocument{
+			Attack:     r.Attack,
+			Found:      found(r),
+			DurationMS: milliseconds(r.Duration),
+			Iterations: r.Iterations,
+		})
+	}
+
+	for _, p := range k.Key.Primes {
+		kd.Primes = append(kd.Primes, p.String())
+	}
+	kd.D = fmpString(k.Key.D)
+
+	if pc := k.Key.Precomputed; pc != nil {
+		kd.CRT = &CRT{Dp: fmpString(pc.Dp), Dq: fmpString(pc.Dq), Qinv: fmpString(pc.Qinv)}
+		for _, v := range pc.CRTValues {
+			kd.CRT.Values = append(kd.CRT.Values, &CRTValue{Exp: fmpString(v.Exp), Coeff: fmpString(v.Coeff), R: fmpString(v.R)})
+		}
+
+		if c := kd.CRT; c.Dp == "" && c.Dq == "" && c.Qinv == "" && c.Values == nil {
+			kd.CRT = nil
+		}
+	}
+
+	if k.Key.D != nil && k.Key.Primes != nil {
+		b, err := keys.EncodePrivateKey(&k.Key, f)
+		if err != nil {
+			kd.Error = fmt.Sprintf("failed encoding the private key: %v", err)
+		} else {
+			kd.PrivateKey = encodeKey(b, f)
+		}
+	}
+
+	for i, pt := range recoveredPlainTexts(k) {
+		kd.PlainTexts = append(kd.PlainTexts, newPlainTextDocument(k, i, pt, o))
+	}
+
+	for _, c := range otherCandidates(k) {
+		kd.Candidates = append(kd.Candidates, &CandidateDocument{Attack: c.Source, Score: c.Score, Data: newData(c.PlainText)})
+	}
+
+	return kd
+}
+
+// newPlainTextDocument describes the plaintext pt of the i'th ciphertext of k like printPlainText.
+func newPlainTextDocument(k *keys.RSA, i int, pt []byte, o plaintext.Options) *PlainTextDocument {
+	pd := &PlainTextDocument{CipherText: i, Data: newData(pt)}
+
+	if k.Key.PublicKey.N != nil {
+		if u := plaintext.Unpad(pt, (k.Key.PublicKey.N.BitLen()+7)/8, o); u != nil {
+			pd.Padding, pd.Warning = u.Padding, u.Warning
+			if u.Message != nil {
+				m := newData(u.Message)
+				pd.Message = &m
+				pt = u.Message
+			}
+		}
+	}
+
+	for i, d := range plaintext.Decode(pt, k.FlagFormat) {
+		if i == topDecodings {
+			break
+		}
+		pd.Decoded = append(pd.Decoded, &DecodedDocument{Chain: d.String(), Data: newData(d.PlainText)})
+	}
+
+	return pd
+}
+
+// PrintDocument writes doc to w as indented JSON.
+func PrintDocument(w io.Writer, doc *Document) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
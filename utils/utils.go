@@ -5,26 +5,33 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"strings"
+	"time"
 	"unicode"
 
 	"github.com/sourcekris/goRsaTool/keys"
 	"github.com/sourcekris/goRsaTool/ln"
+	"github.com/sourcekris/goRsaTool/plaintext"
 	fmp "github.com/sourcekris/goflint"
 )
 
-// ReadBinary imports a binary file and returns a slice of bytes of an error.
-func ReadBinary(bf string) ([]byte, error) {
-	ct, err := ioutil.ReadFile(bf)
-	if err != nil {
-		return nil, err
-	}
+const (
+	// topCandidates is the number of other plaintext candidates ReportResults prints for each key.
+	topCandidates = 5
+	// topDecodings is the number of decodings of each plaintext ReportResults prints.
+	topDecodings = 3
+)
 
-	return bytes.TrimRight(ct, "\n\r"), nil
+// ReadBinary imports a binary file and returns its exact bytes or an error.
+func ReadBinary(bf string) ([]byte, error) {
+	return ioutil.ReadFile(bf)
 }
 
-// ReadCipherText imports a ciphertext binary file and returns a slice of bytes of an error.
-func ReadCipherText(ct string) ([]byte, error) {
-	return ReadBinary(ct)
+// ReadCipherText imports a ciphertext file stored in encoding enc and returns a slice of bytes or an
+// error.
+func ReadCipherText(ct string, enc Encoding) ([]byte, error) {
+	return ReadEncoded(ct, enc)
 }
 
 // IsInt returns true if the string s contains all unicode digit characters.
@@ -47,8 +54,39 @@ func FoundP(p *fmp.Fmpz, ps []*fmp.Fmpz) bool {
 	return false
 }
 
-// EncodeAndPrintKey is called when the -createkey flag is provided.
-func EncodeAndPrintKey(n, e, d string) error {
+// PrintPrivateKey prints priv encoded in format f, binary formats are written as is.
+func PrintPrivateKey(priv *keys.FMPPrivateKey, f keys.Format) error {
+	b, err := keys.EncodePrivateKey(priv, f)
+	if err != nil {
+		return err
+	}
+
+	printKey(b, f)
+	return nil
+}
+
+// PrintPublicKey prints pub encoded in format f, binary formats are written as is.
+func PrintPublicKey(pub *keys.FMPPublicKey, f keys.Format) error {
+	b, err := keys.EncodePublicKey(pub, f)
+	if err != nil {
+		return err
+	}
+
+	printKey(b, f)
+	return nil
+}
+
+func printKey(b []byte, f keys.Format) {
+	if f.Binary() {
+		os.Stdout.Write(b)
+		return
+	}
+
+	fmt.Println(string(b))
+}
+
+// EncodeAndPrintKey is called when the -createkey flag is provided, the key is printed in format f.
+func EncodeAndPrintKey(n, e, d string, f keys.Format) error {
 	if n != "" && e != "" {
 		mod, ok := new(fmp.Fmpz).SetString(n, 10)
 		if !ok {
@@ -73,32 +111,206 @@ func EncodeAndPrintKey(n, e, d string) error {
 			}
 
 			priv.PackGivenP(ln.FindPGivenD(pexp, pk.E, pk.N))
-			fmt.Println(keys.EncodeFMPPrivateKey(&priv.Key))
-			return nil
+			return PrintPrivateKey(&priv.Key, f)
 		}
 
-		fmt.Println(keys.EncodeFMPPublicKey(pk))
-		return nil
+		return PrintPublicKey(pk, f)
 	}
 
 	return errors.New("no exponent or modulus specified - use -n and -e")
 }
 
-// ReportResults iterates a slice of keys and prints the privatekeys or plaintexts found.
-func ReportResults(ks []*keys.RSA) {
+// found lists what r recovered, e.g. "2 factors" or "d".
+func found(r *keys.Result) []string {
+	var f []string
+	if len(r.Factors) > 0 {
+		f = append(f, plural(len(r.Factors), "factor"))
+	}
+	if r.D != nil {
+		f = append(f, "d")
+	}
+	if r.N != nil {
+		f = append(f, "n")
+	}
+	if len(r.PlainTexts) > 0 {
+		f = append(f, plural(len(r.PlainTexts), "plaintext candidate"))
+	}
+	if len(r.Candidates) > 0 {
+		f = append(f, plural(len(r.Candidates), "unconfirmed plaintext candidate"))
+	}
+	if len(f) == 0 {
+		f = append(f, "nothing new")
+	}
+
+	return f
+}
+
+// SummarizeResults returns one line per attack result recorded against ks describing what the
+// attack recovered. Results shared by several keys, like common factors, are listed once.
+func SummarizeResults(ks []*keys.RSA) string {
+	var (
+		b    bytes.Buffer
+		seen = make(map[*keys.Result]bool)
+	)
+
+	for _, k := range ks {
+		for _, r := range k.Results {
+			if seen[r] {
+				continue
+			}
+			seen[r] = true
+
+			stats := r.Duration.Round(time.Millisecond).String()
+			if r.Iterations > 0 {
+				stats = fmt.Sprintf("%s, %s", stats, plural(int(r.Iterations), "iteration"))
+			}
+
+			fmt.Fprintf(&b, "%s recovered %s (%s)\n", r.Attack, strings.Join(found(r), ", "), stats)
+		}
+	}
+
+	return b.String()
+}
+
+// plural returns n followed by noun, pluralised if n is not 1.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// printPlainText prints the plaintext pt of key k as an integer and as text, named for example
+// "plaintext" or "plaintext of c[1]". If pt is padded the message within it is printed as well, and
+// so are the best decodings of any hex, base64, zlib, little-endian or decimal layers left in it.
+func printPlainText(k *keys.RSA, name string, pt []byte, o plaintext.Options) {
+	fmt.Printf("Recovered %s as an integer: %s\n", name, ln.BytesToNumber(pt))
+	fmt.Printf("Recovered %s: \n", name)
+	fmt.Println(string(pt))
+
+	if k.Key.PublicKey.N != nil {
+		if u := plaintext.Unpad(pt, (k.Key.PublicKey.N.BitLen()+7)/8, o); u != nil {
+			if u.Warning != "" {
+				fmt.Printf("Warning: %s\n", u.Warning)
+			}
+
+			if u.Message != nil {
+				fmt.Printf("Recovered %s with %s padding removed: \n", name, u.Padding)
+				fmt.Println(string(u.Message))
+				pt = u.Message
+			}
+		}
+	}
+
+	// Decode any layers of encoding left in the message.
+	for i, d := range plaintext.Decode(pt, k.FlagFormat) {
+		if i == topDecodings {
+			break
+		}
+
+		fmt.Printf("Decoded %s (%s): \n", name, d)
+		fmt.Println(string(d.PlainText))
+	}
+}
+
+// recoveredPlainTexts returns the plaintext of every ciphertext of k by index once d is known, or
+// just the plaintext an attack found.
+func recoveredPlainTexts(k *keys.RSA) [][]byte {
+	cts := k.AllCipherTexts()
+	if len(cts) < 2 || k.Key.D == nil {
+		if len(k.PlainText) > 0 {
+			return [][]byte{k.PlainText}
+		}
+		return nil
+	}
+
+	// Decrypt every ciphertext, an attack may have found the first plaintext without d.
+	var pts [][]byte
+	for i, c := range cts {
+		pt := k.Decrypt(c)
+		if i == 0 && len(k.PlainText) > 0 {
+			pt = k.PlainText
+		}
+		pts = append(pts, pt)
+	}
+
+	return pts
+}
+
+// otherCandidates returns the plaintext candidates of k other than its plaintext, best first.
+func otherCandidates(k *keys.RSA) []plaintext.Candidate {
+	var cs []plaintext.Candidate
+	for _, c := range k.Candidates {
+		if !bytes.Equal(c.PlainText, k.PlainText) {
+			cs = append(cs, c)
+		}
+	}
+
+	return cs
+}
+
+// ReportResults iterates a slice of keys and prints a summary of each attack's result followed by
+// the private keys, moduli or plaintexts found. Keys are printed in format f and plaintexts are
+// interpreted according to o.
+func ReportResults(ks []*keys.RSA, f keys.Format, o plaintext.Options) {
+	fmt.Print(SummarizeResults(ks))
+
 	for _, k := range ks {
+		if len(ks) > 1 && (k.Key.D != nil || len(k.PlainText) > 0 || len(k.Candidates) > 0) {
+			fmt.Printf("%s:\n", k.Name())
+		}
+
+		for _, r := range k.Results {
+			if r.N == nil {
+				continue
+			}
+
+			if k.Key.PublicKey.E != nil {
+				fmt.Println("Recovered public key:")
+				if err := PrintPublicKey(k.Key.PublicKey, f); err != nil {
+					fmt.Printf("failed encoding the public key: %v\n", err)
+				}
+			} else {
+				fmt.Println("Recovered modulus:")
+				fmt.Printf("n = %v\n", r.N)
+			}
+		}
+
 		if k.Key.D != nil && k.Key.Primes != nil {
-			fmt.Println(keys.EncodeFMPPrivateKey(&k.Key))
+			if err := PrintPrivateKey(&k.Key, f); err != nil {
+				fmt.Printf("failed encoding the private key: %v\n", err)
+			}
 		}
 
 		if k.Key.D != nil && k.Key.Primes == nil {
 			fmt.Printf("recovered d but was unable to recover all the primes\nd = %v\n", k.Key.D)
 		}
 
-		if len(k.PlainText) > 0 {
-			fmt.Printf("Recovered plaintext as an integer: %s\n", ln.BytesToNumber(k.PlainText))
-			fmt.Println("Recovered plaintext: ")
-			fmt.Println(string(k.PlainText))
+		pts := recoveredPlainTexts(k)
+		for i, pt := range pts {
+			name := "plaintext"
+			if len(pts) > 1 {
+				name = fmt.Sprintf("plaintext of c[%d]", i)
+			}
+
+			printPlainText(k, name, pt, o)
+		}
+
+		// Report the best other candidates attacks found for this key, they are all that was found
+		// when no attack could confirm a plaintext.
+		label := "Other plaintext candidate"
+		if len(k.PlainText) == 0 {
+			label = "Plaintext candidate"
+		}
+
+		others := otherCandidates(k)
+		for i, c := range others {
+			if i == topCandidates {
+				fmt.Printf("%d less likely plaintext candidates omitted\n", len(others)-i)
+				break
+			}
+			fmt.Printf("%s %d from %s (score %.2f): %s\n", label, i+1, c.Source, c.Score, string(c.PlainText))
 		}
 	}
 }